@@ -0,0 +1,149 @@
+package gocrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	requiredCiphertextParts   = 3
+	supportedCipherAlgorithm  = "aes256gcm"
+	ciphertextPartsSeparator  = "$"
+	requiredEncryptionKeySize = 32
+)
+
+// Keyring holds the keys used to encrypt and decrypt data with AES-256-GCM.
+//
+// New data is always encrypted with the current key. The key ID is stored in the
+// ciphertext, so data encrypted with older keys can still be decrypted while it is
+// rotated, as long as their keys are kept in the keyring.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring Creates a new keyring. Keys must have 32 bytes and currentKeyID must be one of them
+func NewKeyring(currentKeyID string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("current key %q not found. %w", currentKeyID, ErrInvalidEncryptionKey)
+	}
+
+	keyring := &Keyring{current: currentKeyID, keys: make(map[string]cipher.AEAD, len(keys))}
+	for keyID, key := range keys {
+		if keyID == "" || strings.Contains(keyID, ciphertextPartsSeparator) {
+			return nil, fmt.Errorf("invalid key id %q. %w", keyID, ErrInvalidEncryptionKey)
+		}
+
+		if len(key) != requiredEncryptionKeySize {
+			return nil, fmt.Errorf("key %q must have %d bytes. %w", keyID, requiredEncryptionKeySize, ErrInvalidEncryptionKey)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s. %w", err, ErrInvalidEncryptionKey)
+		}
+
+		if keyring.keys[keyID], err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("%s. %w", err, ErrInvalidEncryptionKey)
+		}
+	}
+	return keyring, nil
+}
+
+// NewEncryptionKey Creates a new random key to be used by a Keyring
+func NewEncryptionKey() ([]byte, error) {
+	return randomBytes(requiredEncryptionKeySize)
+}
+
+// CurrentKeyID Returns the ID of the key used to encrypt new data
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+// Encrypt Encrypts and authenticates the plaintext using the current key.
+// The result has the format aes256gcm$<key id>$<base64 nonce and ciphertext>.
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	aead := k.keys[k.current]
+
+	nonce, err := randomBytes(uint32(aead.NonceSize()))
+	if err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(k.current))
+	return strings.Join([]string{
+		supportedCipherAlgorithm,
+		k.current,
+		base64.RawStdEncoding.EncodeToString(sealed),
+	}, ciphertextPartsSeparator), nil
+}
+
+// Decrypt Decrypts a ciphertext created by Encrypt using the key it was encrypted with
+func (k *Keyring) Decrypt(ciphertext string) ([]byte, error) {
+	keyID, sealed, err := parseCiphertext(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found. %w", keyID, ErrUnknownEncryptionKey)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("%s. %w", err, ErrDecryptionFailed)
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation Check if the ciphertext was encrypted with a key other than the current one
+func (k *Keyring) NeedsRotation(ciphertext string) (bool, error) {
+	keyID, err := CiphertextKeyID(ciphertext)
+	if err != nil {
+		return false, err
+	}
+	return keyID != k.current, nil
+}
+
+// Rotate Encrypts the ciphertext again using the current key
+func (k *Keyring) Rotate(ciphertext string) (string, error) {
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext)
+}
+
+// CiphertextKeyID Returns the ID of the key used to encrypt the ciphertext
+func CiphertextKeyID(ciphertext string) (string, error) {
+	keyID, _, err := parseCiphertext(ciphertext)
+	return keyID, err
+}
+
+// parseCiphertext returns the key ID and the sealed data of a ciphertext
+func parseCiphertext(ciphertext string) (string, []byte, error) {
+	parts := strings.Split(ciphertext, ciphertextPartsSeparator)
+
+	if len(parts) != requiredCiphertextParts {
+		return "", nil, ErrInvalidCiphertext
+	}
+
+	if parts[0] != supportedCipherAlgorithm {
+		return "", nil, ErrUnsupportedCipherAlgorithm
+	}
+
+	sealed, err := base64.RawStdEncoding.Strict().DecodeString(parts[2])
+	if err != nil {
+		return "", nil, fmt.Errorf("%s. %w", err, ErrInvalidCiphertext)
+	}
+	return parts[1], sealed, nil
+}
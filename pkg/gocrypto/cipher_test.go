@@ -0,0 +1,108 @@
+package gocrypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	oldKeyring, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	assert.NoError(t, err, "failed to create keyring")
+
+	ciphertext, err := oldKeyring.Encrypt([]byte("john@doe.com"))
+	assert.NoError(t, err, "failed to encrypt")
+	assert.True(t, strings.HasPrefix(ciphertext, "aes256gcm$v1$"), "ciphertext should contain the key id")
+
+	otherCiphertext, err := oldKeyring.Encrypt([]byte("john@doe.com"))
+	assert.NoError(t, err, "failed to encrypt")
+	assert.NotEqual(t, ciphertext, otherCiphertext, "nonces should be random")
+
+	keyring, err := NewKeyring("v2", map[string][]byte{"v1": oldKey, "v2": newKey})
+	assert.NoError(t, err, "failed to create keyring")
+	assert.Equal(t, "v2", keyring.CurrentKeyID())
+
+	plaintext, err := keyring.Decrypt(ciphertext)
+	assert.NoError(t, err, "should decrypt data encrypted with old keys")
+	assert.Equal(t, "john@doe.com", string(plaintext))
+
+	needsRotation, err := keyring.NeedsRotation(ciphertext)
+	assert.NoError(t, err)
+	assert.True(t, needsRotation)
+
+	rotated, err := keyring.Rotate(ciphertext)
+	assert.NoError(t, err, "failed to rotate")
+	keyID, err := CiphertextKeyID(rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", keyID)
+
+	_, err = oldKeyring.Decrypt(rotated)
+	assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+
+	tamperedChar := "A"
+	if rotated[len(rotated)-5:len(rotated)-4] == tamperedChar {
+		tamperedChar = "B"
+	}
+	tampered := rotated[:len(rotated)-5] + tamperedChar + rotated[len(rotated)-4:]
+	_, err = keyring.Decrypt(tampered)
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+
+	forged := strings.Replace(ciphertext, "$v1$", "$v2$", 1)
+	_, err = keyring.Decrypt(forged)
+	assert.ErrorIs(t, err, ErrDecryptionFailed, "key id should be authenticated")
+}
+
+func TestKeyringErrors(t *testing.T) {
+	type test struct {
+		name          string
+		currentKeyID  string
+		keys          map[string][]byte
+		expectedError error
+	}
+	tests := []test{
+		{
+			name:          "should fail without the current key",
+			currentKeyID:  "v2",
+			keys:          map[string][]byte{"v1": bytes.Repeat([]byte{1}, 32)},
+			expectedError: ErrInvalidEncryptionKey,
+		},
+		{
+			name:          "should fail with short keys",
+			currentKeyID:  "v1",
+			keys:          map[string][]byte{"v1": bytes.Repeat([]byte{1}, 16)},
+			expectedError: ErrInvalidEncryptionKey,
+		},
+		{
+			name:          "should fail with invalid key ids",
+			currentKeyID:  "v$1",
+			keys:          map[string][]byte{"v$1": bytes.Repeat([]byte{1}, 32)},
+			expectedError: ErrInvalidEncryptionKey,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewKeyring(test.currentKeyID, test.keys)
+			assert.ErrorIs(t, err, test.expectedError)
+		})
+	}
+
+	key, err := NewEncryptionKey()
+	assert.NoError(t, err)
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": key})
+	assert.NoError(t, err)
+
+	_, err = keyring.Decrypt("aes256gcm$v1")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = keyring.Decrypt("des$v1$AAAA")
+	assert.ErrorIs(t, err, ErrUnsupportedCipherAlgorithm)
+	_, err = keyring.Decrypt("aes256gcm$v1$!!!")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = keyring.Decrypt("aes256gcm$v1$AAAA")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
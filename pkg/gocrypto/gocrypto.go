@@ -24,6 +24,24 @@ var (
 	ErrInvalidEncodedHash       = errors.New("invalid encoded hash")
 	ErrInvalidAlgorithmVersion  = errors.New("invalid algorithm version")
 	ErrUnsupportedHashAlgorithm = errors.New("unsupported hash algorithm")
+
+	ErrInvalidEncryptionKey       = errors.New("invalid encryption key")
+	ErrUnknownEncryptionKey       = errors.New("unknown encryption key")
+	ErrInvalidCiphertext          = errors.New("invalid ciphertext")
+	ErrDecryptionFailed           = errors.New("decryption failed")
+	ErrUnsupportedCipherAlgorithm = errors.New("unsupported cipher algorithm")
+
+	ErrInvalidToken            = errors.New("invalid token")
+	ErrInvalidSignature        = errors.New("invalid token signature")
+	ErrTokenExpired            = errors.New("token expired")
+	ErrMissingTokenExpiry      = errors.New("token without expiry")
+	ErrTokenNotYetValid        = errors.New("token not yet valid")
+	ErrInvalidIssuer           = errors.New("invalid token issuer")
+	ErrInvalidAudience         = errors.New("invalid token audience")
+	ErrInvalidJWTKey           = errors.New("invalid JWT key")
+	ErrUnknownSigningKey       = errors.New("unknown signing key")
+	ErrUnsupportedJWTAlgorithm = errors.New("unsupported JWT algorithm")
+	ErrInvalidJWKS             = errors.New("invalid JWKS")
 )
 
 type HashParsms struct {
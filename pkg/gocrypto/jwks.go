@@ -0,0 +1,338 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultJWKSCacheTTL        = time.Hour
+	defaultJWKSRefreshInterval = time.Minute
+	defaultJWKSTimeout         = 10 * time.Second
+	maxJWKSSize                = 1 << 20
+)
+
+type JWKSConfig struct {
+	// URL is the http(s) URL of the JWKS, such as https://auth.example.com/.well-known/jwks.json.
+	// Its HS256 (oct) keys are skipped, since secrets must not be published.
+	URL string
+	// Path is the file of the JWKS, used when URL is empty. It can hold HS256 keys.
+	Path string
+	// CacheTTL is how long the keys are cached before they are loaded again. Defaults to 1 hour.
+	CacheTTL time.Duration
+	// RefreshInterval is the minimum time between the loads of expired caches and of tokens
+	// signed with unknown keys, which are loaded again so rotated keys are found. Defaults to
+	// 1 minute.
+	RefreshInterval time.Duration
+	// Client loads the JWKS from URL. Defaults to a client with a 10 seconds timeout.
+	Client *http.Client
+}
+
+// JWKS is a cached JSON Web Key Set (RFC 7517) verifying JWTs.
+//
+// The keys are loaded again once the cache expires, or when a token is signed with an
+// unknown key, so keys can be rotated by the issuer. Expired caches are loaded in the
+// background while the cached keys keep verifying tokens. If loading fails, the cached keys
+// are used until it succeeds.
+type JWKS struct {
+	config    JWKSConfig
+	mutex     sync.Mutex
+	group     singleflight.Group
+	keys      map[string]JWTKey
+	loadedAt  time.Time
+	refreshAt time.Time
+	now       func() time.Time
+}
+
+// jsonWebKey is a key of a JWKS
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	K         string `json:"k,omitempty"`
+}
+
+// NewJWKS Creates a new JWKS, loading its keys from the URL or file
+func NewJWKS(ctx context.Context, config JWKSConfig) (*JWKS, error) {
+	if config.URL == "" && config.Path == "" {
+		return nil, fmt.Errorf("URL or path is required. %w", ErrInvalidJWKS)
+	}
+
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaultJWKSCacheTTL
+	}
+
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultJWKSRefreshInterval
+	}
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultJWKSTimeout}
+	}
+
+	jwks := &JWKS{config: config, now: time.Now}
+	if err := jwks.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return jwks, nil
+}
+
+// Key Get the key with the key id, loading the keys again when the cache expired or the key
+// is unknown
+func (j *JWKS) Key(ctx context.Context, keyID string) (JWTKey, error) {
+	key, found, load := j.cachedKey(keyID)
+	if found {
+		if load {
+			// The cached key is used while the keys are loaded. Failures keep the cached keys.
+			go func() { _ = j.Refresh(context.WithoutCancel(ctx)) }()
+		}
+		return key, nil
+	}
+
+	if load {
+		// Failures keep the cached keys
+		_ = j.Refresh(ctx)
+
+		j.mutex.Lock()
+		key, found = j.lookup(keyID)
+		j.mutex.Unlock()
+	}
+
+	if found {
+		return key, nil
+	}
+	return JWTKey{}, fmt.Errorf("key %q not found. %w", keyID, ErrUnknownSigningKey)
+}
+
+// cachedKey Get the cached key with the key id and whether the keys must be loaded again,
+// because the cache expired or the key is unknown. Loads are limited by the refresh interval.
+func (j *JWKS) cachedKey(keyID string) (JWTKey, bool, bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	now := j.now()
+	key, found := j.lookup(keyID)
+	load := !now.Before(j.refreshAt) && (!found || now.Sub(j.loadedAt) >= j.config.CacheTTL)
+	if load {
+		j.refreshAt = now.Add(j.config.RefreshInterval)
+	}
+	return key, found, load
+}
+
+// lookup Get the key with the key id. The mutex must be locked.
+func (j *JWKS) lookup(keyID string) (JWTKey, bool) {
+	if key, ok := j.keys[keyID]; ok {
+		return key, true
+	}
+
+	if keyID == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	return JWTKey{}, false
+}
+
+// Refresh Load the keys again. Concurrent calls share the same load.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	_, err, _ := j.group.Do("refresh", func() (interface{}, error) {
+		return nil, j.refresh(ctx)
+	})
+	return err
+}
+
+// refresh Load the keys, holding the mutex only to replace them
+func (j *JWKS) refresh(ctx context.Context) error {
+	j.mutex.Lock()
+	j.refreshAt = j.now().Add(j.config.RefreshInterval)
+	j.mutex.Unlock()
+
+	data, err := j.load(ctx)
+	if err != nil {
+		return fmt.Errorf("%s. %w", err, ErrInvalidJWKS)
+	}
+
+	parsed, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]JWTKey, len(parsed))
+	for _, key := range parsed {
+		if _, secret := key.Key.([]byte); secret && j.config.URL != "" {
+			continue
+		}
+		keys[key.ID] = key
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.keys = keys
+	j.loadedAt = j.now()
+	return nil
+}
+
+// load Read the JWKS from the URL or file
+func (j *JWKS) load(ctx context.Context) ([]byte, error) {
+	if j.config.URL == "" {
+		return os.ReadFile(j.config.Path)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, j.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := j.config.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, maxJWKSSize))
+}
+
+// ParseJWKS Parse the keys of a JWKS. Keys of unsupported types, curves or algorithms, and
+// encryption keys, are skipped.
+func ParseJWKS(data []byte) ([]JWTKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("%s. %w", err, ErrInvalidJWKS)
+	}
+
+	keys := []JWTKey{}
+	for _, webKey := range jwks.Keys {
+		if webKey.Use != "" && webKey.Use != "sig" {
+			continue
+		}
+
+		key, err := webKey.jwtKey()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// MarshalJWKS Create a JWKS with the public keys, so the verifiers can load them. HS256 keys
+// are secret and skipped.
+func MarshalJWKS(keys ...JWTKey) ([]byte, error) {
+	webKeys := []jsonWebKey{}
+	for _, key := range keys {
+		webKey := jsonWebKey{KeyID: key.ID, Algorithm: key.Algorithm, Use: "sig"}
+		switch publicKey := publicKey(key.Key).(type) {
+		case *rsa.PublicKey:
+			webKey.KeyType = "RSA"
+			webKey.N = encodeBase64URL(publicKey.N.Bytes())
+			webKey.E = encodeBase64URL(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			webKey.KeyType = "EC"
+			webKey.Curve = publicKey.Curve.Params().Name
+			webKey.X = encodeBase64URL(publicKey.X.FillBytes(make([]byte, size)))
+			webKey.Y = encodeBase64URL(publicKey.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			webKey.KeyType = "OKP"
+			webKey.Curve = "Ed25519"
+			webKey.X = encodeBase64URL(publicKey)
+		default:
+			continue
+		}
+		webKeys = append(webKeys, webKey)
+	}
+	return json.Marshal(map[string][]jsonWebKey{"keys": webKeys})
+}
+
+// jwtKey Convert the JSON web key into a JWTKey
+func (k jsonWebKey) jwtKey() (JWTKey, error) {
+	key := JWTKey{ID: k.KeyID, Algorithm: k.Algorithm}
+	switch k.KeyType {
+	case "RSA":
+		n, nErr := decodeBase64URL(k.N)
+		e, eErr := decodeBase64URL(k.E)
+		if nErr != nil || eErr != nil || len(e) > 4 {
+			return JWTKey{}, ErrInvalidJWKS
+		}
+
+		key.Key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		key.Algorithm = defaultIfEmpty(key.Algorithm, AlgRS256)
+	case "EC":
+		x, xErr := decodeBase64URL(k.X)
+		y, yErr := decodeBase64URL(k.Y)
+		if xErr != nil || yErr != nil || k.Curve != elliptic.P256().Params().Name {
+			return JWTKey{}, ErrInvalidJWKS
+		}
+
+		ecKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !ecKey.Curve.IsOnCurve(ecKey.X, ecKey.Y) {
+			return JWTKey{}, ErrInvalidJWKS
+		}
+
+		key.Key = ecKey
+		key.Algorithm = defaultIfEmpty(key.Algorithm, AlgES256)
+	case "OKP":
+		x, err := decodeBase64URL(k.X)
+		if err != nil || k.Curve != "Ed25519" {
+			return JWTKey{}, ErrInvalidJWKS
+		}
+
+		key.Key = ed25519.PublicKey(x)
+		key.Algorithm = defaultIfEmpty(key.Algorithm, AlgEdDSA)
+	case "oct":
+		secret, err := decodeBase64URL(k.K)
+		if err != nil {
+			return JWTKey{}, ErrInvalidJWKS
+		}
+
+		key.Key = secret
+		key.Algorithm = defaultIfEmpty(key.Algorithm, AlgHS256)
+	default:
+		return JWTKey{}, ErrInvalidJWKS
+	}
+	return key, validateJWTKey(key)
+}
+
+// encodeBase64URL Encode bytes as unpadded base64url
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeBase64URL Decode unpadded base64url
+func decodeBase64URL(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
+
+// defaultIfEmpty Get the default value of empty strings
+func defaultIfEmpty(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
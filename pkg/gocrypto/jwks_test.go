@@ -0,0 +1,168 @@
+package gocrypto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKS(t *testing.T) {
+	oldKey, err := GenerateJWTKey("2024-01", AlgRS256)
+	assert.NoError(t, err)
+	newKey, err := GenerateJWTKey("2024-02", AlgES256)
+	assert.NoError(t, err)
+
+	published, err := MarshalJWKS(oldKey)
+	assert.NoError(t, err)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(published)
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL})
+	assert.NoError(t, err)
+
+	verifier, err := NewJWT(JWTConfig{JWKS: jwks})
+	assert.NoError(t, err)
+
+	oldIssuer, err := NewJWT(JWTConfig{Keys: []JWTKey{oldKey}})
+	assert.NoError(t, err)
+	token, err := oldIssuer.Sign(Claims{"sub": "user-1"})
+	assert.NoError(t, err)
+
+	for index := 0; index < 3; index++ {
+		claims, err := verifier.Verify(context.Background(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", claims.Subject())
+	}
+	assert.Equal(t, int32(1), requests.Load(), "keys should be cached")
+
+	// The issuer rotates its key
+	published, err = MarshalJWKS(oldKey, newKey)
+	assert.NoError(t, err)
+	newIssuer, err := NewJWT(JWTConfig{Keys: []JWTKey{newKey}})
+	assert.NoError(t, err)
+	token, err = newIssuer.Sign(Claims{"sub": "user-2"})
+	assert.NoError(t, err)
+
+	_, err = verifier.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, ErrUnknownSigningKey, "unknown keys should not be loaded again before the refresh interval")
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now.Add(defaultJWKSRefreshInterval) }
+	claims, err := verifier.Verify(context.Background(), token)
+	assert.NoError(t, err, "unknown keys should be loaded again")
+	assert.Equal(t, "user-2", claims.Subject())
+	assert.Equal(t, int32(2), requests.Load())
+
+	server.Close()
+	jwks.now = func() time.Time { return now.Add(2 * defaultJWKSCacheTTL) }
+	_, err = verifier.Verify(context.Background(), token)
+	assert.NoError(t, err, "cached keys should be used when the JWKS can not be loaded")
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	key, err := GenerateJWTKey("2024-01", AlgES256)
+	assert.NoError(t, err)
+	published, err := MarshalJWKS(key)
+	assert.NoError(t, err)
+
+	var requests atomic.Int32
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			<-blocked
+		}
+		_, _ = w.Write(published)
+	}))
+	defer server.Close()
+	defer close(blocked)
+
+	jwks, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now.Add(defaultJWKSCacheTTL) }
+	for index := 0; index < 3; index++ {
+		loaded, err := jwks.Key(context.Background(), "2024-01")
+		assert.NoError(t, err, "cached keys should be used while the keys are loaded")
+		assert.Equal(t, key.Public(), loaded)
+	}
+
+	assert.Eventually(t, func() bool { return requests.Load() == 2 }, time.Second, 10*time.Millisecond)
+	_, err = jwks.Key(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrUnknownSigningKey, "unknown keys should not wait for the load in progress")
+	assert.Equal(t, int32(2), requests.Load(), "the keys should be loaded once per refresh interval")
+}
+
+func TestJWKSFile(t *testing.T) {
+	key, err := GenerateJWTKey("ed", AlgEdDSA)
+	assert.NoError(t, err)
+	hsKey, err := GenerateJWTKey("hs", AlgHS256)
+	assert.NoError(t, err)
+
+	data, err := MarshalJWKS(key, hsKey)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), `"hs"`, "secret keys should not be published")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+	jwks, err := NewJWKS(context.Background(), JWKSConfig{Path: path})
+	assert.NoError(t, err)
+
+	loaded, err := jwks.Key(context.Background(), "ed")
+	assert.NoError(t, err)
+	assert.Equal(t, key.Public(), loaded)
+
+	_, err = NewJWKS(context.Background(), JWKSConfig{Path: filepath.Join(t.TempDir(), "missing.json")})
+	assert.ErrorIs(t, err, ErrInvalidJWKS)
+	_, err = NewJWKS(context.Background(), JWKSConfig{})
+	assert.ErrorIs(t, err, ErrInvalidJWKS)
+}
+
+func TestJWKSSecretKeys(t *testing.T) {
+	data := []byte(`{"keys":[{"kty":"oct","kid":"hs","k":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE"}]}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(context.Background(), JWKSConfig{URL: server.URL})
+	assert.NoError(t, err)
+	_, err = jwks.Key(context.Background(), "hs")
+	assert.ErrorIs(t, err, ErrUnknownSigningKey, "secret keys should not be loaded from URLs")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	jwks, err = NewJWKS(context.Background(), JWKSConfig{Path: path})
+	assert.NoError(t, err)
+	key, err := jwks.Key(context.Background(), "hs")
+	assert.NoError(t, err)
+	assert.Equal(t, AlgHS256, key.Algorithm)
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys, err := ParseJWKS([]byte(`{"keys":[
+		{"kty":"oct","kid":"hs","k":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE"},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"},
+		{"kty":"EC","kid":"p384","crv":"P-384","x":"AQAB","y":"AQAB"},
+		{"kty":"EC","kid":"off-curve","crv":"P-256","x":"AQAB","y":"AQAB"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1, "unsupported and encryption keys should be skipped")
+	assert.Equal(t, AlgHS256, keys[0].Algorithm)
+
+	_, err = ParseJWKS([]byte(`{"keys":`))
+	assert.ErrorIs(t, err, ErrInvalidJWKS)
+}
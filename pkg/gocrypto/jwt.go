@@ -0,0 +1,498 @@
+package gocrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	jwtType             = "JWT"
+	defaultTokenTTL     = 15 * time.Minute
+	minHMACKeySize      = 32
+	minRSAKeySize       = 2048
+	es256SignatureSize  = 64
+	requiredTokenParts  = 3
+	tokenPartsSeparator = "."
+)
+
+// JWTKey is a key signing or verifying JWTs
+type JWTKey struct {
+	// ID is sent in the kid header of the tokens, so the verifiers can find the key while keys
+	// are rotated.
+	ID        string
+	Algorithm string
+	// Key is the []byte secret of HS256, or the private key signing the tokens of the other
+	// algorithms: *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey. Verifiers can use
+	// the public keys instead: *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+	Key interface{}
+}
+
+// Claims are the claims of a JWT
+type Claims map[string]interface{}
+
+type JWTConfig struct {
+	// Keys sign and verify the tokens
+	Keys []JWTKey
+	// SigningKeyID is the ID of the key signing new tokens. Defaults to the first key able to
+	// sign them.
+	SigningKeyID string
+	// JWKS verifies the tokens signed with keys not in Keys
+	JWKS *JWKS
+	// Issuer is the iss claim of new tokens. Tokens with other issuers are rejected if set.
+	Issuer string
+	// Audience is the aud claim of new tokens. Tokens for other audiences are rejected if set.
+	Audience []string
+	// Leeway is the clock skew tolerated when checking the exp and nbf claims
+	Leeway time.Duration
+	// AllowMissingExpiry accepts tokens without a numeric exp claim, which never expire.
+	// They are rejected by default.
+	AllowMissingExpiry bool
+	// TTL is how long new tokens are valid. Defaults to 15 minutes.
+	TTL time.Duration
+}
+
+// JWT signs and verifies JSON Web Tokens
+type JWT struct {
+	config     JWTConfig
+	keys       map[string]JWTKey
+	signingKey *JWTKey
+	now        func() time.Time
+}
+
+// NewJWT Creates a new JWT signer and verifier with the given keys
+func NewJWT(config JWTConfig) (*JWT, error) {
+	if len(config.Keys) == 0 && config.JWKS == nil {
+		return nil, fmt.Errorf("keys or JWKS are required. %w", ErrInvalidJWTKey)
+	}
+
+	if config.TTL <= 0 {
+		config.TTL = defaultTokenTTL
+	}
+
+	jwt := &JWT{config: config, keys: make(map[string]JWTKey, len(config.Keys)), now: time.Now}
+	for index, key := range config.Keys {
+		if err := validateJWTKey(key); err != nil {
+			return nil, err
+		}
+
+		if _, ok := jwt.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicated key id %q. %w", key.ID, ErrInvalidJWTKey)
+		}
+		jwt.keys[key.ID] = key
+
+		if canSign(key) && jwt.signingKey == nil && (config.SigningKeyID == "" || config.SigningKeyID == key.ID) {
+			jwt.signingKey = &config.Keys[index]
+		}
+	}
+
+	if config.SigningKeyID != "" && jwt.signingKey == nil {
+		return nil, fmt.Errorf("signing key %q not found. %w", config.SigningKeyID, ErrUnknownSigningKey)
+	}
+	return jwt, nil
+}
+
+// Sign Creates a new token with the claims, signed with the signing key.
+// The iss, aud, iat and exp claims are set from the configuration when missing.
+func (j *JWT) Sign(claims Claims) (string, error) {
+	if j.signingKey == nil {
+		return "", fmt.Errorf("no key can sign tokens. %w", ErrUnknownSigningKey)
+	}
+
+	now := j.now()
+	payload := Claims{"iat": now.Unix(), "exp": now.Add(j.config.TTL).Unix()}
+	if j.config.Issuer != "" {
+		payload["iss"] = j.config.Issuer
+	}
+
+	if len(j.config.Audience) == 1 {
+		payload["aud"] = j.config.Audience[0]
+	} else if len(j.config.Audience) > 1 {
+		payload["aud"] = j.config.Audience
+	}
+
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	header := map[string]string{"alg": j.signingKey.Algorithm, "typ": jwtType}
+	if j.signingKey.ID != "" {
+		header["kid"] = j.signingKey.ID
+	}
+
+	encodedHeader, err := encodeSegment(header)
+	if err != nil {
+		return "", err
+	}
+
+	encodedPayload, err := encodeSegment(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodedHeader + tokenPartsSeparator + encodedPayload
+	signature, err := signJWT(*j.signingKey, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + tokenPartsSeparator + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify Verify the signature and the exp, nbf, iss and aud claims of a token, returning its claims
+func (j *JWT) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, tokenPartsSeparator)
+	if len(parts) != requiredTokenParts {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	key, err := j.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm must be the one of the key, so public keys are never used as HMAC secrets
+	if header.Algorithm != key.Algorithm {
+		return nil, fmt.Errorf("algorithm %q does not match the key. %w", header.Algorithm, ErrInvalidSignature)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s. %w", err, ErrInvalidToken)
+	}
+
+	if !verifyJWT(key, []byte(parts[0]+tokenPartsSeparator+parts[1]), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	claims := Claims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if err := j.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key Get the key verifying the tokens with the key id
+func (j *JWT) key(ctx context.Context, keyID string) (JWTKey, error) {
+	if key, ok := j.keys[keyID]; ok {
+		return key, nil
+	}
+
+	if keyID == "" && len(j.keys) == 1 && j.config.JWKS == nil {
+		for _, key := range j.keys {
+			return key, nil
+		}
+	}
+
+	if j.config.JWKS != nil {
+		return j.config.JWKS.Key(ctx, keyID)
+	}
+	return JWTKey{}, fmt.Errorf("key %q not found. %w", keyID, ErrUnknownSigningKey)
+}
+
+// checkClaims Check the exp, nbf, iss and aud claims
+func (j *JWT) checkClaims(claims Claims) error {
+	now := j.now()
+	expiresAt, ok := claims.Time("exp")
+	if !ok && !j.config.AllowMissingExpiry {
+		return ErrMissingTokenExpiry
+	}
+
+	if ok && now.After(expiresAt.Add(j.config.Leeway)) {
+		return ErrTokenExpired
+	}
+
+	if notBefore, ok := claims.Time("nbf"); ok && now.Add(j.config.Leeway).Before(notBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	if j.config.Issuer != "" && claims.Issuer() != j.config.Issuer {
+		return fmt.Errorf("unexpected issuer %q. %w", claims.Issuer(), ErrInvalidIssuer)
+	}
+
+	if len(j.config.Audience) > 0 {
+		for _, audience := range claims.Audience() {
+			for _, expected := range j.config.Audience {
+				if audience == expected {
+					return nil
+				}
+			}
+		}
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// Subject Get the sub claim
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Issuer Get the iss claim
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// Audience Get the aud claim, which can be a string or a list of strings
+func (c Claims) Audience() []string {
+	switch audience := c["aud"].(type) {
+	case string:
+		return []string{audience}
+	case []string:
+		return audience
+	case []interface{}:
+		audiences := []string{}
+		for _, value := range audience {
+			if value, ok := value.(string); ok {
+				audiences = append(audiences, value)
+			}
+		}
+		return audiences
+	}
+	return nil
+}
+
+// ExpiresAt Get the exp claim
+func (c Claims) ExpiresAt() time.Time {
+	expiresAt, _ := c.Time("exp")
+	return expiresAt
+}
+
+// String Get a string claim
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Time Get a numeric date claim, such as exp
+func (c Claims) Time(name string) (time.Time, bool) {
+	switch value := c[name].(type) {
+	case float64:
+		return time.Unix(int64(value), 0), true
+	case int64:
+		return time.Unix(value, 0), true
+	case int:
+		return time.Unix(int64(value), 0), true
+	case json.Number:
+		seconds, err := value.Float64()
+		return time.Unix(int64(seconds), 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+// GenerateJWTKey Creates a new random key for the algorithm
+func GenerateJWTKey(id string, algorithm string) (JWTKey, error) {
+	var (
+		key interface{}
+		err error
+	)
+
+	switch algorithm {
+	case AlgHS256:
+		key, err = randomBytes(minHMACKeySize)
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, minRSAKeySize)
+	case AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return JWTKey{}, fmt.Errorf("algorithm %q. %w", algorithm, ErrUnsupportedJWTAlgorithm)
+	}
+
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("%s. %w", err, ErrInvalidJWTKey)
+	}
+	return JWTKey{ID: id, Algorithm: algorithm, Key: key}, nil
+}
+
+// ParseJWTKey Creates a key from the raw secret of HS256, or the PEM encoded private or public
+// key of the other algorithms
+func ParseJWTKey(id string, algorithm string, data []byte) (JWTKey, error) {
+	if algorithm == AlgHS256 {
+		key := JWTKey{ID: id, Algorithm: algorithm, Key: data}
+		return key, validateJWTKey(key)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return JWTKey{}, fmt.Errorf("invalid PEM data. %w", ErrInvalidJWTKey)
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	for _, parse := range []func([]byte) (interface{}, error){
+		x509.ParsePKCS8PrivateKey,
+		x509.ParsePKIXPublicKey,
+		func(der []byte) (interface{}, error) { return x509.ParsePKCS1PrivateKey(der) },
+		func(der []byte) (interface{}, error) { return x509.ParseECPrivateKey(der) },
+		func(der []byte) (interface{}, error) { return x509.ParsePKCS1PublicKey(der) },
+	} {
+		if key, err = parse(block.Bytes); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("unsupported PEM key. %w", ErrInvalidJWTKey)
+	}
+
+	jwtKey := JWTKey{ID: id, Algorithm: algorithm, Key: key}
+	return jwtKey, validateJWTKey(jwtKey)
+}
+
+// Public Get the key with its public key, which can be shared with the verifiers
+func (k JWTKey) Public() JWTKey {
+	k.Key = publicKey(k.Key)
+	return k
+}
+
+// validateJWTKey Check if the key type matches its algorithm
+func validateJWTKey(key JWTKey) error {
+	valid := false
+	switch publicKey := publicKey(key.Key).(type) {
+	case []byte:
+		valid = key.Algorithm == AlgHS256 && len(publicKey) >= minHMACKeySize
+	case *rsa.PublicKey:
+		valid = key.Algorithm == AlgRS256 && publicKey.N.BitLen() >= minRSAKeySize
+	case *ecdsa.PublicKey:
+		valid = key.Algorithm == AlgES256 && publicKey.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		valid = key.Algorithm == AlgEdDSA && len(publicKey) == ed25519.PublicKeySize
+	}
+
+	if !valid {
+		return fmt.Errorf("key %q is not a valid %s key. %w", key.ID, key.Algorithm, ErrInvalidJWTKey)
+	}
+	return nil
+}
+
+// canSign Check if a key can sign tokens
+func canSign(key JWTKey) bool {
+	switch key.Key.(type) {
+	case []byte, *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return true
+	}
+	return false
+}
+
+// publicKey Get the public key of private keys. Other keys are returned as is.
+func publicKey(key interface{}) interface{} {
+	switch privateKey := key.(type) {
+	case *rsa.PrivateKey:
+		return &privateKey.PublicKey
+	case *ecdsa.PrivateKey:
+		return &privateKey.PublicKey
+	case ed25519.PrivateKey:
+		return privateKey.Public()
+	}
+	return key
+}
+
+// signJWT Sign the input of a token
+func signJWT(key JWTKey, input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+	switch privateKey := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, privateKey)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+
+		// JWS signatures are the fixed size r and s, instead of ASN.1
+		signature := make([]byte, es256SignatureSize)
+		r.FillBytes(signature[:es256SignatureSize/2])
+		s.FillBytes(signature[es256SignatureSize/2:])
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(privateKey, input), nil
+	}
+	return nil, fmt.Errorf("key %q can not sign tokens. %w", key.ID, ErrInvalidJWTKey)
+}
+
+// verifyJWT Verify the signature of a token
+func verifyJWT(key JWTKey, input []byte, signature []byte) bool {
+	digest := sha256.Sum256(input)
+	switch publicKey := publicKey(key.Key).(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, publicKey)
+		mac.Write(input)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != es256SignatureSize {
+			return false
+		}
+
+		r := new(big.Int).SetBytes(signature[:es256SignatureSize/2])
+		s := new(big.Int).SetBytes(signature[es256SignatureSize/2:])
+		return ecdsa.Verify(publicKey, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(publicKey, input, signature)
+	}
+	return false
+}
+
+// encodeSegment Encode a token header or payload
+func encodeSegment(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%s. %w", err, ErrInvalidToken)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeSegment Decode a token header or payload
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%s. %w", err, ErrInvalidToken)
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("%s. %w", err, ErrInvalidToken)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package gocrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWT(t *testing.T) {
+	for _, algorithm := range []string{AlgHS256, AlgRS256, AlgES256, AlgEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := GenerateJWTKey("key-1", algorithm)
+			assert.NoError(t, err)
+
+			issuer, err := NewJWT(JWTConfig{Keys: []JWTKey{key}, Issuer: "auth", Audience: []string{"users-api"}})
+			assert.NoError(t, err)
+
+			token, err := issuer.Sign(Claims{"sub": "user-1", "roles": []string{"admin"}})
+			assert.NoError(t, err)
+			assert.Len(t, strings.Split(token, "."), 3)
+
+			verifierKey := key
+			if algorithm != AlgHS256 {
+				verifierKey = key.Public()
+			}
+
+			verifier, err := NewJWT(JWTConfig{Keys: []JWTKey{verifierKey}, Issuer: "auth", Audience: []string{"users-api", "admin-api"}})
+			assert.NoError(t, err)
+
+			claims, err := verifier.Verify(context.Background(), token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", claims.Subject())
+			assert.Equal(t, "auth", claims.Issuer())
+			assert.Equal(t, []string{"users-api"}, claims.Audience())
+			assert.WithinDuration(t, time.Now().Add(defaultTokenTTL), claims.ExpiresAt(), 2*time.Second)
+
+			_, err = verifier.Sign(Claims{})
+			if algorithm == AlgHS256 {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrUnknownSigningKey, "public keys can not sign tokens")
+			}
+
+			parts := strings.Split(token, ".")
+			forged, _ := encodeSegment(Claims{"sub": "admin", "iss": "auth", "aud": "users-api"})
+			_, err = verifier.Verify(context.Background(), parts[0]+"."+forged+"."+parts[2])
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestJWTClaims(t *testing.T) {
+	key, err := GenerateJWTKey("", AlgHS256)
+	assert.NoError(t, err)
+
+	jwt, err := NewJWT(JWTConfig{Keys: []JWTKey{key}, Issuer: "auth", Audience: []string{"users-api"}, Leeway: 30 * time.Second})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	tests := []struct {
+		name          string
+		claims        Claims
+		expectedError error
+	}{
+		{name: "should accept expired tokens within the leeway", claims: Claims{"exp": now.Add(-20 * time.Second).Unix()}},
+		{name: "should reject expired tokens", claims: Claims{"exp": now.Add(-time.Minute).Unix()}, expectedError: ErrTokenExpired},
+		{name: "should reject tokens without expiry", claims: Claims{"exp": nil}, expectedError: ErrMissingTokenExpiry},
+		{name: "should reject tokens with a non numeric expiry", claims: Claims{"exp": "tomorrow"}, expectedError: ErrMissingTokenExpiry},
+		{name: "should reject tokens not yet valid", claims: Claims{"nbf": now.Add(time.Minute).Unix()}, expectedError: ErrTokenNotYetValid},
+		{name: "should reject other issuers", claims: Claims{"iss": "other"}, expectedError: ErrInvalidIssuer},
+		{name: "should reject other audiences", claims: Claims{"aud": []string{"billing-api"}}, expectedError: ErrInvalidAudience},
+		{name: "should reject tokens without audience", claims: Claims{"aud": nil}, expectedError: ErrInvalidAudience},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := jwt.Sign(test.claims)
+			assert.NoError(t, err)
+
+			_, err = jwt.Verify(context.Background(), token)
+			if test.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, test.expectedError)
+			}
+		})
+	}
+
+	jwt, err = NewJWT(JWTConfig{Keys: []JWTKey{key}, AllowMissingExpiry: true})
+	assert.NoError(t, err)
+	token, err := jwt.Sign(Claims{"exp": nil})
+	assert.NoError(t, err)
+	_, err = jwt.Verify(context.Background(), token)
+	assert.NoError(t, err, "tokens without expiry should be accepted when allowed")
+}
+
+func TestJWTErrors(t *testing.T) {
+	hsKey, err := GenerateJWTKey("hs", AlgHS256)
+	assert.NoError(t, err)
+	rsKey, err := GenerateJWTKey("rs", AlgRS256)
+	assert.NoError(t, err)
+
+	_, err = NewJWT(JWTConfig{})
+	assert.ErrorIs(t, err, ErrInvalidJWTKey)
+	_, err = NewJWT(JWTConfig{Keys: []JWTKey{{ID: "short", Algorithm: AlgHS256, Key: []byte("secret")}}})
+	assert.ErrorIs(t, err, ErrInvalidJWTKey, "short secrets should be rejected")
+	_, err = NewJWT(JWTConfig{Keys: []JWTKey{{ID: "rs", Algorithm: AlgES256, Key: rsKey.Key}}})
+	assert.ErrorIs(t, err, ErrInvalidJWTKey, "keys should match their algorithm")
+	_, err = NewJWT(JWTConfig{Keys: []JWTKey{hsKey}, SigningKeyID: "rs"})
+	assert.ErrorIs(t, err, ErrUnknownSigningKey)
+	_, err = GenerateJWTKey("none", "none")
+	assert.ErrorIs(t, err, ErrUnsupportedJWTAlgorithm)
+
+	jwt, err := NewJWT(JWTConfig{Keys: []JWTKey{hsKey, rsKey.Public()}})
+	assert.NoError(t, err)
+
+	_, err = jwt.Verify(context.Background(), "invalid")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	header, _ := encodeSegment(map[string]string{"alg": "none", "kid": "hs"})
+	payload, _ := encodeSegment(Claims{"sub": "admin"})
+	_, err = jwt.Verify(context.Background(), header+"."+payload+".")
+	assert.ErrorIs(t, err, ErrInvalidSignature, "unsigned tokens should be rejected")
+
+	// HS256 tokens signed with the public key of a RS256 key
+	publicKey, _ := x509.MarshalPKIXPublicKey(rsKey.Public().Key)
+	confused := JWTKey{ID: "rs", Algorithm: AlgHS256, Key: publicKey}
+	header, _ = encodeSegment(map[string]string{"alg": AlgHS256, "kid": "rs"})
+	signature, _ := signJWT(confused, []byte(header+"."+payload))
+	_, err = jwt.Verify(context.Background(), header+"."+payload+"."+base64.RawURLEncoding.EncodeToString(signature))
+	assert.ErrorIs(t, err, ErrInvalidSignature, "algorithms should not be confused")
+
+	header, _ = encodeSegment(map[string]string{"alg": AlgHS256, "kid": "unknown"})
+	_, err = jwt.Verify(context.Background(), header+"."+payload+".")
+	assert.ErrorIs(t, err, ErrUnknownSigningKey)
+}
+
+func TestParseJWTKey(t *testing.T) {
+	esKey, err := GenerateJWTKey("es", AlgES256)
+	assert.NoError(t, err)
+	edKey, err := GenerateJWTKey("ed", AlgEdDSA)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(esKey.Key)
+	assert.NoError(t, err)
+	parsed, err := ParseJWTKey("es", AlgES256, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.True(t, esKey.Key.(*ecdsa.PrivateKey).Equal(parsed.Key))
+
+	der, err = x509.MarshalPKIXPublicKey(edKey.Public().Key)
+	assert.NoError(t, err)
+	parsed, err = ParseJWTKey("ed", AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, edKey.Key.(ed25519.PrivateKey).Public(), parsed.Key)
+
+	parsed, err = ParseJWTKey("hs", AlgHS256, bytes.Repeat([]byte{1}, 32))
+	assert.NoError(t, err)
+	assert.Equal(t, AlgHS256, parsed.Algorithm)
+
+	_, err = ParseJWTKey("es", AlgES256, []byte("not a pem"))
+	assert.ErrorIs(t, err, ErrInvalidJWTKey)
+	_, err = ParseJWTKey("ed", AlgRS256, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.ErrorIs(t, err, ErrInvalidJWTKey)
+}
@@ -0,0 +1,611 @@
+package godb
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultCacheTTL      = time.Minute
+	defaultCacheCapacity = 1024
+)
+
+type cacheContextKey string
+
+const (
+	cacheOptionsKey cacheContextKey = "godb-cache-options"
+)
+
+var (
+	readTablesRegex  = regexp.MustCompile("(?i)\\b(?:FROM|JOIN)\\s+([\\w.\"`\\[\\]]+)")
+	writeTablesRegex = regexp.MustCompile(
+		"(?i)\\b(?:INSERT\\s+(?:OR\\s+\\w+\\s+)?(?:IGNORE\\s+)?INTO|REPLACE\\s+INTO|UPDATE|DELETE\\s+FROM|" +
+			"TRUNCATE\\s+(?:TABLE\\s+)?|ALTER\\s+TABLE|DROP\\s+TABLE\\s+(?:IF\\s+EXISTS\\s+)?)\\s*([\\w.\"`\\[\\]]+)",
+	)
+)
+
+// CacheBackend defines the storage used by the query cache.
+//
+// Values are the JSON encoded query results. Tags are the names of the tables
+// read by the query plus any tag given through CacheOptions.
+type CacheBackend interface {
+	// Get returns the value stored for key, if any and not expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value for key during ttl and associates it with the given tags.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// Delete removes the given keys.
+	Delete(ctx context.Context, keys ...string) error
+	// InvalidateTags removes every key associated with at least one of the given tags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// CacheOptions defines how the result of a query is cached
+type CacheOptions struct {
+	// TTL is how long the result is kept. Zero means CacheConfig.DefaultTTL.
+	TTL time.Duration
+	// Tags are extra invalidation tags added to the tables read by the query.
+	Tags []string
+	// Disabled bypasses the cache even if the query is listed in CacheConfig.Queries.
+	Disabled bool
+}
+
+// CacheConfig defines the query cache configs
+type CacheConfig struct {
+	// Backend stores the cached results. Defaults to an in-memory LRU cache.
+	Backend CacheBackend
+	// DefaultTTL is used when CacheOptions.TTL is zero. Defaults to one minute.
+	DefaultTTL time.Duration
+	// Queries opts in queries by their exact text, including calls that do not
+	// take a context such as Get and Select.
+	Queries map[string]CacheOptions
+}
+
+// CachedDB defines a database whose Get and Select results can be cached
+type CachedDB interface {
+	DB
+
+	// InvalidateCache removes every cached result associated with the given tags.
+	// Tags are table names, matched without quotes, schema and case, or the exact tags
+	// given through CacheOptions.
+	InvalidateCache(ctx context.Context, tags ...string) error
+}
+
+// WithCache Opts in the Get and Select calls made with the returned context
+func WithCache(ctx context.Context, opts CacheOptions) context.Context {
+	return context.WithValue(ctx, cacheOptionsKey, opts)
+}
+
+// WithoutCache Bypasses the cache for the Get and Select calls made with the returned context
+func WithoutCache(ctx context.Context) context.Context {
+	return WithCache(ctx, CacheOptions{Disabled: true})
+}
+
+// NewCachedDB Returns a DB that caches the results of Get and Select calls that opted in,
+// either through WithCache or CacheConfig.Queries.
+//
+// Concurrent identical queries are executed only once. Exec calls invalidate the results
+// read from the tables they modify; inside a transaction this happens on Commit.
+// Statements executed through prepared statements are not tracked.
+//
+// Results are cached as JSON, so cached reads only fill the fields that are encoded by
+// encoding/json: fields tagged json:"-" and unexported fields are left empty.
+func NewCachedDB(db DB, config CacheConfig) CachedDB {
+	if config.Backend == nil {
+		config.Backend = NewLRUCache(defaultCacheCapacity)
+	}
+
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = defaultCacheTTL
+	}
+
+	return &cachedDB{DB: db, config: config}
+}
+
+// cachedDB implements the CachedDB interface
+type cachedDB struct {
+	DB
+	config CacheConfig
+	group  singleflight.Group
+	// generation changes on every invalidation, so the results loaded before it are not stored
+	generation atomic.Uint64
+}
+
+// InvalidateCache
+func (cdb *cachedDB) InvalidateCache(ctx context.Context, tags ...string) error {
+	// Custom tags are kept as they are, while table names are normalized as the read tables
+	all := make([]string, 0, 2*len(tags))
+	seen := map[string]struct{}{}
+	for _, tag := range tags {
+		for _, candidate := range []string{tag, normalizeTableName(tag)} {
+			if _, ok := seen[candidate]; !ok && candidate != "" {
+				seen[candidate] = struct{}{}
+				all = append(all, candidate)
+			}
+		}
+	}
+	return cdb.invalidateTags(ctx, all...)
+}
+
+// invalidateTags removes the cached results of the tags and discards the results being loaded
+func (cdb *cachedDB) invalidateTags(ctx context.Context, tags ...string) error {
+	cdb.generation.Add(1)
+	return cdb.config.Backend.InvalidateTags(ctx, tags...)
+}
+
+// cacheOptions returns the cache options for the given query
+func (cdb *cachedDB) cacheOptions(ctx context.Context, query string) (CacheOptions, bool) {
+	opts, ok := ctx.Value(cacheOptionsKey).(CacheOptions)
+	if !ok {
+		opts, ok = cdb.config.Queries[query]
+	}
+
+	if !ok || opts.Disabled {
+		return CacheOptions{}, false
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = cdb.config.DefaultTTL
+	}
+	return opts, true
+}
+
+// cachedRead loads dest from the cache or using load
+func (cdb *cachedDB) cachedRead(
+	ctx context.Context,
+	operation string,
+	dest interface{},
+	query string,
+	args []interface{},
+	load func(dest interface{}) error,
+) error {
+	destValue := reflect.ValueOf(dest)
+	opts, ok := cdb.cacheOptions(ctx, query)
+
+	if !ok || destValue.Kind() != reflect.Pointer || destValue.IsNil() {
+		return load(dest)
+	}
+
+	destType := destValue.Type().Elem()
+	key := cacheKey(operation, destType, query, args)
+
+	if data, found, err := cdb.config.Backend.Get(ctx, key); err == nil && found {
+		if err := decodeCachedValue(data, destValue); err == nil {
+			return nil
+		}
+	}
+
+	// Loads started after an invalidation do not join the ones started before it
+	generation := cdb.generation.Load()
+	data, err, _ := cdb.group.Do(fmt.Sprintf("%s|%d", key, generation), func() (interface{}, error) {
+		value := reflect.New(destType)
+		if err := load(value.Interface()); err != nil {
+			return nil, err
+		}
+
+		bs, err := json.Marshal(value.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		// Results loaded while a write invalidated the cache can be stale, so they are not kept
+		if cdb.generation.Load() != generation {
+			return bs, nil
+		}
+
+		tags := append(readTables(query), opts.Tags...)
+		// The cache is best effort. A failure to store the result must not fail the query.
+		_ = cdb.config.Backend.Set(ctx, key, bs, opts.TTL, tags)
+		if cdb.generation.Load() != generation {
+			_ = cdb.config.Backend.Delete(ctx, key)
+		}
+		return bs, nil
+	})
+
+	if err != nil {
+		return err
+	}
+	return decodeCachedValue(data.([]byte), destValue)
+}
+
+// invalidateWrites invalidates the cached results read from the tables modified by query
+func (cdb *cachedDB) invalidateWrites(ctx context.Context, query string) {
+	if tables := writeTables(query); len(tables) > 0 {
+		_ = cdb.invalidateTags(ctx, tables...)
+	}
+}
+
+// Get
+func (cdb *cachedDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return cdb.cachedRead(context.Background(), "get", dest, query, args, func(dest interface{}) error {
+		return cdb.DB.Get(dest, query, args...)
+	})
+}
+
+// GetContext
+func (cdb *cachedDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return cdb.cachedRead(ctx, "get", dest, query, args, func(dest interface{}) error {
+		return cdb.DB.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// Select
+func (cdb *cachedDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return cdb.cachedRead(context.Background(), "select", dest, query, args, func(dest interface{}) error {
+		return cdb.DB.Select(dest, query, args...)
+	})
+}
+
+// SelectContext
+func (cdb *cachedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return cdb.cachedRead(ctx, "select", dest, query, args, func(dest interface{}) error {
+		return cdb.DB.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+// Exec
+func (cdb *cachedDB) Exec(query string, args ...any) (sql.Result, error) {
+	defer cdb.invalidateWrites(context.Background(), query)
+	return cdb.DB.Exec(query, args...)
+}
+
+// ExecContext
+func (cdb *cachedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer cdb.invalidateWrites(ctx, query)
+	return cdb.DB.ExecContext(ctx, query, args...)
+}
+
+// MustExec
+func (cdb *cachedDB) MustExec(query string, args ...interface{}) sql.Result {
+	defer cdb.invalidateWrites(context.Background(), query)
+	return cdb.DB.MustExec(query, args...)
+}
+
+// MustExecContext
+func (cdb *cachedDB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
+	defer cdb.invalidateWrites(ctx, query)
+	return cdb.DB.MustExecContext(ctx, query, args...)
+}
+
+// NamedExec
+func (cdb *cachedDB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	defer cdb.invalidateWrites(context.Background(), query)
+	return cdb.DB.NamedExec(query, arg)
+}
+
+// NamedExecContext
+func (cdb *cachedDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	defer cdb.invalidateWrites(ctx, query)
+	return cdb.DB.NamedExecContext(ctx, query, arg)
+}
+
+// BeginTx
+func (cdb *cachedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := cdb.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return tx, err
+	}
+	return cdb.newCachedTx(tx), nil
+}
+
+// Begin
+func (cdb *cachedDB) Begin() (Tx, error) {
+	tx, err := cdb.DB.Begin()
+	if err != nil {
+		return tx, err
+	}
+	return cdb.newCachedTx(tx), nil
+}
+
+// MustBegin
+func (cdb *cachedDB) MustBegin() Tx {
+	return cdb.newCachedTx(cdb.DB.MustBegin())
+}
+
+// MustBeginTx
+func (cdb *cachedDB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) Tx {
+	return cdb.newCachedTx(cdb.DB.MustBeginTx(ctx, opts))
+}
+
+// Conn
+func (cdb *cachedDB) Conn(ctx context.Context) (Conn, error) {
+	conn, err := cdb.DB.Conn(ctx)
+	if err != nil {
+		return conn, err
+	}
+	return &cachedConn{Conn: conn, cdb: cdb}, nil
+}
+
+// newCachedTx wraps tx so its writes invalidate the cache on commit
+func (cdb *cachedDB) newCachedTx(tx Tx) Tx {
+	return &cachedTx{Tx: tx, cdb: cdb, tables: map[string]struct{}{}}
+}
+
+// cachedTx records the tables modified inside a transaction
+type cachedTx struct {
+	Tx
+	cdb    *cachedDB
+	mu     sync.Mutex
+	tables map[string]struct{}
+}
+
+// track records the tables modified by query
+func (ct *cachedTx) track(query string) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for _, table := range writeTables(query) {
+		ct.tables[table] = struct{}{}
+	}
+}
+
+// Commit
+func (ct *cachedTx) Commit() error {
+	if err := ct.Tx.Commit(); err != nil {
+		return err
+	}
+
+	ct.mu.Lock()
+	tables := make([]string, 0, len(ct.tables))
+	for table := range ct.tables {
+		tables = append(tables, table)
+	}
+	ct.mu.Unlock()
+
+	if len(tables) > 0 {
+		_ = ct.cdb.invalidateTags(context.Background(), tables...)
+	}
+	return nil
+}
+
+// Exec
+func (ct *cachedTx) Exec(query string, args ...any) (sql.Result, error) {
+	ct.track(query)
+	return ct.Tx.Exec(query, args...)
+}
+
+// ExecContext
+func (ct *cachedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ct.track(query)
+	return ct.Tx.ExecContext(ctx, query, args...)
+}
+
+// MustExec
+func (ct *cachedTx) MustExec(query string, args ...interface{}) sql.Result {
+	ct.track(query)
+	return ct.Tx.MustExec(query, args...)
+}
+
+// MustExecContext
+func (ct *cachedTx) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
+	ct.track(query)
+	return ct.Tx.MustExecContext(ctx, query, args...)
+}
+
+// NamedExec
+func (ct *cachedTx) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	ct.track(query)
+	return ct.Tx.NamedExec(query, arg)
+}
+
+// NamedExecContext
+func (ct *cachedTx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	ct.track(query)
+	return ct.Tx.NamedExecContext(ctx, query, arg)
+}
+
+// cachedConn invalidates the cache on writes made through a single connection
+type cachedConn struct {
+	Conn
+	cdb *cachedDB
+}
+
+// ExecContext
+func (cc *cachedConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer cc.cdb.invalidateWrites(ctx, query)
+	return cc.Conn.ExecContext(ctx, query, args...)
+}
+
+// BeginTx
+func (cc *cachedConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := cc.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return tx, err
+	}
+	return cc.cdb.newCachedTx(tx), nil
+}
+
+// decodeCachedValue decodes data into a fresh value and assigns it to dest
+func decodeCachedValue(data []byte, dest reflect.Value) error {
+	value := reflect.New(dest.Type().Elem())
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return err
+	}
+	dest.Elem().Set(value.Elem())
+	return nil
+}
+
+// cacheKey builds the cache key of a query
+func cacheKey(operation string, destType reflect.Type, query string, args []interface{}) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s|%s|%s", operation, destType.String(), query)
+	for _, arg := range args {
+		fmt.Fprintf(hash, "|%T:%v", arg, arg)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// readTables returns the tables read by query
+func readTables(query string) []string {
+	return matchTables(readTablesRegex, query)
+}
+
+// writeTables returns the tables modified by query
+func writeTables(query string) []string {
+	return matchTables(writeTablesRegex, query)
+}
+
+// matchTables returns the unique table names captured by regex
+func matchTables(regex *regexp.Regexp, query string) []string {
+	tables := []string{}
+	seen := map[string]struct{}{}
+
+	for _, match := range regex.FindAllStringSubmatch(query, -1) {
+		table := normalizeTableName(match[1])
+		if _, ok := seen[table]; ok || table == "" {
+			continue
+		}
+		seen[table] = struct{}{}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// normalizeTableName removes quotes and schema from a table name
+func normalizeTableName(name string) string {
+	name = strings.Trim(name, "\"`[]")
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		name = name[index+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "\"`[]"))
+}
+
+// LRUCache is an in-memory CacheBackend that evicts the least recently used entries
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+}
+
+// lruEntry defines a LRUCache entry
+type lruEntry struct {
+	key       string
+	value     []byte
+	tags      []string
+	expiresAt time.Time
+}
+
+// NewLRUCache Creates a new in-memory LRU cache holding at most capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = defaultCacheCapacity
+	}
+
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+		tags:     map[string]map[string]struct{}{},
+	}
+}
+
+// Len returns the number of entries in the cache, including expired ones not yet evicted
+func (lru *LRUCache) Len() int {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	return lru.order.Len()
+}
+
+// Get
+func (lru *LRUCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	element, ok := lru.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		lru.remove(element)
+		return nil, false, nil
+	}
+
+	lru.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set
+func (lru *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if element, ok := lru.items[key]; ok {
+		lru.remove(element)
+	}
+
+	entry := &lruEntry{key: key, value: value, tags: tags, expiresAt: time.Now().Add(ttl)}
+	lru.items[key] = lru.order.PushFront(entry)
+
+	for _, tag := range tags {
+		if _, ok := lru.tags[tag]; !ok {
+			lru.tags[tag] = map[string]struct{}{}
+		}
+		lru.tags[tag][key] = struct{}{}
+	}
+
+	for lru.order.Len() > lru.capacity {
+		lru.remove(lru.order.Back())
+	}
+	return nil
+}
+
+// Delete
+func (lru *LRUCache) Delete(_ context.Context, keys ...string) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := lru.items[key]; ok {
+			lru.remove(element)
+		}
+	}
+	return nil
+}
+
+// InvalidateTags
+func (lru *LRUCache) InvalidateTags(_ context.Context, tags ...string) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range lru.tags[tag] {
+			if element, ok := lru.items[key]; ok {
+				lru.remove(element)
+			}
+		}
+		delete(lru.tags, tag)
+	}
+	return nil
+}
+
+// remove removes an element and its tag references. Must be called holding the lock.
+func (lru *LRUCache) remove(element *list.Element) {
+	entry := element.Value.(*lruEntry)
+	lru.order.Remove(element)
+	delete(lru.items, entry.key)
+
+	for _, tag := range entry.tags {
+		if keys, ok := lru.tags[tag]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(lru.tags, tag)
+			}
+		}
+	}
+}
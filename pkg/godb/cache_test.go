@@ -0,0 +1,330 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSQLiteTestDB creates an in-memory SQLite database with a single connection
+func newSQLiteTestDB(t *testing.T, ddl string) DB {
+	db, err := NewDB(DBConfig{
+		User:             "admin",
+		Password:         "qwerty",
+		Database:         "test-db",
+		DatabaseType:     SQLiteDB,
+		ConnectTimeout:   time.Millisecond * 500,
+		ConnectionParams: SQLiteDefaultParams,
+	})
+	if err != nil {
+		assert.FailNow(t, "failed to create new db: %s", err.Error())
+	}
+
+	// Every in-memory connection has its own database
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	if ddl != "" {
+		if _, err = db.Exec(ddl); err != nil {
+			assert.FailNow(t, "failed to execute ddl: %s", err.Error())
+		}
+	}
+	return db
+}
+
+func Test_CachedDB(t *testing.T) {
+	type customData struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	ddl := `
+		CREATE TABLE custom_table (
+			id INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL
+		);
+		INSERT INTO custom_table (id, name) VALUES (1, 'John Doe'), (2, 'Jane Doe');
+	`
+
+	tests := []struct {
+		name   string
+		assert func(t *testing.T, db CachedDB, backend *LRUCache)
+	}{
+		{
+			name: "should not cache queries that did not opt in",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				var data customData
+				assert.NoError(t, db.GetContext(context.Background(), &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, "John Doe", data.Name)
+				assert.Equal(t, 0, backend.Len(), "nothing should be cached")
+			},
+		},
+		{
+			name: "should cache get and select results",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				ctx := WithCache(context.Background(), CacheOptions{TTL: time.Minute})
+
+				var data customData
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+
+				var list []customData
+				assert.NoError(t, db.SelectContext(ctx, &list, "SELECT * FROM custom_table ORDER BY id"))
+				assert.Equal(t, 2, backend.Len(), "results should be cached")
+
+				// Change the data without going through the cached db
+				_, err := db.(*cachedDB).DB.Exec("UPDATE custom_table SET name = 'Changed'")
+				assert.NoError(t, err)
+
+				data = customData{}
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, customData{ID: 1, Name: "John Doe"}, data, "should read the cached value")
+
+				list = nil
+				assert.NoError(t, db.SelectContext(ctx, &list, "SELECT * FROM custom_table ORDER BY id"))
+				assert.Len(t, list, 2)
+				assert.Equal(t, "Jane Doe", list[1].Name, "should read the cached value")
+
+				assert.NoError(t, db.InvalidateCache(ctx, "custom_table"))
+				assert.Equal(t, 0, backend.Len(), "cache should be empty")
+
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, "Changed", data.Name)
+			},
+		},
+		{
+			name: "should cache queries configured by text",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				var name string
+				assert.NoError(t, db.Get(&name, "SELECT name FROM custom_table WHERE id = 2"))
+				assert.Equal(t, "Jane Doe", name)
+				assert.Equal(t, 1, backend.Len())
+
+				assert.NoError(t, db.GetContext(WithoutCache(context.Background()), &name, "SELECT name FROM custom_table WHERE id = 2"))
+				assert.Equal(t, 1, backend.Len())
+			},
+		},
+		{
+			name: "should invalidate on exec",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				ctx := WithCache(context.Background(), CacheOptions{})
+
+				var data customData
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, 1, backend.Len())
+
+				_, err := db.Exec("UPDATE custom_table SET name = ? WHERE id = ?", "Johnny", 1)
+				assert.NoError(t, err)
+				assert.Equal(t, 0, backend.Len(), "exec should invalidate the cache")
+
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, "Johnny", data.Name)
+			},
+		},
+		{
+			name: "should invalidate on transaction commit",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				ctx := WithCache(context.Background(), CacheOptions{Tags: []string{"users"}})
+
+				var data customData
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+
+				tx, err := db.BeginTx(ctx, nil)
+				assert.NoError(t, err)
+				_, err = tx.NamedExec("DELETE FROM custom_table WHERE id = :id", data)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, backend.Len(), "should only invalidate on commit")
+
+				assert.NoError(t, tx.Commit())
+				assert.Equal(t, 0, backend.Len(), "commit should invalidate the cache")
+			},
+		},
+		{
+			name: "should invalidate custom tags as they are",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				var data customData
+				ctx := WithCache(context.Background(), CacheOptions{Tags: []string{"User:42"}})
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				ctx = WithCache(context.Background(), CacheOptions{Tags: []string{"tenant.acme"}})
+				assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 2))
+				assert.Equal(t, 2, backend.Len())
+
+				assert.NoError(t, db.InvalidateCache(ctx, "User:42"))
+				assert.Equal(t, 1, backend.Len())
+				assert.NoError(t, db.InvalidateCache(ctx, "tenant.acme"))
+				assert.Equal(t, 0, backend.Len())
+			},
+		},
+		{
+			name: "should not cache errors",
+			assert: func(t *testing.T, db CachedDB, backend *LRUCache) {
+				ctx := WithCache(context.Background(), CacheOptions{})
+
+				var data customData
+				assert.Error(t, db.GetContext(ctx, &data, "SELECT * FROM custom_table WHERE id = ?", 100))
+				assert.Equal(t, 0, backend.Len())
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend := NewLRUCache(10)
+			db := NewCachedDB(newSQLiteTestDB(t, ddl), CacheConfig{
+				Backend: backend,
+				Queries: map[string]CacheOptions{
+					"SELECT name FROM custom_table WHERE id = 2": {},
+				},
+			})
+			test.assert(t, db, backend)
+		})
+	}
+}
+
+func Test_CachedDBSingleflight(t *testing.T) {
+	var (
+		calls   int32
+		release = make(chan struct{})
+		dbMock  = NewMockDB()
+	)
+
+	dbMock.CallbackGetContext = func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		*dest.(*string) = "value"
+		return nil
+	}
+
+	db := NewCachedDB(dbMock, CacheConfig{})
+	ctx := WithCache(context.Background(), CacheOptions{})
+
+	wg := sync.WaitGroup{}
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, db.GetContext(ctx, &results[i], "SELECT value FROM table_name"))
+		}(i)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "query should run once")
+	for _, result := range results {
+		assert.Equal(t, "value", result)
+	}
+
+	dbMock.CallbackGetContext = func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+		return errors.New("should not be called")
+	}
+	var result string
+	assert.NoError(t, db.GetContext(ctx, &result, "SELECT value FROM table_name"))
+	assert.Equal(t, "value", result)
+}
+
+func Test_CachedDBStaleLoad(t *testing.T) {
+	var (
+		loading = make(chan struct{})
+		release = make(chan struct{})
+		dbMock  = NewMockDB()
+		backend = NewLRUCache(10)
+	)
+
+	dbMock.CallbackGetContext = func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+		close(loading)
+		<-release
+		*dest.(*string) = "stale"
+		return nil
+	}
+	dbMock.CallbackExec = func(query string, args ...any) (sql.Result, error) {
+		return &ResultMock{}, nil
+	}
+
+	db := NewCachedDB(dbMock, CacheConfig{Backend: backend})
+	ctx := WithCache(context.Background(), CacheOptions{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var result string
+		assert.NoError(t, db.GetContext(ctx, &result, "SELECT value FROM table_name"))
+		assert.Equal(t, "stale", result)
+	}()
+
+	<-loading
+	_, err := db.Exec("UPDATE table_name SET value = 'fresh'")
+	assert.NoError(t, err)
+	close(release)
+	<-done
+
+	assert.Equal(t, 0, backend.Len(), "results loaded before an invalidation should not be cached")
+}
+
+func Test_CachedDBIgnoredFields(t *testing.T) {
+	type customData struct {
+		ID     int    `db:"id"`
+		Secret string `db:"secret" json:"-"`
+	}
+
+	dbMock := NewMockDB()
+	dbMock.CallbackGetContext = func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+		*dest.(*customData) = customData{ID: 1, Secret: "value"}
+		return nil
+	}
+
+	db := NewCachedDB(dbMock, CacheConfig{})
+	ctx := WithCache(context.Background(), CacheOptions{})
+
+	var data customData
+	assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM table_name"))
+	assert.NoError(t, db.GetContext(ctx, &data, "SELECT * FROM table_name"))
+	assert.Equal(t, customData{ID: 1}, data, "fields skipped by encoding/json should not be cached")
+}
+
+func Test_LRUCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute, []string{"t1"}))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("2"), time.Minute, []string{"t2"}))
+
+	// a becomes the most recently used entry
+	_, found, _ := cache.Get(ctx, "a")
+	assert.True(t, found)
+
+	assert.NoError(t, cache.Set(ctx, "c", []byte("3"), time.Minute, []string{"t1"}))
+	_, found, _ = cache.Get(ctx, "b")
+	assert.False(t, found, "b should be evicted")
+
+	assert.NoError(t, cache.InvalidateTags(ctx, "t1"))
+	assert.Equal(t, 0, cache.Len())
+
+	assert.NoError(t, cache.Set(ctx, "d", []byte("4"), time.Millisecond, nil))
+	time.Sleep(time.Millisecond * 5)
+	_, found, _ = cache.Get(ctx, "d")
+	assert.False(t, found, "d should be expired")
+
+	assert.NoError(t, cache.Set(ctx, "e", []byte("5"), time.Minute, nil))
+	assert.NoError(t, cache.Delete(ctx, "e"))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func Test_QueryTables(t *testing.T) {
+	assert.Equal(t, []string{"users", "orders"}, readTables(`SELECT * FROM public."users" u JOIN orders o ON o.user_id = u.id`))
+	assert.Equal(t, []string{"users"}, writeTables("INSERT INTO `users` (name) VALUES (?)"))
+	assert.Equal(t, []string{"users"}, writeTables("update users set name = ?"))
+	assert.Equal(t, []string{"users"}, writeTables("DELETE FROM users WHERE id = ?"))
+	assert.Equal(t, []string{"users"}, writeTables("TRUNCATE TABLE users"))
+	assert.Empty(t, writeTables("SELECT 1"))
+}
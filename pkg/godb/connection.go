@@ -64,6 +64,7 @@ type Conn interface {
 type customConn struct {
 	testErr error
 	conn    *sqlx.Conn
+	hooks   queryHooks
 }
 
 // pushTestError
@@ -85,7 +86,7 @@ func (c *customConn) Close() error {
 
 // ExecContext
 func (c *customConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return c.conn.ExecContext(ctx, query, args...)
+	return c.hooks.exec(ctx, OperationExec, query, args, c.conn.ExecContext)
 }
 
 // PingContext
@@ -103,7 +104,7 @@ func (c *customConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, erro
 	if tx, err := c.beginTx(ctx, opts); err != nil {
 		return &customTx{}, err
 	} else {
-		return &customTx{tx: tx}, nil
+		return &customTx{tx: tx, hooks: c.hooks}, nil
 	}
 }
 
@@ -117,16 +118,12 @@ func (c *customConn) beginTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx
 
 // GetContext
 func (c *customConn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return c.conn.GetContext(ctx, dest, query, args...)
+	return c.hooks.scan(ctx, OperationGet, dest, query, args, c.conn.GetContext)
 }
 
 // PrepareContext
 func (c *customConn) PrepareContext(ctx context.Context, query string) (Stmt, error) {
-	if stmt, err := c.prepareContext(ctx, query); err != nil {
-		return &customStmt{}, err
-	} else {
-		return &customStmt{stmt: stmt}, nil
-	}
+	return c.hooks.prepare(ctx, query, c.prepareContext)
 }
 
 // prepareContext
@@ -139,16 +136,12 @@ func (c *customConn) prepareContext(ctx context.Context, query string) (*sqlx.St
 
 // QueryRowContext
 func (c *customConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
-	return &customRow{row: c.conn.QueryRowxContext(ctx, query, args...)}
+	return c.hooks.row(ctx, query, args, c.conn.QueryRowxContext)
 }
 
 // QueryContext
 func (c *customConn) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	if rows, err := c.queryContext(ctx, query, args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	return c.hooks.rows(ctx, OperationQuery, query, args, c.queryContext)
 }
 
 // queryContext
@@ -166,5 +159,5 @@ func (c *customConn) Rebind(query string) string {
 
 // SelectContext
 func (c *customConn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return c.conn.SelectContext(ctx, dest, query, args...)
+	return c.hooks.scan(ctx, OperationSelect, dest, query, args, c.conn.SelectContext)
 }
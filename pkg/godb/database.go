@@ -6,6 +6,7 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -71,11 +72,21 @@ func NewDB(config DBConfig) (DB, error) {
 
 	ctx, cancel := context.WithTimeout(ctx, config.ConnectTimeout)
 
-	if dbx, err := connect(ctx, cancel, dbType, dsn); err != nil {
+	dbx, err := connect(ctx, cancel, dbType, dsn)
+	if err != nil {
 		return &customDB{}, err
-	} else {
-		return &customDB{db: dbx}, nil
 	}
+
+	db := &customDB{db: dbx, hooks: queryHooks{dbType: config.DatabaseType}}
+	if config.QueryTimeout > 0 {
+		db.AddHook(queryTimeoutHook{timeout: config.QueryTimeout})
+	}
+	db.AddHook(config.Hooks...)
+
+	if config.StmtCacheSize > 0 {
+		return NewStmtCacheDB(db, config.StmtCacheSize), nil
+	}
+	return db, nil
 }
 
 // connect Open a new databse connection
@@ -190,6 +201,12 @@ type DB interface {
 	// Stats returns database statistics.
 	Stats() sql.DBStats
 
+	// godb
+
+	// AddHook installs query hooks on this DB. The godb.Tx, godb.Conn, godb.Stmt and
+	// godb.NamedStmt created afterwards inherit them. See QueryHook.
+	AddHook(hooks ...QueryHook)
+
 	// sqlx
 
 	// BeginTx begins a transaction and returns an godb.Tx instead of an *sql.Tx.
@@ -297,6 +314,14 @@ type DBConfig struct {
 	DatabaseType     DBType
 	ConnectTimeout   time.Duration
 	ConnectionParams DBConnectionParams
+	// StmtCacheSize enables the prepared statement cache when greater than zero.
+	// See NewStmtCacheDB.
+	StmtCacheSize int
+	// Hooks are the query hooks installed on the DB. See QueryHook.
+	Hooks []QueryHook
+	// QueryTimeout is the timeout of statements whose context has no deadline, including
+	// the methods without a context. Zero disables it. See WithQueryTimeout.
+	QueryTimeout time.Duration
 }
 
 // dsn return data source name
@@ -366,6 +391,8 @@ func prepareConnectionParams(connectionParams DBConnectionParams) string {
 type customDB struct {
 	db      *sqlx.DB
 	testErr error
+	hooks   queryHooks
+	mutex   sync.RWMutex
 }
 
 // pushTestError
@@ -380,6 +407,20 @@ func (cdb *customDB) popTestError() error {
 	return err
 }
 
+// getHooks returns the installed query hooks
+func (cdb *customDB) getHooks() queryHooks {
+	cdb.mutex.RLock()
+	defer cdb.mutex.RUnlock()
+	return cdb.hooks
+}
+
+// AddHook
+func (cdb *customDB) AddHook(hooks ...QueryHook) {
+	cdb.mutex.Lock()
+	defer cdb.mutex.Unlock()
+	cdb.hooks = cdb.hooks.with(hooks...)
+}
+
 // Close
 func (cdb *customDB) Close() error {
 	return cdb.db.Close()
@@ -392,12 +433,12 @@ func (cdb *customDB) Driver() driver.Driver {
 
 // Exec
 func (cdb *customDB) Exec(query string, args ...any) (sql.Result, error) {
-	return cdb.db.Exec(query, args...)
+	return cdb.ExecContext(context.Background(), query, args...)
 }
 
 // ExecContext
 func (cdb *customDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return cdb.db.ExecContext(ctx, query, args...)
+	return cdb.getHooks().exec(ctx, OperationExec, query, args, cdb.db.ExecContext)
 }
 
 // Ping
@@ -440,7 +481,7 @@ func (cdb *customDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, erro
 	if tx, err := cdb.beginTx(ctx, opts); err != nil {
 		return &customTx{}, err
 	} else {
-		return &customTx{tx: tx}, nil
+		return &customTx{tx: tx, hooks: cdb.getHooks()}, nil
 	}
 }
 
@@ -454,19 +495,7 @@ func (cdb *customDB) beginTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx
 
 // Begin
 func (cdb *customDB) Begin() (Tx, error) {
-	if tx, err := cdb.begin(); err != nil {
-		return &customTx{}, err
-	} else {
-		return &customTx{tx: tx}, nil
-	}
-}
-
-// begin
-func (cdb *customDB) begin() (*sqlx.Tx, error) {
-	if cdb.testErr != nil {
-		return &sqlx.Tx{}, cdb.popTestError()
-	}
-	return cdb.db.Beginx()
+	return cdb.BeginTx(context.Background(), nil)
 }
 
 // BindNamed
@@ -479,7 +508,7 @@ func (cdb *customDB) Conn(ctx context.Context) (Conn, error) {
 	if conn, err := cdb.conn(ctx); err != nil {
 		return &customConn{}, err
 	} else {
-		return &customConn{conn: conn}, nil
+		return &customConn{conn: conn, hooks: cdb.getHooks()}, nil
 	}
 }
 
@@ -498,12 +527,12 @@ func (cdb *customDB) DriverName() string {
 
 // Get
 func (cdb *customDB) Get(dest interface{}, query string, args ...interface{}) error {
-	return cdb.db.Get(dest, query, args...)
+	return cdb.GetContext(context.Background(), dest, query, args...)
 }
 
 // GetContext
 func (cdb *customDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return cdb.db.GetContext(ctx, dest, query, args...)
+	return cdb.getHooks().scan(ctx, OperationGet, dest, query, args, cdb.db.GetContext)
 }
 
 // MapperFunc
@@ -513,92 +542,79 @@ func (cdb *customDB) MapperFunc(mf func(string) string) {
 
 // MustBegin
 func (cdb *customDB) MustBegin() Tx {
-	return &customTx{tx: cdb.db.MustBegin()}
+	return &customTx{tx: cdb.db.MustBegin(), hooks: cdb.getHooks()}
 }
 
 // MustBeginTx
 func (cdb *customDB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) Tx {
-	return &customTx{tx: cdb.db.MustBeginTx(ctx, opts)}
+	return &customTx{tx: cdb.db.MustBeginTx(ctx, opts), hooks: cdb.getHooks()}
 }
 
 // MustExec
 func (cdb *customDB) MustExec(query string, args ...interface{}) sql.Result {
-	return cdb.db.MustExec(query, args...)
+	return mustExec(cdb.ExecContext(context.Background(), query, args...))
 }
 
 // MustExecContext
 func (cdb *customDB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
-	return cdb.db.MustExecContext(ctx, query, args...)
+	return mustExec(cdb.ExecContext(ctx, query, args...))
 }
 
 // NamedExec
 func (cdb *customDB) NamedExec(query string, arg interface{}) (sql.Result, error) {
-	return cdb.db.NamedExec(query, arg)
+	return cdb.NamedExecContext(context.Background(), query, arg)
 }
 
 // NamedExecContext
 func (cdb *customDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
-	return cdb.db.NamedExecContext(ctx, query, arg)
+	hooks := cdb.getHooks()
+	return hooks.exec(ctx, OperationNamedExec, query, []interface{}{arg},
+		func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+			query, args, err := hooks.bindNamed(cdb.db, query, namedArg(args))
+			if err != nil {
+				return nil, err
+			}
+			return cdb.db.ExecContext(ctx, query, args...)
+		},
+	)
 }
 
 // NamedQuery
 func (cdb *customDB) NamedQuery(query string, arg interface{}) (Rows, error) {
-	if rows, err := cdb.namedQuery(query, arg); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
-}
-
-// namedQuery
-func (cdb *customDB) namedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
-	if cdb.testErr != nil {
-		return &sqlx.Rows{}, cdb.popTestError()
-	}
-	return cdb.db.NamedQuery(query, arg)
+	return cdb.NamedQueryContext(context.Background(), query, arg)
 }
 
 // NamedQueryContext
 func (cdb *customDB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (Rows, error) {
-	if rows, err := cdb.namedQueryContext(ctx, query, arg); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	hooks := cdb.getHooks()
+	return hooks.rows(ctx, OperationNamedQuery, query, []interface{}{arg},
+		func(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+			return cdb.namedQueryContext(ctx, hooks, query, namedArg(args))
+		},
+	)
 }
 
 // namedQueryContext
-func (cdb *customDB) namedQueryContext(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+func (cdb *customDB) namedQueryContext(ctx context.Context, hooks queryHooks, query string, arg interface{}) (*sqlx.Rows, error) {
 	if cdb.testErr != nil {
 		return &sqlx.Rows{}, cdb.popTestError()
 	}
-	return cdb.db.NamedQueryContext(ctx, query, arg)
-}
 
-// PrepareNamed
-func (cdb *customDB) PrepareNamed(query string) (NamedStmt, error) {
-	if namedStmt, err := cdb.prepareNamed(query); err != nil {
-		return &customNamedStmt{}, err
-	} else {
-		return &customNamedStmt{namedStmt: namedStmt}, nil
+	query, args, err := hooks.bindNamed(cdb.db, query, arg)
+	if err != nil {
+		return nil, err
 	}
+	return cdb.db.QueryxContext(ctx, query, args...)
 }
 
-// prepareNamed
-func (cdb *customDB) prepareNamed(query string) (*sqlx.NamedStmt, error) {
-	if cdb.testErr != nil {
-		return &sqlx.NamedStmt{}, cdb.popTestError()
-	}
-	return cdb.db.PrepareNamed(query)
+// PrepareNamed
+func (cdb *customDB) PrepareNamed(query string) (NamedStmt, error) {
+	return cdb.PrepareNamedContext(context.Background(), query)
 }
 
 // PrepareNamedContext
 func (cdb *customDB) PrepareNamedContext(ctx context.Context, query string) (NamedStmt, error) {
-	if namedStmt, err := cdb.prepareNamedContext(ctx, query); err != nil {
-		return &customNamedStmt{}, err
-	} else {
-		return &customNamedStmt{namedStmt: namedStmt}, nil
-	}
+	return cdb.getHooks().prepareNamed(ctx, query, cdb.prepareNamedContext)
 }
 
 // prepareNamedContext
@@ -611,28 +627,12 @@ func (cdb *customDB) prepareNamedContext(ctx context.Context, query string) (*sq
 
 // Prepare
 func (cdb *customDB) Prepare(query string) (Stmt, error) {
-	if stmt, err := cdb.prepare(query); err != nil {
-		return &customStmt{}, err
-	} else {
-		return &customStmt{stmt: stmt}, nil
-	}
-}
-
-// prepare
-func (cdb *customDB) prepare(query string) (*sqlx.Stmt, error) {
-	if cdb.testErr != nil {
-		return &sqlx.Stmt{}, cdb.popTestError()
-	}
-	return cdb.db.Preparex(query)
+	return cdb.PrepareContext(context.Background(), query)
 }
 
 // PrepareContext
 func (cdb *customDB) PrepareContext(ctx context.Context, query string) (Stmt, error) {
-	if stmt, err := cdb.prepareContext(ctx, query); err != nil {
-		return &customStmt{}, err
-	} else {
-		return &customStmt{stmt: stmt}, nil
-	}
+	return cdb.getHooks().prepare(ctx, query, cdb.prepareContext)
 }
 
 // prepareContext
@@ -645,38 +645,22 @@ func (cdb *customDB) prepareContext(ctx context.Context, query string) (*sqlx.St
 
 // QueryRow
 func (cdb *customDB) QueryRow(query string, args ...interface{}) Row {
-	return &customRow{row: cdb.db.QueryRowx(query, args...)}
+	return cdb.QueryRowContext(context.Background(), query, args...)
 }
 
 // QueryRowContext
 func (cdb *customDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
-	return &customRow{row: cdb.db.QueryRowxContext(ctx, query, args...)}
+	return cdb.getHooks().row(ctx, query, args, cdb.db.QueryRowxContext)
 }
 
 // Query
 func (cdb *customDB) Query(query string, args ...interface{}) (Rows, error) {
-	if rows, err := cdb.query(query, args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
-}
-
-// query
-func (cdb *customDB) query(query string, args ...interface{}) (*sqlx.Rows, error) {
-	if cdb.testErr != nil {
-		return &sqlx.Rows{}, cdb.popTestError()
-	}
-	return cdb.db.Queryx(query, args...)
+	return cdb.QueryContext(context.Background(), query, args...)
 }
 
 // QueryContext
 func (cdb *customDB) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	if rows, err := cdb.queryContext(ctx, query, args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	return cdb.getHooks().rows(ctx, OperationQuery, query, args, cdb.queryContext)
 }
 
 // queryContext
@@ -694,12 +678,12 @@ func (cdb *customDB) Rebind(query string) string {
 
 // Select
 func (cdb *customDB) Select(dest interface{}, query string, args ...interface{}) error {
-	return cdb.db.Select(dest, query, args...)
+	return cdb.SelectContext(context.Background(), dest, query, args...)
 }
 
 // SelectContext
 func (cdb *customDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return cdb.db.SelectContext(ctx, dest, query, args...)
+	return cdb.getHooks().scan(ctx, OperationSelect, dest, query, args, cdb.db.SelectContext)
 }
 
 // Unsafe
@@ -25,6 +25,7 @@ type DBMock struct {
 	CallbackSetMaxOpenConns     func(n int)
 	CallbackSetMaxIdleConns     func(n int)
 	CallbackStats               func() sql.DBStats
+	CallbackAddHook             func(hooks ...QueryHook)
 	CallbackBeginTx             func(ctx context.Context, opts *sql.TxOptions) (Tx, error)
 	CallbackBegin               func() (Tx, error)
 	CallbackBindNamed           func(query string, arg interface{}) (string, []interface{}, error)
@@ -166,6 +167,13 @@ func (dbm *DBMock) Stats() sql.DBStats {
 	return sql.DBStats{}
 }
 
+// AddHook calls the callback function CallbackAddHook if it is set.
+func (dbm *DBMock) AddHook(hooks ...QueryHook) {
+	if dbm.CallbackAddHook != nil {
+		dbm.CallbackAddHook(hooks...)
+	}
+}
+
 // BeginTx calls the callback function CallbackBeginTx if it is set.
 // Otherwise, it returns a new instance of TxMock.
 func (dbm *DBMock) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
@@ -243,6 +243,19 @@ func Test_DBMock(t *testing.T) {
 				assert.Equal(t, sql.DBStats{}, dbMock.Stats(), "Stats should return an empty sql.DBStats")
 			},
 		},
+		{
+			name:   "Should run CallbackAddHook",
+			dbMock: NewMockDB(),
+			assert: func(t *testing.T, dbMock *DBMock) {
+				callCount := 0
+				dbMock.CallbackAddHook = func(hooks ...QueryHook) {
+					callCount++
+					assert.Len(t, hooks, 2, "AddHook should receive 2 hooks")
+				}
+				dbMock.AddHook(QueryHookFuncs{}, QueryHookFuncs{})
+				assert.Equal(t, 1, callCount, "CallbackAddHook should be called once")
+			},
+		},
 		{
 			name:   "Should run CallbackBeginTx",
 			dbMock: NewMockDB(),
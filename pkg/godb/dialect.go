@@ -0,0 +1,49 @@
+package godb
+
+import (
+	"strings"
+)
+
+// quoteIdentifier quotes a table, column or schema name using the dialect of dbType
+func quoteIdentifier(dbType DBType, name string) string {
+	switch dbType {
+	case MySQLDB:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// quoteQualified quotes a table name qualified with its schema, such as public.users,
+// quoting each part
+func quoteQualified(dbType DBType, name string) string {
+	parts := strings.Split(name, ".")
+	for index, part := range parts {
+		parts[index] = quoteIdentifier(dbType, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// QuoteIdentifier Returns a column, table or schema name quoted for the driver, such as
+// the one returned by DB.DriverName. Unknown drivers use double quotes.
+func QuoteIdentifier(driverName string, name string) string {
+	dbType, _ := driverDBType(driverName)
+	return quoteIdentifier(dbType, name)
+}
+
+// QuoteQualified Returns a table name, which can be qualified with its schema, quoted for
+// the driver. Unknown drivers use double quotes.
+func QuoteQualified(driverName string, name string) string {
+	dbType, _ := driverDBType(driverName)
+	return quoteQualified(dbType, name)
+}
+
+// driverDBType returns the DBType of a driver name, such as the one returned by DB.DriverName
+func driverDBType(driverName string) (DBType, bool) {
+	for dbType, name := range validDBTypes {
+		if name == driverName {
+			return dbType, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,15 @@
+package godb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_QuoteQualified(t *testing.T) {
+	assert.Equal(t, `"public"."users"`, QuoteQualified("postgres", "public.users"))
+	assert.Equal(t, "`app`.`users`", QuoteQualified("mysql", "app.users"))
+	assert.Equal(t, `"users"`, QuoteQualified("unknown", "users"))
+	assert.Equal(t, "`user``s`", QuoteIdentifier("mysql", "user`s"))
+	assert.Equal(t, `"a.b"`, QuoteIdentifier("sqlite3", "a.b"))
+}
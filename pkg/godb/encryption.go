@@ -0,0 +1,598 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocrypto"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+const encryptTag = "encrypt"
+
+var (
+	defaultEncrypter      Encrypter
+	defaultEncrypterMutex sync.RWMutex
+
+	_ Encrypter = (*gocrypto.Keyring)(nil)
+)
+
+// Encrypter defines the authenticated cipher used to encrypt column values.
+// The ciphertext must identify the key it was encrypted with, so keys can be rotated
+// while old rows are still readable. *gocrypto.Keyring implements it.
+type Encrypter interface {
+	// Encrypt encrypts the plaintext using the current key.
+	Encrypt(plaintext []byte) (string, error)
+	// Decrypt decrypts a ciphertext created by Encrypt using the key it was encrypted with.
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// SetEncrypter Sets the default Encrypter used by Encrypted values and by encrypted
+// databases created without their own Encrypter
+func SetEncrypter(encrypter Encrypter) {
+	defaultEncrypterMutex.Lock()
+	defer defaultEncrypterMutex.Unlock()
+	defaultEncrypter = encrypter
+}
+
+// getEncrypter returns the default Encrypter
+func getEncrypter() (Encrypter, error) {
+	defaultEncrypterMutex.RLock()
+	defer defaultEncrypterMutex.RUnlock()
+
+	if defaultEncrypter == nil {
+		return nil, ErrNoEncrypter
+	}
+	return defaultEncrypter, nil
+}
+
+// Encrypted stores V encrypted at rest using the Encrypter set by SetEncrypter.
+//
+// Strings and byte slices are encrypted as they are, other types are encoded as JSON
+// first. It works with any query, including Exec and prepared statements. NULL columns
+// are scanned as the zero value.
+type Encrypted[T any] struct {
+	V T
+}
+
+// NewEncrypted Returns a new Encrypted value
+func NewEncrypted[T any](value T) Encrypted[T] {
+	return Encrypted[T]{V: value}
+}
+
+// Value
+func (e Encrypted[T]) Value() (driver.Value, error) {
+	encrypter, err := getEncrypter()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := encodePlaintext(e.V)
+	if err != nil {
+		return nil, err
+	}
+	return encrypter.Encrypt(plaintext)
+}
+
+// Scan
+func (e *Encrypted[T]) Scan(src interface{}) error {
+	var ciphertext string
+	switch value := src.(type) {
+	case nil:
+		var zero T
+		e.V = zero
+		return nil
+	case string:
+		ciphertext = value
+	case []byte:
+		ciphertext = string(value)
+	default:
+		return fmt.Errorf("can not scan %T into %T. %w", src, e, ErrUnsupportedEncryptedField)
+	}
+
+	encrypter, err := getEncrypter()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := encrypter.Decrypt(ciphertext)
+	if err != nil {
+		return err
+	}
+	return decodePlaintext(plaintext, &e.V)
+}
+
+// MarshalJSON
+func (e Encrypted[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.V)
+}
+
+// UnmarshalJSON
+func (e *Encrypted[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &e.V)
+}
+
+// encodePlaintext returns the bytes to be encrypted for value
+func encodePlaintext(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// decodePlaintext decodes the decrypted bytes into dest
+func decodePlaintext(plaintext []byte, dest interface{}) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = string(plaintext)
+		return nil
+	case *[]byte:
+		*d = plaintext
+		return nil
+	default:
+		return json.Unmarshal(plaintext, dest)
+	}
+}
+
+// NewEncryptedDB Returns a DB that encrypts the struct fields tagged with encrypt:"true"
+// before NamedExec and NamedQuery, and decrypts them after Get, Select and StructScan.
+// Transactions started from it behave the same way.
+//
+// Tagged fields must be a string, *string or []byte. Empty values are stored and read
+// as they are. Positional arguments and prepared statements are not inspected; use
+// Encrypted for them. If encrypter is nil the one set by SetEncrypter is used.
+func NewEncryptedDB(db DB, encrypter Encrypter) DB {
+	// Safe is nil for databases without a single underlying database, such as a TenantDB
+	var mapper *reflectx.Mapper
+	if safe := db.Safe(); safe != nil {
+		mapper = safe.Mapper
+	}
+
+	if mapper == nil {
+		mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+	}
+	return &encryptedDB{DB: db, fc: &fieldCrypter{encrypter: encrypter, mapper: mapper}}
+}
+
+// fieldCrypter encrypts and decrypts the tagged struct fields
+type fieldCrypter struct {
+	encrypter Encrypter
+	mapper    *reflectx.Mapper
+}
+
+// getEncrypter returns the Encrypter used by the fields
+func (fc *fieldCrypter) getEncrypter() (Encrypter, error) {
+	if fc.encrypter != nil {
+		return fc.encrypter, nil
+	}
+	return getEncrypter()
+}
+
+// fields returns the index of the tagged fields of a struct type
+func (fc *fieldCrypter) fields(t reflect.Type) ([][]int, error) {
+	var fields [][]int
+	for _, field := range fc.mapper.TypeMap(t).Index {
+		if field.Field.Tag.Get(encryptTag) != "true" {
+			continue
+		}
+
+		switch fieldType := field.Field.Type; {
+		case fieldType.Kind() == reflect.String,
+			fieldType.Kind() == reflect.Pointer && fieldType.Elem().Kind() == reflect.String,
+			fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8:
+			fields = append(fields, field.Index)
+		default:
+			return nil, fmt.Errorf("field %s has type %s. %w", field.Path, fieldType, ErrUnsupportedEncryptedField)
+		}
+	}
+	return fields, nil
+}
+
+// encryptArg returns a copy of a named query argument with the tagged fields encrypted.
+// Maps and structs without tagged fields are returned as they are.
+func (fc *fieldCrypter) encryptArg(arg interface{}) (interface{}, error) {
+	value := reflect.Indirect(reflect.ValueOf(arg))
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return fc.encryptStruct(value, arg)
+	case reflect.Slice, reflect.Array:
+		elemType := reflectx.Deref(value.Type().Elem())
+		if elemType.Kind() != reflect.Struct {
+			return arg, nil
+		}
+
+		if fields, err := fc.fields(elemType); err != nil || len(fields) == 0 {
+			return arg, err
+		}
+
+		encrypted := reflect.MakeSlice(reflect.SliceOf(elemType), value.Len(), value.Len())
+		for index := 0; index < value.Len(); index++ {
+			elemValue := reflect.Indirect(value.Index(index))
+			if !elemValue.IsValid() {
+				continue
+			}
+
+			elem, err := fc.encryptStruct(elemValue, nil)
+			if err != nil {
+				return nil, err
+			}
+			encrypted.Index(index).Set(reflect.ValueOf(elem))
+		}
+		return encrypted.Interface(), nil
+	default:
+		return arg, nil
+	}
+}
+
+// encryptStruct returns a copy of value with the tagged fields encrypted
+func (fc *fieldCrypter) encryptStruct(value reflect.Value, original interface{}) (interface{}, error) {
+	fields, err := fc.fields(value.Type())
+	if err != nil || len(fields) == 0 {
+		return original, err
+	}
+
+	encrypter, err := fc.getEncrypter()
+	if err != nil {
+		return nil, err
+	}
+
+	copied := reflect.New(value.Type()).Elem()
+	copied.Set(value)
+
+	for _, index := range fields {
+		field := copiedField(copied, index)
+		plaintext, ok := fieldBytes(field)
+		if !ok {
+			continue
+		}
+
+		ciphertext, err := encrypter.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		setFieldBytes(field, []byte(ciphertext))
+	}
+	return copied.Interface(), nil
+}
+
+// copiedField returns the field at index, copying the embedded struct pointers on its path
+// so the value they point to is not modified
+func copiedField(value reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		value = reflect.Indirect(value).Field(i)
+		if value.Kind() == reflect.Pointer && value.Type().Elem().Kind() == reflect.Struct {
+			copied := reflect.New(value.Type().Elem())
+			if !value.IsNil() {
+				copied.Elem().Set(value.Elem())
+			}
+			value.Set(copied)
+		}
+	}
+	return value
+}
+
+// decryptDest decrypts the tagged fields of a struct or a slice of structs in place
+func (fc *fieldCrypter) decryptDest(dest interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(dest))
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return fc.decryptStruct(value)
+	case reflect.Slice:
+		if reflectx.Deref(value.Type().Elem()).Kind() != reflect.Struct {
+			return nil
+		}
+
+		for index := 0; index < value.Len(); index++ {
+			if err := fc.decryptStruct(reflect.Indirect(value.Index(index))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decryptStruct decrypts the tagged fields of value in place
+func (fc *fieldCrypter) decryptStruct(value reflect.Value) error {
+	if !value.IsValid() {
+		return nil
+	}
+
+	fields, err := fc.fields(value.Type())
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	encrypter, err := fc.getEncrypter()
+	if err != nil {
+		return err
+	}
+
+	for _, index := range fields {
+		field := reflectx.FieldByIndexes(value, index)
+		ciphertext, ok := fieldBytes(field)
+		if !ok {
+			continue
+		}
+
+		plaintext, err := encrypter.Decrypt(string(ciphertext))
+		if err != nil {
+			return err
+		}
+		setFieldBytes(field, plaintext)
+	}
+	return nil
+}
+
+// fieldBytes returns the content of a string, *string or []byte field.
+// It returns false if the field is empty.
+func fieldBytes(field reflect.Value) ([]byte, bool) {
+	switch field.Kind() {
+	case reflect.String:
+		return []byte(field.String()), field.Len() > 0
+	case reflect.Pointer:
+		if field.IsNil() || field.Elem().Len() == 0 {
+			return nil, false
+		}
+		return []byte(field.Elem().String()), true
+	default:
+		return field.Bytes(), field.Len() > 0
+	}
+}
+
+// setFieldBytes sets the content of a string, *string or []byte field
+func setFieldBytes(field reflect.Value, data []byte) {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(string(data))
+	case reflect.Pointer:
+		value := reflect.New(field.Type().Elem())
+		value.Elem().SetString(string(data))
+		field.Set(value)
+	default:
+		field.SetBytes(data)
+	}
+}
+
+// encryptedDB encrypts and decrypts the tagged fields of the queries
+type encryptedDB struct {
+	DB
+	fc *fieldCrypter
+}
+
+// NamedExec
+func (edb *encryptedDB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return edb.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext
+func (edb *encryptedDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	arg, err := edb.fc.encryptArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	return edb.DB.NamedExecContext(ctx, query, arg)
+}
+
+// NamedQuery
+func (edb *encryptedDB) NamedQuery(query string, arg interface{}) (Rows, error) {
+	return edb.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext
+func (edb *encryptedDB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (Rows, error) {
+	arg, err := edb.fc.encryptArg(arg)
+	if err != nil {
+		return &customRows{}, err
+	}
+
+	rows, err := edb.DB.NamedQueryContext(ctx, query, arg)
+	if err != nil {
+		return rows, err
+	}
+	return &encryptedRows{Rows: rows, fc: edb.fc}, nil
+}
+
+// Get
+func (edb *encryptedDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return edb.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext
+func (edb *encryptedDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := edb.DB.GetContext(ctx, dest, query, args...); err != nil {
+		return err
+	}
+	return edb.fc.decryptDest(dest)
+}
+
+// Select
+func (edb *encryptedDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return edb.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext
+func (edb *encryptedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := edb.DB.SelectContext(ctx, dest, query, args...); err != nil {
+		return err
+	}
+	return edb.fc.decryptDest(dest)
+}
+
+// Query
+func (edb *encryptedDB) Query(query string, args ...interface{}) (Rows, error) {
+	return edb.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext
+func (edb *encryptedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := edb.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return rows, err
+	}
+	return &encryptedRows{Rows: rows, fc: edb.fc}, nil
+}
+
+// QueryRow
+func (edb *encryptedDB) QueryRow(query string, args ...interface{}) Row {
+	return edb.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext
+func (edb *encryptedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	return &encryptedRow{Row: edb.DB.QueryRowContext(ctx, query, args...), fc: edb.fc}
+}
+
+// BeginTx
+func (edb *encryptedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := edb.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return tx, err
+	}
+	return &encryptedTx{Tx: tx, fc: edb.fc}, nil
+}
+
+// Begin
+func (edb *encryptedDB) Begin() (Tx, error) {
+	tx, err := edb.DB.Begin()
+	if err != nil {
+		return tx, err
+	}
+	return &encryptedTx{Tx: tx, fc: edb.fc}, nil
+}
+
+// MustBegin
+func (edb *encryptedDB) MustBegin() Tx {
+	return &encryptedTx{Tx: edb.DB.MustBegin(), fc: edb.fc}
+}
+
+// MustBeginTx
+func (edb *encryptedDB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) Tx {
+	return &encryptedTx{Tx: edb.DB.MustBeginTx(ctx, opts), fc: edb.fc}
+}
+
+// encryptedTx encrypts and decrypts the tagged fields of the queries within a transaction
+type encryptedTx struct {
+	Tx
+	fc *fieldCrypter
+}
+
+// NamedExec
+func (etx *encryptedTx) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return etx.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext
+func (etx *encryptedTx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	arg, err := etx.fc.encryptArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	return etx.Tx.NamedExecContext(ctx, query, arg)
+}
+
+// NamedQuery
+func (etx *encryptedTx) NamedQuery(query string, arg interface{}) (Rows, error) {
+	arg, err := etx.fc.encryptArg(arg)
+	if err != nil {
+		return &customRows{}, err
+	}
+
+	rows, err := etx.Tx.NamedQuery(query, arg)
+	if err != nil {
+		return rows, err
+	}
+	return &encryptedRows{Rows: rows, fc: etx.fc}, nil
+}
+
+// Get
+func (etx *encryptedTx) Get(dest interface{}, query string, args ...interface{}) error {
+	return etx.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext
+func (etx *encryptedTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := etx.Tx.GetContext(ctx, dest, query, args...); err != nil {
+		return err
+	}
+	return etx.fc.decryptDest(dest)
+}
+
+// Select
+func (etx *encryptedTx) Select(dest interface{}, query string, args ...interface{}) error {
+	return etx.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext
+func (etx *encryptedTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := etx.Tx.SelectContext(ctx, dest, query, args...); err != nil {
+		return err
+	}
+	return etx.fc.decryptDest(dest)
+}
+
+// Query
+func (etx *encryptedTx) Query(query string, args ...interface{}) (Rows, error) {
+	return etx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext
+func (etx *encryptedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := etx.Tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return rows, err
+	}
+	return &encryptedRows{Rows: rows, fc: etx.fc}, nil
+}
+
+// QueryRow
+func (etx *encryptedTx) QueryRow(query string, args ...interface{}) Row {
+	return etx.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext
+func (etx *encryptedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	return &encryptedRow{Row: etx.Tx.QueryRowContext(ctx, query, args...), fc: etx.fc}
+}
+
+// encryptedRows decrypts the tagged fields on StructScan
+type encryptedRows struct {
+	Rows
+	fc *fieldCrypter
+}
+
+// StructScan
+func (er *encryptedRows) StructScan(dest interface{}) error {
+	if err := er.Rows.StructScan(dest); err != nil {
+		return err
+	}
+	return er.fc.decryptDest(dest)
+}
+
+// encryptedRow decrypts the tagged fields on StructScan
+type encryptedRow struct {
+	Row
+	fc *fieldCrypter
+}
+
+// StructScan
+func (er *encryptedRow) StructScan(dest interface{}) error {
+	if err := er.Row.StructScan(dest); err != nil {
+		return err
+	}
+	return er.fc.decryptDest(dest)
+}
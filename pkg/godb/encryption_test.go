@@ -0,0 +1,191 @@
+package godb
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocrypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EncryptedDB(t *testing.T) {
+	type Contact struct {
+		Phone *string `db:"phone" encrypt:"true"`
+	}
+
+	type customData struct {
+		ID    int    `db:"id"`
+		Email string `db:"email" encrypt:"true"`
+		Notes []byte `db:"notes" encrypt:"true"`
+		*Contact
+	}
+
+	ddl := `
+		CREATE TABLE custom_table (
+			id INTEGER PRIMARY KEY,
+			email TEXT NOT NULL,
+			notes BLOB,
+			phone TEXT
+		);
+	`
+
+	oldKeyring, err := gocrypto.NewKeyring("v1", map[string][]byte{"v1": bytes.Repeat([]byte{1}, 32)})
+	assert.NoError(t, err)
+	keyring, err := gocrypto.NewKeyring("v2", map[string][]byte{
+		"v1": bytes.Repeat([]byte{1}, 32),
+		"v2": bytes.Repeat([]byte{2}, 32),
+	})
+	assert.NoError(t, err)
+
+	insert := "INSERT INTO custom_table (id, email, notes, phone) VALUES (:id, :email, :notes, :phone)"
+	phone := "555-0100"
+
+	tests := []struct {
+		name   string
+		assert func(t *testing.T, raw DB, db DB)
+	}{
+		{
+			name: "should encrypt tagged fields",
+			assert: func(t *testing.T, raw DB, db DB) {
+				data := customData{ID: 1, Email: "john@doe.com", Notes: []byte("vip"), Contact: &Contact{Phone: &phone}}
+				_, err := db.NamedExec(insert, data)
+				assert.NoError(t, err)
+				assert.Equal(t, "john@doe.com", data.Email, "arguments should not be modified")
+				assert.Equal(t, "555-0100", *data.Phone, "embedded arguments should not be modified")
+
+				var stored customData
+				assert.NoError(t, raw.Get(&stored, "SELECT * FROM custom_table WHERE id = 1"))
+				assert.True(t, strings.HasPrefix(stored.Email, "aes256gcm$v2$"), "email should be encrypted")
+				assert.True(t, strings.HasPrefix(*stored.Phone, "aes256gcm$v2$"), "phone should be encrypted")
+
+				var found customData
+				assert.NoError(t, db.Get(&found, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, data, found)
+
+				var list []*customData
+				assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table"))
+				assert.Equal(t, []*customData{&data}, list)
+
+				var email string
+				assert.NoError(t, db.QueryRow("SELECT email FROM custom_table").Scan(&email))
+				assert.NotEqual(t, "john@doe.com", email, "plain scans should not be decrypted")
+			},
+		},
+		{
+			name: "should read rows encrypted with old keys",
+			assert: func(t *testing.T, raw DB, db DB) {
+				_, err := NewEncryptedDB(raw, oldKeyring).NamedExec(insert, []customData{
+					{ID: 1, Email: "john@doe.com", Contact: &Contact{}},
+					{ID: 2, Email: "jane@doe.com", Contact: &Contact{}},
+				})
+				assert.NoError(t, err)
+
+				tx, err := db.Begin()
+				assert.NoError(t, err)
+				_, err = tx.NamedExec("UPDATE custom_table SET email = :email WHERE id = :id", customData{ID: 2, Email: "janet@doe.com"})
+				assert.NoError(t, err)
+
+				rows, err := tx.Query("SELECT * FROM custom_table ORDER BY id")
+				assert.NoError(t, err)
+				emails := []string{}
+				for rows.Next() {
+					var data customData
+					assert.NoError(t, rows.StructScan(&data))
+					emails = append(emails, data.Email)
+				}
+				assert.NoError(t, rows.Close())
+				assert.Equal(t, []string{"john@doe.com", "janet@doe.com"}, emails)
+				assert.NoError(t, tx.Commit())
+
+				var keyIDs []string
+				assert.NoError(t, raw.Select(&keyIDs, "SELECT substr(email, 11, 2) FROM custom_table ORDER BY id"))
+				assert.Equal(t, []string{"v1", "v2"}, keyIDs, "only updated rows should use the new key")
+			},
+		},
+		{
+			name: "should reject unsupported fields",
+			assert: func(t *testing.T, raw DB, db DB) {
+				type invalidData struct {
+					ID int `db:"id" encrypt:"true"`
+				}
+
+				_, err := db.NamedExec("DELETE FROM custom_table WHERE id = :id", invalidData{ID: 1})
+				assert.ErrorIs(t, err, ErrUnsupportedEncryptedField)
+
+				_, err = NewEncryptedDB(raw, nil).NamedExec(insert, customData{ID: 1, Email: "john@doe.com"})
+				assert.ErrorIs(t, err, ErrNoEncrypter)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw := newSQLiteTestDB(t, ddl)
+			test.assert(t, raw, NewEncryptedDB(raw, keyring))
+		})
+	}
+
+	tenantDB, err := NewTenantDB(TenantConfig{
+		Mode:         TenantDatabaseMode,
+		DatabaseType: SQLiteDB,
+		DBConfig:     func(tenant string) (DBConfig, error) { return DBConfig{}, nil },
+	})
+	assert.NoError(t, err)
+	defer tenantDB.Close()
+	assert.NotPanics(t, func() { NewEncryptedDB(tenantDB, keyring) }, "databases without Safe should be supported")
+}
+
+func Test_Encrypted(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+
+	type customData struct {
+		ID      int                `db:"id"`
+		Email   Encrypted[string]  `db:"email"`
+		Address Encrypted[address] `db:"address"`
+	}
+
+	key, err := gocrypto.NewEncryptionKey()
+	assert.NoError(t, err)
+	keyring, err := gocrypto.NewKeyring("v1", map[string][]byte{"v1": key})
+	assert.NoError(t, err)
+
+	db := newSQLiteTestDB(t, "CREATE TABLE custom_table (id INTEGER PRIMARY KEY, email TEXT, address TEXT)")
+
+	_, err = db.Exec("INSERT INTO custom_table (id, email) VALUES (?, ?)", 1, NewEncrypted("john@doe.com"))
+	assert.ErrorContains(t, err, ErrNoEncrypter.Error(), "database/sql does not wrap Valuer errors")
+
+	SetEncrypter(keyring)
+	t.Cleanup(func() { SetEncrypter(nil) })
+
+	_, err = db.ExecContext(context.Background(), "INSERT INTO custom_table (id, email, address) VALUES (?, ?, ?)",
+		1, NewEncrypted("john@doe.com"), NewEncrypted(address{City: "Lisbon"}),
+	)
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO custom_table (id) VALUES (?)", 2)
+	assert.NoError(t, err)
+
+	var stored string
+	assert.NoError(t, db.Get(&stored, "SELECT address FROM custom_table WHERE id = 1"))
+	assert.True(t, strings.HasPrefix(stored, "aes256gcm$v1$"), "address should be encrypted")
+
+	var list []customData
+	assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+	assert.Equal(t, []customData{
+		{ID: 1, Email: NewEncrypted("john@doe.com"), Address: NewEncrypted(address{City: "Lisbon"})},
+		{ID: 2},
+	}, list)
+
+	data, err := list[0].Address.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"city": "Lisbon"}`, string(data))
+
+	var email Encrypted[string]
+	assert.NoError(t, email.UnmarshalJSON([]byte(`"jane@doe.com"`)))
+	assert.Equal(t, "jane@doe.com", email.V)
+	assert.ErrorIs(t, email.Scan(10), ErrUnsupportedEncryptedField)
+	assert.ErrorIs(t, email.Scan("aes256gcm$v9$AAAA"), gocrypto.ErrUnknownEncryptionKey)
+}
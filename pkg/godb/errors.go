@@ -6,4 +6,26 @@ var (
 	ErrUnknown                   = errors.New("unknown error")
 	ErrInvalidDBType             = errors.New("invalid database type")
 	ErrConnectionTimeoutExceeded = errors.New("connection timeout exceeded")
+	ErrNoTenant                  = errors.New("no tenant found in context")
+	ErrInvalidTenantConfig       = errors.New("invalid tenant config")
+	ErrUnsupportedOperation      = errors.New("unsupported operation")
+	ErrNoEncrypter               = errors.New("no encrypter set")
+	ErrUnsupportedEncryptedField = errors.New("unsupported encrypted field")
+	ErrTableNotFound             = errors.New("table not found")
+	ErrUnsupportedScanType       = errors.New("unsupported scan type")
+	ErrInvalidArray              = errors.New("invalid array")
+	ErrQueryTimeout              = errors.New("query timeout exceeded")
+	ErrStatementTimeout          = errors.New("statement timeout exceeded")
+	ErrUnsupportedFormat         = errors.New("unsupported format")
+	ErrInvalidImport             = errors.New("invalid import")
+	ErrNoShard                   = errors.New("no shard found")
+	ErrInvalidShardConfig        = errors.New("invalid shard config")
+	ErrInvalidShardKey           = errors.New("invalid shard key")
+	ErrStaleUpdate               = errors.New("stale update")
+	ErrInvalidVersionConfig      = errors.New("invalid version config")
+	ErrInvalidRepository         = errors.New("invalid repository")
+	ErrUnknownColumn             = errors.New("unknown column")
+	ErrInvalidFilter             = errors.New("invalid filter")
+	ErrDangerousStatement        = errors.New("dangerous statement")
+	ErrInvalidExplainConfig      = errors.New("invalid explain config")
 )
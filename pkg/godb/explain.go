@@ -0,0 +1,336 @@
+package godb
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+)
+
+const (
+	defaultExplainInterval = time.Minute
+	defaultExplainTimeout  = 5 * time.Second
+)
+
+// placeholderLists matches lists of normalized values, such as the values of IN (?, ?, ?)
+var placeholderLists = regexp.MustCompile(`\?(\s*,\s*\?)+`)
+
+// ExplainLogger defines the logger used by the slow query explainer
+type ExplainLogger interface {
+	Warn(ctx context.Context, message string, opts ...golog.Options)
+}
+
+// ExplainConfig defines the slow query explainer configs
+type ExplainConfig struct {
+	// Threshold is the duration after which a statement is explained. Required.
+	Threshold time.Duration
+	// Interval is the minimum time between two captures of the same fingerprint.
+	// Defaults to one minute.
+	Interval time.Duration
+	// MaxConcurrent is the maximum amount of captures running at the same time. Slow
+	// statements are not explained while the limit is reached. Defaults to 1.
+	MaxConcurrent int
+	// Timeout is the timeout of the EXPLAIN statements. Defaults to 5 seconds.
+	Timeout time.Duration
+	// Logger logs the plans. Defaults to golog.Log().
+	Logger ExplainLogger
+}
+
+// explainDialects defines the EXPLAIN statement of each database
+var explainDialects = map[DBType]string{
+	PostgresDB: "EXPLAIN (FORMAT JSON) ",
+	MySQLDB:    "EXPLAIN FORMAT=JSON ",
+	SQLiteDB:   "EXPLAIN QUERY PLAN ",
+}
+
+// ExplainHook defines the slow query explainer hook
+type ExplainHook interface {
+	QueryHook
+	// Wait waits for the running captures until ctx is done
+	Wait(ctx context.Context) error
+	// Close stops new captures and waits for the running ones until ctx is done. Call it
+	// before closing the DB, such as in a goweb shutdown hook.
+	Close(ctx context.Context) error
+}
+
+// explainContextKey marks the EXPLAIN statements, so they are not explained themselves
+type explainContextKey struct{}
+
+// slowQueryExplainer implements the slow query explainer hook
+type slowQueryExplainer struct {
+	db      DB
+	explain string
+	config  ExplainConfig
+	slots   chan struct{}
+
+	mutex    sync.Mutex
+	captured map[string]time.Time
+	closed   bool
+	// wg tracks the running captures
+	wg sync.WaitGroup
+}
+
+// NewExplainHook Returns a QueryHook that explains the statements slower than the threshold.
+// Install it on db with DB.AddHook, so the statements of its Tx and Conn are explained too.
+//
+// The plan is read with the EXPLAIN of the database on a separate connection of db, with the
+// same args, after the statement finished, and is logged with the query fingerprint. EXPLAIN
+// does not execute the statement on any of the supported databases. Captures are limited to
+// one per fingerprint per interval. The captures run in the background, so close the hook
+// before db.
+func NewExplainHook(db DB, config ExplainConfig) (ExplainHook, error) {
+	dbType, ok := driverDBType(db.DriverName())
+	if !ok {
+		return nil, ErrInvalidDBType
+	}
+
+	if config.Threshold <= 0 {
+		return nil, fmt.Errorf("%w. threshold is required", ErrInvalidExplainConfig)
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = defaultExplainInterval
+	}
+
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 1
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultExplainTimeout
+	}
+
+	if config.Logger == nil {
+		config.Logger = golog.Log()
+	}
+
+	return &slowQueryExplainer{
+		db:       db,
+		explain:  explainDialects[dbType],
+		config:   config,
+		slots:    make(chan struct{}, config.MaxConcurrent),
+		captured: map[string]time.Time{},
+	}, nil
+}
+
+// BeforeQuery
+func (sqe *slowQueryExplainer) BeforeQuery(ctx context.Context, _ *QueryEvent) (context.Context, error) {
+	return ctx, nil
+}
+
+// AfterQuery
+func (sqe *slowQueryExplainer) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Duration < sqe.config.Threshold || ctx.Value(explainContextKey{}) != nil || !explainable(event) {
+		return
+	}
+
+	fingerprint := Fingerprint(event.Query)
+	if !sqe.acquire(fingerprint) {
+		return
+	}
+
+	query, args := event.Query, event.Args
+	if event.Operation == OperationNamedExec || event.Operation == OperationNamedQuery {
+		var err error
+		if query, args, err = sqe.db.BindNamed(event.Query, namedArg(args)); err != nil {
+			<-sqe.slots
+			sqe.wg.Done()
+			return
+		}
+	}
+
+	// The statement can still hold its connection, such as Rows being read, so the plan is
+	// captured in the background. It keeps the context values, such as the trace span.
+	go func() {
+		defer sqe.wg.Done()
+		defer func() { <-sqe.slots }()
+		sqe.capture(context.WithoutCancel(ctx), event, fingerprint, query, args)
+	}()
+}
+
+// acquire returns if a capture of fingerprint can start, reserving a slot and tracking it if so
+func (sqe *slowQueryExplainer) acquire(fingerprint string) bool {
+	sqe.mutex.Lock()
+	defer sqe.mutex.Unlock()
+
+	now := time.Now()
+	if sqe.closed {
+		return false
+	}
+
+	if last, ok := sqe.captured[fingerprint]; ok && now.Sub(last) < sqe.config.Interval {
+		return false
+	}
+
+	select {
+	case sqe.slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	for key, last := range sqe.captured {
+		if now.Sub(last) >= sqe.config.Interval {
+			delete(sqe.captured, key)
+		}
+	}
+
+	sqe.captured[fingerprint] = now
+	sqe.wg.Add(1)
+	return true
+}
+
+// capture explains a statement and logs its plan
+func (sqe *slowQueryExplainer) capture(ctx context.Context, event *QueryEvent, fingerprint string, query string, args []interface{}) {
+	tags := map[string]interface{}{
+		"fingerprint": fingerprint,
+		"operation":   event.Operation,
+		"query":       event.Query,
+		"duration":    event.Duration.String(),
+	}
+
+	plan, err := sqe.plan(ctx, query, args)
+	if err != nil {
+		tags["explain_error"] = err.Error()
+		sqe.config.Logger.Warn(ctx, "slow query", golog.WithTags(tags))
+		return
+	}
+
+	tags["plan"] = plan
+	sqe.config.Logger.Warn(ctx, "slow query", golog.WithTags(tags))
+}
+
+// plan returns the plan of a statement
+func (sqe *slowQueryExplainer) plan(ctx context.Context, query string, args []interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, explainContextKey{}, true), sqe.config.Timeout)
+	defer cancel()
+
+	conn, err := sqe.db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, sqe.explain+query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	// Postgres and MySQL return a single JSON document while SQLite returns a row per step,
+	// whose detail column describes it
+	detail := 0
+	for index, column := range columns {
+		if column == "detail" {
+			detail = index
+		}
+	}
+
+	lines := []string{}
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return "", err
+		}
+		line, ok := asString(values[detail])
+		if !ok {
+			line = fmt.Sprint(values[detail])
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Wait
+func (sqe *slowQueryExplainer) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		sqe.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close
+func (sqe *slowQueryExplainer) Close(ctx context.Context) error {
+	sqe.mutex.Lock()
+	sqe.closed = true
+	sqe.mutex.Unlock()
+	return sqe.Wait(ctx)
+}
+
+// explainable returns if the statement of an event can be explained
+func explainable(event *QueryEvent) bool {
+	switch event.Operation {
+	case OperationPrepare, OperationPrepareNamed:
+		return false
+	}
+
+	statements := tokenizeSQL(event.Query)
+	if len(statements) != 1 {
+		return false
+	}
+	return statements[0][0].is("SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "WITH")
+}
+
+// Fingerprint Returns the fingerprint of a query, which is the same for the queries that
+// only differ by their literals, placeholders, comments, spacing or keyword case.
+func Fingerprint(query string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(NormalizeQuery(query)))
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// NormalizeQuery Returns a query with its literals and placeholders replaced by ?, comments
+// removed, whitespace collapsed and words in lower case. Lists of values, such as the values
+// of IN, are collapsed into a single ?.
+func NormalizeQuery(query string) string {
+	statements := []string{}
+	for _, tokens := range tokenizeSQL(query) {
+		words := make([]string, len(tokens))
+		for index, token := range tokens {
+			switch {
+			case token.quoted:
+				words[index] = token.text
+			case token.text == "''" || token.text == "?" || isValueWord(token.text):
+				words[index] = "?"
+			default:
+				words[index] = strings.ToLower(token.text)
+			}
+		}
+		statements = append(statements, placeholderLists.ReplaceAllString(strings.Join(words, " "), "?"))
+	}
+	return strings.Join(statements, "; ")
+}
+
+// isValueWord returns if a word is a number or a placeholder, such as $1, :name or @p1
+func isValueWord(word string) bool {
+	if word == "" {
+		return false
+	}
+
+	switch word[0] {
+	case '$', ':', '@':
+		return len(word) > 1
+	}
+	return word[0] >= '0' && word[0] <= '9'
+}
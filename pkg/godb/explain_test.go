@@ -0,0 +1,78 @@
+package godb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExplainHook(t *testing.T) {
+	ddl := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, age INTEGER);
+		INSERT INTO users (id, name, age) VALUES (1, 'John Doe', 30), (2, 'Jane Doe', 40);
+	`
+
+	ctx := context.Background()
+	logger := &guardRecorder{}
+	db := newSQLiteTestDB(t, ddl)
+
+	hook, err := NewExplainHook(db, ExplainConfig{Threshold: time.Nanosecond, Logger: logger})
+	assert.NoError(t, err)
+	db.AddHook(hook)
+
+	var names []string
+	assert.NoError(t, db.SelectContext(ctx, &names, "SELECT name FROM users WHERE age > ?", 20))
+	assert.NoError(t, hook.Wait(ctx))
+	assert.NoError(t, db.SelectContext(ctx, &names, "select name from users where age > 35"))
+	assert.NoError(t, hook.Wait(ctx))
+	assert.Len(t, logger.messages, 1, "the same fingerprint should be captured once per interval")
+
+	_, err = db.NamedExecContext(ctx, "UPDATE users SET age = :age WHERE id = :id", map[string]interface{}{"id": 1, "age": 31})
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, "CREATE TABLE events (id INTEGER)")
+	assert.NoError(t, err)
+	assert.NoError(t, hook.Wait(ctx))
+	assert.Equal(t, []string{"slow query", "slow query"}, logger.messages, "only DML should be explained")
+
+	plan, err := hook.(*slowQueryExplainer).plan(ctx, "SELECT name FROM users WHERE id = ?", []interface{}{1})
+	assert.NoError(t, err)
+	assert.Contains(t, plan, "USING INTEGER PRIMARY KEY")
+
+	assert.NoError(t, hook.Close(ctx))
+	assert.NoError(t, db.SelectContext(ctx, &names, "SELECT id FROM users"))
+	assert.NoError(t, hook.Wait(ctx))
+	assert.Len(t, logger.messages, 2, "closed hooks should not capture plans")
+
+	_, err = NewExplainHook(db, ExplainConfig{})
+	assert.ErrorIs(t, err, ErrInvalidExplainConfig)
+}
+
+func Test_NormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT name FROM users WHERE id = 10 AND name = 'John' -- comment",
+			expected: "select name from users where id = ? and name = ?",
+		},
+		{
+			query:    "select  name\n from users where id = $1 and name = :name",
+			expected: "select name from users where id = ? and name = ?",
+		},
+		{
+			query:    `SELECT "Name" FROM users WHERE id IN (?, ?, ?)`,
+			expected: "select Name from users where id in ( ? )",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, NormalizeQuery(test.query), test.query)
+	}
+
+	assert.Equal(t, Fingerprint("SELECT * FROM users WHERE id IN (1, 2)"), Fingerprint("select * from users where id in (3)"))
+	assert.NotEqual(t, Fingerprint("SELECT * FROM users"), Fingerprint("SELECT * FROM orders"))
+	assert.Len(t, Fingerprint("SELECT 1"), 16)
+}
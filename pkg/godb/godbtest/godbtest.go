@@ -0,0 +1,360 @@
+// Provides isolated test databases and fixtures for godb.
+package godbtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	testDBUser           = "godbtest"
+	testDBPassword       = "godbtest"
+	testDBConnectTimeout = time.Millisecond * 500
+)
+
+var (
+	ErrUnsupportedFixture  = errors.New("unsupported fixture file")
+	ErrCircularForeignKeys = errors.New("circular foreign keys between fixture tables")
+
+	dbCounter      atomic.Uint64
+	invalidDBChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+)
+
+// Config defines the test database configs
+type Config struct {
+	// File stores the database in a temporary file instead of memory. In-memory databases
+	// are limited to a single connection, so a transaction holds the whole database.
+	File bool
+	// Schema are SQL files applied after the database is created.
+	Schema []string
+	// Fixtures are YAML or JSON fixture files loaded after the schema. See LoadFixtures.
+	Fixtures []string
+}
+
+// Executor defines the operations used to apply schemas and fixtures.
+// It is implemented by godb.DB and godb.Tx.
+type Executor interface {
+	DriverName() string
+	Rebind(query string) string
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// NewSQLiteDB Returns an isolated SQLite database with foreign keys enabled.
+// The database is closed and removed when the test finishes.
+func NewSQLiteDB(t testing.TB, config Config) godb.DB {
+	t.Helper()
+
+	name := fmt.Sprintf("%s_%d", invalidDBChars.ReplaceAllString(t.Name(), "_"), dbCounter.Add(1))
+	params := godb.DBConnectionParams{
+		"_foreign_keys": "1",
+		"_busy_timeout": "5000",
+	}
+
+	if config.File {
+		name = filepath.Join(t.TempDir(), name)
+	} else {
+		params["mode"] = "memory"
+		params["cache"] = "private"
+	}
+
+	db, err := godb.NewDB(godb.DBConfig{
+		User:             testDBUser,
+		Password:         testDBPassword,
+		Database:         name,
+		DatabaseType:     godb.SQLiteDB,
+		ConnectTimeout:   testDBConnectTimeout,
+		ConnectionParams: params,
+	})
+	if err != nil {
+		t.Fatalf("failed to create test database. %s", err)
+	}
+
+	if !config.File {
+		// Every connection to a private in-memory database sees a different database
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxIdleTime(0)
+		db.SetConnMaxLifetime(0)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close test database. %s", err)
+		}
+	})
+
+	ApplySchema(t, db, config.Schema...)
+	LoadFixtures(t, db, config.Fixtures...)
+	return db
+}
+
+// NewRollbackTx Begins a transaction that is rolled back when the test finishes.
+// It isolates tests that share a database.
+func NewRollbackTx(t testing.TB, db godb.DB) godb.Tx {
+	t.Helper()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to begin test transaction. %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("failed to rollback test transaction. %s", err)
+		}
+	})
+	return tx
+}
+
+// ApplySchema Executes the SQL files in the given order
+func ApplySchema(t testing.TB, db Executor, files ...string) {
+	t.Helper()
+
+	if err := applySchema(context.Background(), db, files...); err != nil {
+		t.Fatalf("failed to apply schema. %s", err)
+	}
+}
+
+// LoadFixtures Loads the YAML or JSON fixture files into the database.
+//
+// Each file maps table names to the list of rows to insert:
+//
+//	users:
+//	  - id: 1
+//	    name: John Doe
+//	orders:
+//	  - id: 1
+//	    user_id: 1
+//
+// Rows from different files are merged. The fixture tables are emptied first and
+// filled in foreign key order, so referenced tables are loaded before the tables
+// referencing them. Nested objects and lists are stored as JSON.
+func LoadFixtures(t testing.TB, db Executor, files ...string) {
+	t.Helper()
+
+	if err := loadFixtures(context.Background(), db, files...); err != nil {
+		t.Fatalf("failed to load fixtures. %s", err)
+	}
+}
+
+// applySchema executes the SQL files in the given order
+func applySchema(ctx context.Context, db Executor, files ...string) error {
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		if _, err := db.ExecContext(ctx, string(content)); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// fixtures defines the rows of each table
+type fixtures map[string][]map[string]interface{}
+
+// loadFixtures loads the fixture files into the database
+func loadFixtures(ctx context.Context, db Executor, files ...string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	data := fixtures{}
+	for _, file := range files {
+		if err := data.read(file); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	tables, err := loadOrder(ctx, db, data)
+	if err != nil {
+		return err
+	}
+
+	for index := len(tables) - 1; index >= 0; index-- {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+godb.QuoteQualified(db.DriverName(), tables[index])); err != nil {
+			return fmt.Errorf("failed to clear %s. %w", tables[index], err)
+		}
+	}
+
+	for _, table := range tables {
+		for _, row := range data[table] {
+			if err := insertRow(ctx, db, table, row); err != nil {
+				return fmt.Errorf("failed to insert into %s. %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// read merges the rows of a fixture file
+func (f fixtures) read(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	fileData := fixtures{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, &fileData)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		err = decoder.Decode(&fileData)
+	default:
+		err = ErrUnsupportedFixture
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for table, rows := range fileData {
+		f[table] = append(f[table], rows...)
+	}
+	return nil
+}
+
+// loadOrder sorts the fixture tables so referenced tables come first
+func loadOrder(ctx context.Context, db Executor, data fixtures) ([]string, error) {
+	pending := map[string][]string{}
+	for table := range data {
+		references, err := foreignTables(ctx, db, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the foreign keys of %s. %w", table, err)
+		}
+
+		for _, reference := range references {
+			if _, ok := data[reference]; ok && reference != table {
+				pending[table] = append(pending[table], reference)
+			}
+		}
+	}
+
+	tables := make([]string, 0, len(data))
+	loaded := map[string]bool{}
+	for len(tables) < len(data) {
+		ready := []string{}
+		for table := range data {
+			if !loaded[table] && allLoaded(pending[table], loaded) {
+				ready = append(ready, table)
+			}
+		}
+
+		if len(ready) == 0 {
+			return nil, ErrCircularForeignKeys
+		}
+
+		sort.Strings(ready)
+		for _, table := range ready {
+			loaded[table] = true
+		}
+		tables = append(tables, ready...)
+	}
+	return tables, nil
+}
+
+// allLoaded check if all tables were loaded
+func allLoaded(tables []string, loaded map[string]bool) bool {
+	for _, table := range tables {
+		if !loaded[table] {
+			return false
+		}
+	}
+	return true
+}
+
+// foreignTables returns the tables referenced by the foreign keys of table
+func foreignTables(ctx context.Context, db Executor, table string) ([]string, error) {
+	var query string
+	switch db.DriverName() {
+	case "sqlite3":
+		query = `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`
+	case "postgres":
+		query = `
+			SELECT DISTINCT ccu.table_name
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.constraint_column_usage ccu
+				ON ccu.constraint_name = tc.constraint_name AND ccu.constraint_schema = tc.constraint_schema
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema() AND tc.table_name = ?
+		`
+	case "mysql":
+		query = `
+			SELECT DISTINCT referenced_table_name
+			FROM information_schema.key_column_usage
+			WHERE table_schema = DATABASE() AND table_name = ? AND referenced_table_name IS NOT NULL
+		`
+	default:
+		return nil, nil
+	}
+
+	var tables []string
+	err := db.SelectContext(ctx, &tables, db.Rebind(query), table)
+	return tables, err
+}
+
+// insertRow inserts a fixture row
+func insertRow(ctx context.Context, db Executor, table string, row map[string]interface{}) error {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for index, column := range columns {
+		value, err := fixtureValue(row[column])
+		if err != nil {
+			return err
+		}
+
+		quoted[index] = godb.QuoteIdentifier(db.DriverName(), column)
+		placeholders[index] = "?"
+		args[index] = value
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		godb.QuoteQualified(db.DriverName(), table),
+		strings.Join(quoted, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	_, err := db.ExecContext(ctx, db.Rebind(query), args...)
+	return err
+}
+
+// fixtureValue converts a decoded fixture value into a query argument
+func fixtureValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if number, err := v.Int64(); err == nil {
+			return number, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return v, nil
+	}
+}
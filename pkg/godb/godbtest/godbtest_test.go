@@ -0,0 +1,88 @@
+package godbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSQLiteDB(t *testing.T) {
+	type order struct {
+		ID     int    `db:"id"`
+		UserID int    `db:"user_id"`
+		Items  string `db:"items"`
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name: "should create an in-memory database",
+			config: Config{
+				Schema:   []string{"testdata/schema.sql"},
+				Fixtures: []string{"testdata/orders.yml", "testdata/users.json"},
+			},
+		},
+		{
+			name: "should create a temp file database",
+			config: Config{
+				File:     true,
+				Schema:   []string{"testdata/schema.sql"},
+				Fixtures: []string{"testdata/orders.yml", "testdata/users.json"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := NewSQLiteDB(t, test.config)
+
+			var names []string
+			assert.NoError(t, db.Select(&names, "SELECT name FROM users ORDER BY id"))
+			assert.Equal(t, []string{"John Doe", "Jane Doe"}, names)
+
+			var data order
+			assert.NoError(t, db.Get(&data, "SELECT * FROM orders"))
+			assert.Equal(t, 1, data.UserID)
+			assert.JSONEq(t, `[{"sku": "A-1", "quantity": 2}]`, data.Items, "nested values should be stored as JSON")
+
+			LoadFixtures(t, db, "testdata/users.json", "testdata/orders.yml")
+			var count int
+			assert.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM order_notes"))
+			assert.Equal(t, 1, count, "fixture tables should be emptied before loading")
+
+			_, err := db.Exec("INSERT INTO orders (id, user_id) VALUES (?, ?)", 11, 99)
+			assert.Error(t, err, "foreign keys should be enabled")
+		})
+	}
+}
+
+func TestNewRollbackTx(t *testing.T) {
+	db := NewSQLiteDB(t, Config{File: true, Schema: []string{"testdata/schema.sql"}})
+
+	t.Run("should load fixtures inside the transaction", func(t *testing.T) {
+		tx := NewRollbackTx(t, db)
+		LoadFixtures(t, tx, "testdata/users.json")
+
+		var count int
+		assert.NoError(t, tx.Get(&count, "SELECT COUNT(*) FROM users"))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("should roll back the previous test", func(t *testing.T) {
+		var count int
+		assert.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM users"))
+		assert.Equal(t, 0, count)
+	})
+}
+
+func TestLoadFixturesErrors(t *testing.T) {
+	db := NewSQLiteDB(t, Config{Schema: []string{"testdata/circular.sql"}})
+
+	assert.ErrorIs(t, loadFixtures(context.Background(), db, "testdata/circular.yml"), ErrCircularForeignKeys)
+	assert.ErrorIs(t, loadFixtures(context.Background(), db, "testdata/circular.sql"), ErrUnsupportedFixture)
+	assert.Error(t, loadFixtures(context.Background(), db, "testdata/unknown.yml"))
+	assert.Error(t, applySchema(context.Background(), db, "testdata/orders.yml"))
+}
@@ -0,0 +1,16 @@
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE orders (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	items TEXT
+);
+
+CREATE TABLE order_notes (
+	id INTEGER PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders (id),
+	note TEXT NOT NULL
+);
@@ -0,0 +1,468 @@
+package godb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/JhonatanRSantos/gocore/pkg/goenv"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+)
+
+// GuardRule identifies a kind of dangerous statement
+type GuardRule string
+
+// Rules checked by the statement guard
+const (
+	// RuleUpdateWithoutWhere reports UPDATE statements without a WHERE clause.
+	RuleUpdateWithoutWhere GuardRule = "update_without_where"
+	// RuleDeleteWithoutWhere reports DELETE statements without a WHERE clause.
+	RuleDeleteWithoutWhere GuardRule = "delete_without_where"
+	// RuleDDL reports CREATE, ALTER, DROP, TRUNCATE and RENAME statements outside migrations.
+	// See WithMigration.
+	RuleDDL GuardRule = "ddl"
+	// RuleSelectStar reports SELECT * on the GuardConfig.LargeTables.
+	RuleSelectStar GuardRule = "select_star"
+	// RuleMissingLimit reports Select, Query and NamedQuery statements reading a table without
+	// a LIMIT or FETCH clause.
+	RuleMissingLimit GuardRule = "missing_limit"
+)
+
+// GuardAction defines what the guard does with a dangerous statement
+type GuardAction int
+
+const (
+	// GuardOff executes the statement.
+	GuardOff GuardAction = iota
+	// GuardLog executes the statement and logs a warning.
+	GuardLog
+	// GuardBlock returns an error wrapping ErrDangerousStatement instead of executing the statement.
+	GuardBlock
+)
+
+// GuardPolicy defines the action of each rule. Missing rules are GuardOff.
+type GuardPolicy map[GuardRule]GuardAction
+
+// GuardLogger defines the logger used by the guard
+type GuardLogger interface {
+	Warn(ctx context.Context, message string, opts ...golog.Options)
+}
+
+// GuardConfig defines the statement guard configs
+type GuardConfig struct {
+	// Env selects DefaultGuardPolicy(Env) when Policy is nil. Empty is treated as production,
+	// so nothing is checked unless an environment is set.
+	Env goenv.Env
+	// Policy overrides the default policy of Env.
+	Policy GuardPolicy
+	// LargeTables are the tables reported by RuleSelectStar, optionally qualified with their
+	// schema. Empty reports every table.
+	LargeTables []string
+	// Allowlist skips the statements matching any of the expressions.
+	Allowlist []*regexp.Regexp
+	// Logger logs the GuardLog statements. Defaults to golog.Log().
+	Logger GuardLogger
+}
+
+// GuardViolation describes a dangerous statement
+type GuardViolation struct {
+	Rule      GuardRule
+	Operation string
+	Query     string
+	// Table is the table read by RuleSelectStar and RuleMissingLimit statements.
+	Table string
+}
+
+// DefaultGuardPolicy Returns the default policy of an environment. Production and empty
+// environments are never checked, staging blocks writes without WHERE and logs the other rules, and the other
+// environments also block DDL.
+func DefaultGuardPolicy(env goenv.Env) GuardPolicy {
+	switch env {
+	case goenv.Production, "":
+		return GuardPolicy{}
+	case goenv.Staging:
+		return GuardPolicy{
+			RuleUpdateWithoutWhere: GuardBlock,
+			RuleDeleteWithoutWhere: GuardBlock,
+			RuleDDL:                GuardLog,
+			RuleSelectStar:         GuardLog,
+			RuleMissingLimit:       GuardLog,
+		}
+	default:
+		return GuardPolicy{
+			RuleUpdateWithoutWhere: GuardBlock,
+			RuleDeleteWithoutWhere: GuardBlock,
+			RuleDDL:                GuardBlock,
+			RuleSelectStar:         GuardLog,
+			RuleMissingLimit:       GuardLog,
+		}
+	}
+}
+
+// guardAllowKey defines the context key of AllowStatements
+type guardAllowKey struct{}
+
+// AllowStatements Returns a context whose statements skip the given rules, or every rule
+// if none is given.
+func AllowStatements(ctx context.Context, rules ...GuardRule) context.Context {
+	if len(rules) == 0 {
+		rules = []GuardRule{RuleUpdateWithoutWhere, RuleDeleteWithoutWhere, RuleDDL, RuleSelectStar, RuleMissingLimit}
+	}
+
+	allowed := map[GuardRule]bool{}
+	if parent, ok := ctx.Value(guardAllowKey{}).(map[GuardRule]bool); ok {
+		for rule := range parent {
+			allowed[rule] = true
+		}
+	}
+
+	for _, rule := range rules {
+		allowed[rule] = true
+	}
+	return context.WithValue(ctx, guardAllowKey{}, allowed)
+}
+
+// WithMigration Returns a context for migrations, whose DDL statements are allowed
+func WithMigration(ctx context.Context) context.Context {
+	return AllowStatements(ctx, RuleDDL)
+}
+
+// statementGuard implements the statement guard hook
+type statementGuard struct {
+	policy      GuardPolicy
+	largeTables map[string]bool
+	allowlist   []*regexp.Regexp
+	logger      GuardLogger
+}
+
+// NewStatementGuard Returns a QueryHook that checks every statement for dangerous patterns,
+// such as UPDATE and DELETE without WHERE, and logs or blocks them according to the policy.
+// Install it with DBConfig.Hooks or DB.AddHook.
+//
+// Statements are checked with a lightweight tokenizer, not a full SQL parser, so unusual
+// statements can be reported by mistake. Use the allowlist or AllowStatements to skip them.
+func NewStatementGuard(config GuardConfig) QueryHook {
+	guard := &statementGuard{
+		policy:      config.Policy,
+		largeTables: map[string]bool{},
+		allowlist:   config.Allowlist,
+		logger:      config.Logger,
+	}
+
+	if guard.policy == nil {
+		guard.policy = DefaultGuardPolicy(config.Env)
+	}
+
+	if guard.logger == nil {
+		guard.logger = golog.Log()
+	}
+
+	for _, table := range config.LargeTables {
+		guard.largeTables[strings.ToLower(table)] = true
+	}
+	return guard
+}
+
+// BeforeQuery
+func (sg *statementGuard) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	for _, expression := range sg.allowlist {
+		if expression.MatchString(event.Query) {
+			return ctx, nil
+		}
+	}
+
+	allowed, _ := ctx.Value(guardAllowKey{}).(map[GuardRule]bool)
+	for _, violation := range sg.check(event.Operation, event.Query) {
+		if allowed[violation.Rule] {
+			continue
+		}
+
+		switch sg.policy[violation.Rule] {
+		case GuardBlock:
+			return ctx, fmt.Errorf("%w. %s: %s", ErrDangerousStatement, violation.Rule, violation.Query)
+		case GuardLog:
+			sg.logger.Warn(ctx, "dangerous statement", golog.WithTags(map[string]interface{}{
+				"rule":      string(violation.Rule),
+				"operation": violation.Operation,
+				"query":     violation.Query,
+				"table":     violation.Table,
+			}))
+		}
+	}
+	return ctx, nil
+}
+
+// AfterQuery
+func (sg *statementGuard) AfterQuery(context.Context, *QueryEvent) {}
+
+// check returns the rules broken by the statements of a query, ignoring the policy
+func (sg *statementGuard) check(operation string, query string) []GuardViolation {
+	violations := []GuardViolation{}
+	for _, statement := range tokenizeSQL(query) {
+		violations = append(violations, sg.checkStatement(operation, query, statement)...)
+	}
+	return violations
+}
+
+// checkStatement returns the rules broken by a statement
+func (sg *statementGuard) checkStatement(operation string, query string, tokens []sqlToken) []GuardViolation {
+	// Only the top level of the statement is checked, so subqueries are ignored
+	top := []sqlToken{}
+	for _, token := range tokens {
+		if token.depth == 0 {
+			top = append(top, token)
+		}
+	}
+
+	if len(top) == 0 {
+		return nil
+	}
+
+	violation := func(rule GuardRule, table string) []GuardViolation {
+		return []GuardViolation{{Rule: rule, Operation: operation, Query: query, Table: table}}
+	}
+
+	keyword := top[0].word
+	if keyword == "WITH" {
+		// The statement of a common table expression follows the CTE definitions
+		for _, token := range top[1:] {
+			if token.is("SELECT", "INSERT", "UPDATE", "DELETE", "MERGE") {
+				keyword = token.word
+				break
+			}
+		}
+	}
+
+	switch keyword {
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME":
+		return violation(RuleDDL, "")
+	case "UPDATE":
+		if !containsKeyword(top, "WHERE") {
+			return violation(RuleUpdateWithoutWhere, "")
+		}
+	case "DELETE":
+		if !containsKeyword(top, "WHERE") {
+			return violation(RuleDeleteWithoutWhere, "")
+		}
+	case "SELECT":
+		return sg.checkSelect(operation, top, violation)
+	}
+	return nil
+}
+
+// checkSelect returns the rules broken by a SELECT statement
+func (sg *statementGuard) checkSelect(
+	operation string,
+	top []sqlToken,
+	violation func(rule GuardRule, table string) []GuardViolation,
+) []GuardViolation {
+	start := 0
+	for top[start].word != "SELECT" {
+		start++
+	}
+
+	table := ""
+	star := false
+	for index := start + 1; index < len(top); index++ {
+		token := top[index]
+		if token.word == "FROM" {
+			if index+1 < len(top) && top[index+1].identifier() {
+				table = strings.ToLower(top[index+1].text)
+			}
+			break
+		}
+
+		// Multiplications are not projections: a star must follow SELECT, DISTINCT, ALL,
+		// a comma or a qualifier such as users.*
+		if token.text == "*" {
+			previous := top[index-1]
+			star = star || previous.is("SELECT", "DISTINCT", "ALL") || previous.text == "," || strings.HasSuffix(previous.text, ".")
+		}
+	}
+
+	if table == "" {
+		return nil
+	}
+
+	violations := []GuardViolation{}
+	if star && sg.isLargeTable(table) {
+		violations = append(violations, violation(RuleSelectStar, table)...)
+	}
+
+	switch operation {
+	case OperationSelect, OperationQuery, OperationNamedQuery:
+		if !containsKeyword(top[start:], "LIMIT", "FETCH") {
+			violations = append(violations, violation(RuleMissingLimit, table)...)
+		}
+	}
+	return violations
+}
+
+// isLargeTable returns if a table is one of the large tables
+func (sg *statementGuard) isLargeTable(table string) bool {
+	if len(sg.largeTables) == 0 || sg.largeTables[table] {
+		return true
+	}
+	return sg.largeTables[table[strings.LastIndex(table, ".")+1:]]
+}
+
+// containsKeyword returns if any of the tokens is one of the keywords
+func containsKeyword(tokens []sqlToken, keywords ...string) bool {
+	for _, token := range tokens {
+		if token.is(keywords...) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlToken defines a token of a SQL statement
+type sqlToken struct {
+	// text is the token text. Quoted identifiers are unquoted and strings are replaced by ''.
+	text string
+	// word is the upper case text of unquoted words, used to match keywords.
+	word string
+	// depth is the parenthesis depth of the token.
+	depth int
+	// quoted is set for quoted identifiers.
+	quoted bool
+}
+
+// is returns if the token is one of the keywords
+func (st sqlToken) is(keywords ...string) bool {
+	for _, keyword := range keywords {
+		if st.word == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// identifier returns if the token can be a table name
+func (st sqlToken) identifier() bool {
+	return st.quoted || st.word != ""
+}
+
+// tokenizeSQL splits a query in statements and tokens. Comments are skipped.
+func tokenizeSQL(query string) [][]sqlToken {
+	statements := [][]sqlToken{}
+	tokens := []sqlToken{}
+	depth := 0
+
+	runes := []rune(query)
+	for index := 0; index < len(runes); index++ {
+		current := runes[index]
+		next := rune(0)
+		if index+1 < len(runes) {
+			next = runes[index+1]
+		}
+
+		switch {
+		case unicode.IsSpace(current):
+		case current == '-' && next == '-':
+			for index < len(runes) && runes[index] != '\n' {
+				index++
+			}
+		case current == '/' && next == '*':
+			index += 2
+			for index < len(runes) && !(runes[index] == '*' && index+1 < len(runes) && runes[index+1] == '/') {
+				index++
+			}
+			index++
+		case current == '\'':
+			index = skipQuoted(runes, index, '\'')
+			tokens = append(tokens, sqlToken{text: "''", depth: depth})
+		case current == '"' || current == '`':
+			end := skipQuoted(runes, index, current)
+			text := strings.ReplaceAll(string(runes[index+1:min(end, len(runes))]), string([]rune{current, current}), string(current))
+			index = end
+
+			// Qualified names such as app."users" are merged into a single token
+			if count := len(tokens); count > 0 && strings.HasSuffix(tokens[count-1].text, ".") {
+				tokens[count-1].text += text
+				tokens[count-1].quoted = true
+				continue
+			}
+			tokens = append(tokens, sqlToken{text: text, depth: depth, quoted: true})
+		case current == '$' && (next == '$' || unicode.IsLetter(next) || next == '_'):
+			index = skipDollarQuoted(runes, index)
+			tokens = append(tokens, sqlToken{text: "''", depth: depth})
+		case current == '(':
+			tokens = append(tokens, sqlToken{text: "(", depth: depth})
+			depth++
+		case current == ')':
+			depth = max(depth-1, 0)
+			tokens = append(tokens, sqlToken{text: ")", depth: depth})
+		case current == ';' && depth == 0:
+			if len(tokens) > 0 {
+				statements = append(statements, tokens)
+			}
+			tokens = []sqlToken{}
+		case isWordRune(current):
+			start := index
+			for index+1 < len(runes) && isWordRune(runes[index+1]) {
+				index++
+			}
+
+			text := string(runes[start : index+1])
+			if count := len(tokens); count > 0 && tokens[count-1].quoted && strings.HasPrefix(text, ".") {
+				tokens[count-1].text += text
+				continue
+			}
+			tokens = append(tokens, sqlToken{text: text, word: strings.ToUpper(text), depth: depth})
+		default:
+			tokens = append(tokens, sqlToken{text: string(current), depth: depth})
+		}
+	}
+
+	if len(tokens) > 0 {
+		statements = append(statements, tokens)
+	}
+	return statements
+}
+
+// isWordRune returns if r is part of a word, including qualified names and placeholders
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '$' || r == ':' || r == '@'
+}
+
+// skipQuoted returns the index of the quote closing the quoted text starting at start.
+// Doubled quotes and backslash escapes are part of the text.
+func skipQuoted(runes []rune, start int, quote rune) int {
+	for index := start + 1; index < len(runes); index++ {
+		switch runes[index] {
+		case '\\':
+			if quote == '\'' {
+				index++
+			}
+		case quote:
+			if index+1 < len(runes) && runes[index+1] == quote {
+				index++
+				continue
+			}
+			return index
+		}
+	}
+	return len(runes)
+}
+
+// skipDollarQuoted returns the index of the end of a Postgres dollar quoted string, such as
+// $$text$$ or $tag$text$tag$
+func skipDollarQuoted(runes []rune, start int) int {
+	end := start + 1
+	for end < len(runes) && runes[end] != '$' {
+		if !unicode.IsLetter(runes[end]) && !unicode.IsDigit(runes[end]) && runes[end] != '_' {
+			// Not a dollar quote, such as a $1 placeholder
+			return end - 1
+		}
+		end++
+	}
+
+	tag := string(runes[start : end+1])
+	if closing := strings.Index(string(runes[end+1:]), tag); closing >= 0 {
+		return end + len([]rune(string(runes[end+1:])[:closing])) + len([]rune(tag))
+	}
+	return len(runes)
+}
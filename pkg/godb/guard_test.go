@@ -0,0 +1,110 @@
+package godb
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/goenv"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/stretchr/testify/assert"
+)
+
+// guardRecorder records the guard warnings
+type guardRecorder struct {
+	messages []string
+}
+
+// Warn
+func (gr *guardRecorder) Warn(_ context.Context, message string, _ ...golog.Options) {
+	gr.messages = append(gr.messages, message)
+}
+
+func Test_StatementGuardCheck(t *testing.T) {
+	guard := NewStatementGuard(GuardConfig{LargeTables: []string{"events"}}).(*statementGuard)
+
+	tests := []struct {
+		name      string
+		operation string
+		query     string
+		expected  []GuardRule
+	}{
+		{name: "update with where", operation: OperationExec, query: "UPDATE users SET name = 'WHERE' WHERE id = 1"},
+		{name: "update without where", operation: OperationExec, query: "UPDATE users SET name = 'x WHERE y'", expected: []GuardRule{RuleUpdateWithoutWhere}},
+		{name: "update with subquery where", operation: OperationExec, query: "UPDATE users SET age = (SELECT 1 FROM t WHERE a = 1)", expected: []GuardRule{RuleUpdateWithoutWhere}},
+		{name: "delete without where", operation: OperationExec, query: "delete from users -- WHERE id = 1", expected: []GuardRule{RuleDeleteWithoutWhere}},
+		{name: "delete with cte", operation: OperationExec, query: "WITH old AS (SELECT id FROM users WHERE age > 90) DELETE FROM users", expected: []GuardRule{RuleDeleteWithoutWhere}},
+		{name: "multiple statements", operation: OperationExec, query: "UPDATE a SET x = 1 WHERE id = 1; /* cleanup */ TRUNCATE b", expected: []GuardRule{RuleDDL}},
+		{name: "ddl", operation: OperationExec, query: "  CREATE TABLE users (id INT)", expected: []GuardRule{RuleDDL}},
+		{name: "select star", operation: OperationGet, query: `SELECT * FROM "app"."events" WHERE id = $1`, expected: []GuardRule{RuleSelectStar}},
+		{name: "qualified select star", operation: OperationGet, query: "SELECT e.* FROM `events` e WHERE id = ?", expected: []GuardRule{RuleSelectStar}},
+		{name: "small table star", operation: OperationGet, query: "SELECT * FROM countries WHERE id = ?"},
+		{name: "count star", operation: OperationGet, query: "SELECT COUNT(*), price * 2 FROM events"},
+		{name: "missing limit", operation: OperationSelect, query: "SELECT id FROM users WHERE age > ? ORDER BY id", expected: []GuardRule{RuleMissingLimit}},
+		{name: "star and missing limit", operation: OperationQuery, query: "SELECT * FROM events", expected: []GuardRule{RuleSelectStar, RuleMissingLimit}},
+		{name: "limit", operation: OperationSelect, query: "SELECT id FROM users LIMIT 10"},
+		{name: "fetch", operation: OperationSelect, query: "SELECT id FROM users FETCH FIRST 10 ROWS ONLY"},
+		{name: "subquery limit", operation: OperationSelect, query: "SELECT id FROM users WHERE id IN (SELECT user_id FROM orders LIMIT 5)", expected: []GuardRule{RuleMissingLimit}},
+		{name: "without table", operation: OperationSelect, query: "SELECT 1"},
+		{name: "dollar quoted", operation: OperationExec, query: "INSERT INTO scripts (body) VALUES ($body$DELETE FROM users$body$)"},
+	}
+
+	for _, test := range tests {
+		rules := []GuardRule{}
+		for _, violation := range guard.check(test.operation, test.query) {
+			rules = append(rules, violation.Rule)
+		}
+
+		if test.expected == nil {
+			test.expected = []GuardRule{}
+		}
+		assert.Equal(t, test.expected, rules, test.name)
+	}
+}
+
+func Test_StatementGuard(t *testing.T) {
+	ddl := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO users (id, name) VALUES (1, 'John Doe'), (2, 'Jane Doe');
+	`
+
+	ctx := context.Background()
+	logger := &guardRecorder{}
+	db := newSQLiteTestDB(t, ddl)
+	db.AddHook(NewStatementGuard(GuardConfig{
+		Env:       goenv.Development,
+		Allowlist: []*regexp.Regexp{regexp.MustCompile(`^DELETE FROM users$`)},
+		Logger:    logger,
+	}))
+
+	_, err := db.ExecContext(ctx, "UPDATE users SET name = ?", "Jim")
+	assert.ErrorIs(t, err, ErrDangerousStatement)
+	assert.ErrorContains(t, err, string(RuleUpdateWithoutWhere))
+
+	_, err = db.ExecContext(ctx, "DROP TABLE users")
+	assert.ErrorIs(t, err, ErrDangerousStatement)
+
+	users := []string{}
+	assert.NoError(t, db.SelectContext(ctx, &users, "SELECT name FROM users ORDER BY id"))
+	assert.Equal(t, []string{"John Doe", "Jane Doe"}, users, "blocked statements should not be executed")
+	assert.Equal(t, []string{"dangerous statement"}, logger.messages, "missing limits should be logged")
+
+	_, err = db.ExecContext(AllowStatements(ctx, RuleUpdateWithoutWhere), "UPDATE users SET name = ?", "Jim")
+	assert.NoError(t, err)
+	_, err = db.ExecContext(WithMigration(ctx), "CREATE TABLE events (id INTEGER)")
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, "DELETE FROM users")
+	assert.NoError(t, err, "allowlisted statements should be executed")
+
+	production := newSQLiteTestDB(t, ddl)
+	production.AddHook(NewStatementGuard(GuardConfig{Env: goenv.Production, Logger: logger}))
+	_, err = production.ExecContext(ctx, "DELETE FROM users")
+	assert.NoError(t, err, "production should not be checked by default")
+
+	unset := newSQLiteTestDB(t, ddl)
+	unset.AddHook(NewStatementGuard(GuardConfig{Logger: logger}))
+	_, err = unset.ExecContext(ctx, "CREATE TABLE audit (id INTEGER PRIMARY KEY)")
+	assert.NoError(t, err, "an empty env should not be checked by default")
+	_, err = unset.ExecContext(ctx, "DELETE FROM users")
+	assert.NoError(t, err, "an empty env should not be checked by default")
+}
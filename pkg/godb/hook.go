@@ -0,0 +1,320 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Operations reported by the query hooks
+const (
+	OperationExec         = "Exec"
+	OperationGet          = "Get"
+	OperationSelect       = "Select"
+	OperationQuery        = "Query"
+	OperationQueryRow     = "QueryRow"
+	OperationNamedExec    = "NamedExec"
+	OperationNamedQuery   = "NamedQuery"
+	OperationPrepare      = "Prepare"
+	OperationPrepareNamed = "PrepareNamed"
+)
+
+// QueryEvent describes a statement executed by godb
+type QueryEvent struct {
+	// Operation is the godb operation, such as OperationExec or OperationSelect.
+	Operation string
+	// Query is the statement text. BeforeQuery hooks can rewrite it, except for prepared
+	// statements whose text is fixed when they are prepared. Named statements report
+	// their bound query.
+	Query string
+	// Args are the statement arguments. BeforeQuery hooks can rewrite them, AfterQuery hooks
+	// receive them encoded for the database.
+	// Named operations have a single argument holding the struct or map.
+	Args []interface{}
+	// StartedAt is when the statement started. Set before AfterQuery.
+	StartedAt time.Time
+	// Duration is how long the statement took. Set before AfterQuery.
+	Duration time.Duration
+	// RowsAffected is the amount of rows affected by Exec operations, the amount of rows read
+	// by Select and 1 for a successful Get. Set before AfterQuery.
+	RowsAffected int64
+	// Err is the error returned by the statement. Set before AfterQuery.
+	// Timeouts are reported as ErrQueryTimeout or ErrStatementTimeout.
+	Err error
+
+	// cancel releases the query timeout. Rows and Row release it when they are consumed.
+	cancel context.CancelFunc
+}
+
+// QueryHook defines the callbacks called around every statement.
+//
+// Hooks installed on a DB are inherited by its Tx, Conn, Stmt and NamedStmt.
+// BeforeQuery hooks are called in the order they were added and AfterQuery
+// hooks in the reverse order.
+type QueryHook interface {
+	// BeforeQuery is called before the statement. The returned context is used by the
+	// statement and the following hooks. If an error is returned the statement is not
+	// executed and the error is returned to the caller; AfterQuery is still called for
+	// the hooks that already ran.
+	BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error)
+	// AfterQuery is called after the statement.
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// QueryHookFuncs implements QueryHook using functions. Nil functions are skipped.
+type QueryHookFuncs struct {
+	Before func(ctx context.Context, event *QueryEvent) (context.Context, error)
+	After  func(ctx context.Context, event *QueryEvent)
+}
+
+// BeforeQuery
+func (qhf QueryHookFuncs) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	if qhf.Before != nil {
+		return qhf.Before(ctx, event)
+	}
+	return ctx, nil
+}
+
+// AfterQuery
+func (qhf QueryHookFuncs) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if qhf.After != nil {
+		qhf.After(ctx, event)
+	}
+}
+
+// queryHooks defines the hooks installed on a DB, Tx, Conn, Stmt or NamedStmt
+type queryHooks struct {
+	hooks []QueryHook
+	// dbType encodes the dialect dependent arguments, such as Array. Zero leaves them as they are.
+	dbType DBType
+}
+
+// with returns a copy of the hooks including the given ones
+func (qh queryHooks) with(hooks ...QueryHook) queryHooks {
+	all := make([]QueryHook, 0, len(qh.hooks)+len(hooks))
+	return queryHooks{hooks: append(append(all, qh.hooks...), hooks...), dbType: qh.dbType}
+}
+
+// encodeArgs encodes the dialect dependent arguments
+func (qh queryHooks) encodeArgs(args []interface{}) ([]interface{}, error) {
+	if qh.dbType == 0 {
+		return args, nil
+	}
+	return encodeArgs(qh.dbType, args)
+}
+
+// bindNamed binds a named query with binder and encodes its arguments
+func (qh queryHooks) bindNamed(binder namedBinder, query string, arg interface{}) (string, []interface{}, error) {
+	query, args, err := binder.BindNamed(query, arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args, err = qh.encodeArgs(args)
+	return query, args, err
+}
+
+// run calls fn between the BeforeQuery and AfterQuery hooks
+func (qh queryHooks) run(
+	ctx context.Context,
+	operation string,
+	query string,
+	args []interface{},
+	fn func(ctx context.Context, event *QueryEvent) error,
+) error {
+	event := &QueryEvent{Operation: operation, Query: query, Args: args}
+	if len(qh.hooks) == 0 {
+		var err error
+		if event.Args, err = qh.encodeArgs(event.Args); err != nil {
+			return err
+		}
+		return timeoutError(ctx, fn(ctx, event))
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for index, hook := range qh.hooks {
+		var err error
+		if ctx, err = hook.BeforeQuery(ctx, event); err != nil {
+			event.Err = err
+			queryHooks{hooks: qh.hooks[:index]}.after(ctx, event)
+			return err
+		}
+	}
+
+	var err error
+	if event.Args, err = qh.encodeArgs(event.Args); err != nil {
+		event.Err = err
+		qh.after(ctx, event)
+		return err
+	}
+
+	event.StartedAt = time.Now()
+	event.Err = timeoutError(ctx, fn(ctx, event))
+	event.Duration = time.Since(event.StartedAt)
+
+	qh.after(ctx, event)
+	return event.Err
+}
+
+// after calls the AfterQuery hooks in the reverse order
+func (qh queryHooks) after(ctx context.Context, event *QueryEvent) {
+	for index := len(qh.hooks) - 1; index >= 0; index-- {
+		qh.hooks[index].AfterQuery(ctx, event)
+	}
+}
+
+// setResult sets the amount of rows affected by result
+func (e *QueryEvent) setResult(result sql.Result, err error) {
+	if err == nil && result != nil {
+		e.RowsAffected, _ = result.RowsAffected()
+	}
+}
+
+// setScanned sets the amount of rows scanned into dest
+func (e *QueryEvent) setScanned(dest interface{}, err error) {
+	if err != nil {
+		return
+	}
+
+	value := reflect.ValueOf(dest)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Slice {
+		e.RowsAffected = int64(value.Len())
+	} else {
+		e.RowsAffected = 1
+	}
+}
+
+// exec runs an exec operation between the hooks
+func (qh queryHooks) exec(
+	ctx context.Context,
+	operation string,
+	query string,
+	args []interface{},
+	fn func(ctx context.Context, query string, args ...interface{}) (sql.Result, error),
+) (sql.Result, error) {
+	var result sql.Result
+	err := qh.run(ctx, operation, query, args, func(ctx context.Context, event *QueryEvent) (err error) {
+		result, err = fn(ctx, event.Query, event.Args...)
+		event.setResult(result, err)
+		return err
+	})
+	return result, err
+}
+
+// scan runs a Get or Select operation between the hooks
+func (qh queryHooks) scan(
+	ctx context.Context,
+	operation string,
+	dest interface{},
+	query string,
+	args []interface{},
+	fn func(ctx context.Context, dest interface{}, query string, args ...interface{}) error,
+) error {
+	return qh.run(ctx, operation, query, args, func(ctx context.Context, event *QueryEvent) error {
+		err := fn(ctx, dest, event.Query, event.Args...)
+		event.setScanned(dest, err)
+		return err
+	})
+}
+
+// rows runs a query operation between the hooks
+func (qh queryHooks) rows(
+	ctx context.Context,
+	operation string,
+	query string,
+	args []interface{},
+	fn func(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error),
+) (Rows, error) {
+	result := &customRows{}
+	if err := qh.run(ctx, operation, query, args, func(ctx context.Context, event *QueryEvent) (err error) {
+		result.ctx, result.cancel = ctx, event.cancel
+		result.rows, err = fn(ctx, event.Query, event.Args...)
+		return err
+	}); err != nil {
+		return &customRows{}, err
+	}
+	return result, nil
+}
+
+// row runs a QueryRow operation between the hooks
+func (qh queryHooks) row(
+	ctx context.Context,
+	query string,
+	args []interface{},
+	fn func(ctx context.Context, query string, args ...interface{}) *sqlx.Row,
+) Row {
+	result := &customRow{}
+	err := qh.run(ctx, OperationQueryRow, query, args, func(ctx context.Context, event *QueryEvent) error {
+		result.ctx, result.cancel = ctx, event.cancel
+		result.row = fn(ctx, event.Query, event.Args...)
+		return result.row.Err()
+	})
+	if result.row == nil {
+		return &errorRow{err: err}
+	}
+	return result
+}
+
+// prepare runs a Prepare operation between the hooks. The statement inherits the hooks.
+func (qh queryHooks) prepare(
+	ctx context.Context,
+	query string,
+	fn func(ctx context.Context, query string) (*sqlx.Stmt, error),
+) (Stmt, error) {
+	var stmt *sqlx.Stmt
+	if err := qh.run(ctx, OperationPrepare, query, nil, func(ctx context.Context, event *QueryEvent) (err error) {
+		query = event.Query
+		stmt, err = fn(ctx, event.Query)
+		return err
+	}); err != nil {
+		return &customStmt{}, err
+	}
+	return &customStmt{stmt: stmt, hooks: qh, query: query}, nil
+}
+
+// prepareNamed runs a PrepareNamed operation between the hooks. The statement inherits the hooks.
+func (qh queryHooks) prepareNamed(
+	ctx context.Context,
+	query string,
+	fn func(ctx context.Context, query string) (*sqlx.NamedStmt, error),
+) (NamedStmt, error) {
+	var namedStmt *sqlx.NamedStmt
+	if err := qh.run(ctx, OperationPrepareNamed, query, nil, func(ctx context.Context, event *QueryEvent) (err error) {
+		namedStmt, err = fn(ctx, event.Query)
+		return err
+	}); err != nil {
+		return &customNamedStmt{}, err
+	}
+	return &customNamedStmt{namedStmt: namedStmt, hooks: qh}, nil
+}
+
+// namedBinder binds named queries, such as sqlx.DB and sqlx.Tx
+type namedBinder interface {
+	BindNamed(query string, arg interface{}) (string, []interface{}, error)
+}
+
+// namedArg returns the argument of a named operation, which a hook may have rewritten
+func namedArg(args []interface{}) interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	return args[0]
+}
+
+// mustExec panics if err is not nil
+func mustExec(result sql.Result, err error) sql.Result {
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
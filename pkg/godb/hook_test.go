@@ -0,0 +1,175 @@
+package godb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hookContextKey struct{}
+
+// recorderHook records the hook calls
+type recorderHook struct {
+	name   string
+	calls  *[]string
+	events []QueryEvent
+}
+
+// BeforeQuery
+func (rh *recorderHook) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	*rh.calls = append(*rh.calls, "before:"+rh.name)
+	return context.WithValue(ctx, hookContextKey{}, rh.name), nil
+}
+
+// AfterQuery
+func (rh *recorderHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	*rh.calls = append(*rh.calls, "after:"+rh.name)
+	rh.events = append(rh.events, *event)
+}
+
+func Test_QueryHooks(t *testing.T) {
+	type customData struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	ddl := `
+		CREATE TABLE custom_table (
+			id INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL
+		);
+		INSERT INTO custom_table (id, name) VALUES (1, 'John Doe'), (2, 'Jane Doe');
+	`
+
+	tests := []struct {
+		name   string
+		assert func(t *testing.T, db DB, hook *recorderHook)
+	}{
+		{
+			name: "should report db operations",
+			assert: func(t *testing.T, db DB, hook *recorderHook) {
+				_, err := db.Exec("UPDATE custom_table SET name = ?", "Johnny")
+				assert.NoError(t, err)
+
+				var list []customData
+				assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table"))
+
+				var data customData
+				assert.NoError(t, db.GetContext(context.Background(), &data, "SELECT * FROM custom_table WHERE id = ?", 1))
+
+				_, err = db.NamedExec("DELETE FROM custom_table WHERE id = :id", customData{ID: 2})
+				assert.NoError(t, err)
+
+				assert.Error(t, db.Get(&data, "SELECT * FROM unknown_table"))
+
+				assert.Len(t, hook.events, 5)
+				assert.Equal(t, OperationExec, hook.events[0].Operation)
+				assert.Equal(t, "UPDATE custom_table SET name = ?", hook.events[0].Query)
+				assert.Equal(t, []interface{}{"Johnny"}, hook.events[0].Args)
+				assert.Equal(t, int64(2), hook.events[0].RowsAffected)
+				assert.False(t, hook.events[0].StartedAt.IsZero())
+				assert.Equal(t, OperationSelect, hook.events[1].Operation)
+				assert.Equal(t, int64(2), hook.events[1].RowsAffected)
+				assert.Equal(t, OperationGet, hook.events[2].Operation)
+				assert.Equal(t, int64(1), hook.events[2].RowsAffected)
+				assert.Equal(t, OperationNamedExec, hook.events[3].Operation)
+				assert.Equal(t, []interface{}{customData{ID: 2}}, hook.events[3].Args)
+				assert.Equal(t, int64(1), hook.events[3].RowsAffected)
+				assert.Error(t, hook.events[4].Err)
+			},
+		},
+		{
+			name: "should be inherited by transactions and statements",
+			assert: func(t *testing.T, db DB, hook *recorderHook) {
+				tx, err := db.Begin()
+				assert.NoError(t, err)
+				_, err = tx.Exec("DELETE FROM custom_table WHERE id = ?", 1)
+				assert.NoError(t, err)
+				assert.NoError(t, tx.Rollback())
+
+				stmt, err := db.Prepare("SELECT name FROM custom_table WHERE id = ?")
+				assert.NoError(t, err)
+				var name string
+				assert.NoError(t, stmt.QueryRow(2).Scan(&name))
+				assert.NoError(t, stmt.Close())
+
+				namedStmt, err := db.PrepareNamed("SELECT name FROM custom_table WHERE id = :id")
+				assert.NoError(t, err)
+				assert.NoError(t, namedStmt.Get(&name, customData{ID: 1}))
+				assert.NoError(t, namedStmt.Close())
+
+				conn, err := db.Conn(context.Background())
+				assert.NoError(t, err)
+				rows, err := conn.QueryContext(context.Background(), "SELECT * FROM custom_table")
+				assert.NoError(t, err)
+				assert.NoError(t, rows.Close())
+				assert.NoError(t, conn.Close())
+
+				operations := []string{}
+				queries := []string{}
+				for _, event := range hook.events {
+					operations = append(operations, event.Operation)
+					queries = append(queries, event.Query)
+				}
+				assert.Equal(t, []string{
+					OperationExec,
+					OperationPrepare, OperationQueryRow,
+					OperationPrepareNamed, OperationGet,
+					OperationQuery,
+				}, operations)
+				assert.Equal(t, "SELECT name FROM custom_table WHERE id = ?", queries[2], "statements should report their query")
+				assert.Equal(t, "SELECT name FROM custom_table WHERE id = ?", queries[4], "named statements should report their bound query")
+			},
+		},
+		{
+			name: "should call hooks in order",
+			assert: func(t *testing.T, db DB, hook *recorderHook) {
+				second := &recorderHook{name: "second", calls: hook.calls}
+				db.AddHook(second, QueryHookFuncs{
+					Before: func(ctx context.Context, event *QueryEvent) (context.Context, error) {
+						assert.Equal(t, "second", ctx.Value(hookContextKey{}), "hooks should receive the previous context")
+						event.Query = "SELECT COUNT(*) FROM custom_table"
+						return ctx, nil
+					},
+				})
+				*hook.calls = (*hook.calls)[:0]
+
+				var count int
+				assert.NoError(t, db.Get(&count, "SELECT 0"))
+				assert.Equal(t, 2, count, "hooks should rewrite the query")
+				assert.Equal(t, []string{"before:first", "before:second", "after:second", "after:first"}, *hook.calls)
+			},
+		},
+		{
+			name: "should abort the statement",
+			assert: func(t *testing.T, db DB, hook *recorderHook) {
+				errBlocked := errors.New("blocked")
+				db.AddHook(QueryHookFuncs{
+					Before: func(ctx context.Context, event *QueryEvent) (context.Context, error) {
+						return ctx, errBlocked
+					},
+				})
+
+				_, err := db.Exec("DELETE FROM custom_table")
+				assert.ErrorIs(t, err, errBlocked)
+				assert.ErrorIs(t, db.QueryRow("SELECT * FROM custom_table").Scan(), errBlocked)
+				_, err = db.Prepare("SELECT * FROM custom_table")
+				assert.ErrorIs(t, err, errBlocked)
+
+				assert.Len(t, hook.events, 3, "previous hooks should be notified")
+				assert.ErrorIs(t, hook.events[0].Err, errBlocked)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newSQLiteTestDB(t, ddl)
+			hook := &recorderHook{name: "first", calls: &[]string{}}
+			db.AddHook(hook)
+			test.assert(t, db, hook)
+		})
+	}
+}
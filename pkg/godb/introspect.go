@@ -0,0 +1,419 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Schema describes the tables and views of a database
+type Schema struct {
+	Tables []Table `json:"tables"`
+	Views  []View  `json:"views"`
+}
+
+// Table describes a database table
+type Table struct {
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	PrimaryKey  []string     `json:"primary_key"`
+	Indexes     []Index      `json:"indexes"`
+	ForeignKeys []ForeignKey `json:"foreign_keys"`
+}
+
+// Column describes a table or view column
+type Column struct {
+	Name string `json:"name"`
+	// Type is the column type as reported by the database, such as "character varying(255)"
+	// on Postgres, "varchar(255)" on MySQL or the declared type on SQLite.
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default,omitempty"`
+	Position int     `json:"position"`
+}
+
+// Index describes a table index. Primary key indexes are not included.
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+// ForeignKey describes a foreign key. SQLite foreign keys have no name.
+type ForeignKey struct {
+	Name              string   `json:"name,omitempty"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnUpdate          string   `json:"on_update"`
+	OnDelete          string   `json:"on_delete"`
+}
+
+// View describes a database view
+type View struct {
+	Name       string   `json:"name"`
+	Definition string   `json:"definition,omitempty"`
+	Columns    []Column `json:"columns"`
+}
+
+// Inspector reads the schema of the database it is connected to.
+// Postgres is inspected within the current schema and MySQL within the current database.
+type Inspector interface {
+	// Schema returns every table and view.
+	Schema(ctx context.Context) (Schema, error)
+	// TableNames returns the table names in alphabetical order.
+	TableNames(ctx context.Context) ([]string, error)
+	// Table returns a table. It returns ErrTableNotFound if the table does not exist.
+	Table(ctx context.Context, name string) (Table, error)
+	// Views returns the views in alphabetical order.
+	Views(ctx context.Context) ([]View, error)
+}
+
+// NewInspector Returns the Inspector for the dialect of db
+func NewInspector(db DB) (Inspector, error) {
+	dbType, _ := driverDBType(db.DriverName())
+	switch dbType {
+	case SQLiteDB:
+		return &inspector{db: db, dialect: sqliteInspectorDialect}, nil
+	case PostgresDB:
+		return &inspector{db: db, dialect: postgresInspectorDialect}, nil
+	case MySQLDB:
+		return &inspector{db: db, dialect: mysqlInspectorDialect}, nil
+	default:
+		return nil, ErrInvalidDBType
+	}
+}
+
+// inspectorDialect defines the queries used to inspect a database.
+// Every query returns the columns expected by the row types below.
+type inspectorDialect struct {
+	tables      string
+	columns     string
+	primaryKey  string
+	indexes     string
+	foreignKeys string
+	views       string
+	// actions translates the foreign key actions reported by the database
+	actions map[string]string
+}
+
+// columnRow defines a row of the columns query
+type columnRow struct {
+	Name     string         `db:"name"`
+	Type     string         `db:"type"`
+	Nullable bool           `db:"nullable"`
+	Default  sql.NullString `db:"default_value"`
+	Position int            `db:"position"`
+}
+
+// indexRow defines a row of the indexes query, one per index column
+type indexRow struct {
+	Name   string `db:"name"`
+	Unique bool   `db:"is_unique"`
+	Column string `db:"column_name"`
+}
+
+// foreignKeyRow defines a row of the foreign keys query, one per key column
+type foreignKeyRow struct {
+	ID               string         `db:"id"`
+	Name             sql.NullString `db:"name"`
+	Column           string         `db:"column_name"`
+	ReferencedTable  string         `db:"referenced_table"`
+	ReferencedColumn sql.NullString `db:"referenced_column"`
+	OnUpdate         string         `db:"on_update"`
+	OnDelete         string         `db:"on_delete"`
+}
+
+// viewRow defines a row of the views query
+type viewRow struct {
+	Name       string         `db:"name"`
+	Definition sql.NullString `db:"definition"`
+}
+
+// inspector implements the Inspector interface
+type inspector struct {
+	db      DB
+	dialect inspectorDialect
+}
+
+// Schema
+func (i *inspector) Schema(ctx context.Context) (Schema, error) {
+	names, err := i.TableNames(ctx)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	schema := Schema{Tables: make([]Table, 0, len(names))}
+	for _, name := range names {
+		table, err := i.Table(ctx, name)
+		if err != nil {
+			return Schema{}, err
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+
+	if schema.Views, err = i.Views(ctx); err != nil {
+		return Schema{}, err
+	}
+	return schema, nil
+}
+
+// TableNames
+func (i *inspector) TableNames(ctx context.Context) ([]string, error) {
+	names := []string{}
+	if err := i.db.SelectContext(ctx, &names, i.db.Rebind(i.dialect.tables)); err != nil {
+		return nil, fmt.Errorf("failed to list tables. %w", err)
+	}
+	return names, nil
+}
+
+// Table
+func (i *inspector) Table(ctx context.Context, name string) (Table, error) {
+	columns, err := i.columns(ctx, name)
+	if err != nil {
+		return Table{}, err
+	}
+
+	if len(columns) == 0 {
+		return Table{}, fmt.Errorf("%s. %w", name, ErrTableNotFound)
+	}
+
+	table := Table{Name: name, Columns: columns, PrimaryKey: []string{}}
+	if err := i.db.SelectContext(ctx, &table.PrimaryKey, i.db.Rebind(i.dialect.primaryKey), name); err != nil {
+		return Table{}, fmt.Errorf("failed to read the primary key of %s. %w", name, err)
+	}
+
+	if table.Indexes, err = i.indexes(ctx, name); err != nil {
+		return Table{}, err
+	}
+
+	if table.ForeignKeys, err = i.foreignKeys(ctx, name); err != nil {
+		return Table{}, err
+	}
+	return table, nil
+}
+
+// Views
+func (i *inspector) Views(ctx context.Context) ([]View, error) {
+	var rows []viewRow
+	if err := i.db.SelectContext(ctx, &rows, i.db.Rebind(i.dialect.views)); err != nil {
+		return nil, fmt.Errorf("failed to list views. %w", err)
+	}
+
+	views := make([]View, 0, len(rows))
+	for _, row := range rows {
+		columns, err := i.columns(ctx, row.Name)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, View{Name: row.Name, Definition: row.Definition.String, Columns: columns})
+	}
+	return views, nil
+}
+
+// columns returns the columns of a table or view
+func (i *inspector) columns(ctx context.Context, name string) ([]Column, error) {
+	var rows []columnRow
+	if err := i.db.SelectContext(ctx, &rows, i.db.Rebind(i.dialect.columns), name); err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s. %w", name, err)
+	}
+
+	columns := make([]Column, 0, len(rows))
+	for _, row := range rows {
+		column := Column{Name: row.Name, Type: row.Type, Nullable: row.Nullable, Position: row.Position}
+		if row.Default.Valid {
+			column.Default = &row.Default.String
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// indexes returns the indexes of a table
+func (i *inspector) indexes(ctx context.Context, table string) ([]Index, error) {
+	var rows []indexRow
+	if err := i.db.SelectContext(ctx, &rows, i.db.Rebind(i.dialect.indexes), table); err != nil {
+		return nil, fmt.Errorf("failed to read the indexes of %s. %w", table, err)
+	}
+
+	indexes := []Index{}
+	for _, row := range rows {
+		if last := len(indexes) - 1; last >= 0 && indexes[last].Name == row.Name {
+			indexes[last].Columns = append(indexes[last].Columns, row.Column)
+			continue
+		}
+		indexes = append(indexes, Index{Name: row.Name, Unique: row.Unique, Columns: []string{row.Column}})
+	}
+	return indexes, nil
+}
+
+// foreignKeys returns the foreign keys of a table
+func (i *inspector) foreignKeys(ctx context.Context, table string) ([]ForeignKey, error) {
+	var rows []foreignKeyRow
+	if err := i.db.SelectContext(ctx, &rows, i.db.Rebind(i.dialect.foreignKeys), table); err != nil {
+		return nil, fmt.Errorf("failed to read the foreign keys of %s. %w", table, err)
+	}
+
+	var (
+		ids         []string
+		foreignKeys = []ForeignKey{}
+	)
+
+	for _, row := range rows {
+		last := len(foreignKeys) - 1
+		if last < 0 || ids[last] != row.ID {
+			ids = append(ids, row.ID)
+			foreignKeys = append(foreignKeys, ForeignKey{
+				Name:            row.Name.String,
+				ReferencedTable: row.ReferencedTable,
+				OnUpdate:        i.action(row.OnUpdate),
+				OnDelete:        i.action(row.OnDelete),
+			})
+			last++
+		}
+
+		foreignKeys[last].Columns = append(foreignKeys[last].Columns, row.Column)
+		foreignKeys[last].ReferencedColumns = append(foreignKeys[last].ReferencedColumns, row.ReferencedColumn.String)
+	}
+	return foreignKeys, nil
+}
+
+// action returns the standard name of a foreign key action
+func (i *inspector) action(action string) string {
+	if name, ok := i.dialect.actions[action]; ok {
+		return name
+	}
+	return action
+}
+
+var (
+	// https://www.sqlite.org/pragma.html
+	sqliteInspectorDialect = inspectorDialect{
+		tables: `
+			SELECT name FROM sqlite_master
+			WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+			ORDER BY name
+		`,
+		columns: `
+			SELECT name, type, "notnull" = 0 AND pk = 0 AS nullable, dflt_value AS default_value, cid + 1 AS position
+			FROM pragma_table_info(?)
+			ORDER BY cid
+		`,
+		primaryKey: `SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`,
+		indexes: `
+			SELECT il.name, il."unique" AS is_unique, ii.name AS column_name
+			FROM pragma_index_list(?) il, pragma_index_info(il.name) ii
+			WHERE il.origin <> 'pk'
+			ORDER BY il.name, ii.seqno
+		`,
+		foreignKeys: `
+			SELECT id, NULL AS name, "from" AS column_name, "table" AS referenced_table,
+				"to" AS referenced_column, on_update, on_delete
+			FROM pragma_foreign_key_list(?)
+			ORDER BY id, seq
+		`,
+		views: `SELECT name, sql AS definition FROM sqlite_master WHERE type = 'view' ORDER BY name`,
+	}
+
+	// https://www.postgresql.org/docs/current/catalogs.html
+	postgresInspectorDialect = inspectorDialect{
+		tables: `
+			SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+			ORDER BY table_name
+		`,
+		columns: `
+			SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, NOT a.attnotnull AS nullable,
+				pg_get_expr(d.adbin, d.adrelid) AS default_value, a.attnum AS position
+			FROM pg_attribute a
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE a.attrelid = to_regclass(quote_ident(current_schema()) || '.' || quote_ident(?))
+				AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum
+		`,
+		primaryKey: `
+			SELECT a.attname
+			FROM pg_index i
+			CROSS JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+			WHERE i.indrelid = to_regclass(quote_ident(current_schema()) || '.' || quote_ident(?)) AND i.indisprimary
+			ORDER BY k.ord
+		`,
+		indexes: `
+			SELECT ic.relname AS name, i.indisunique AS is_unique, a.attname AS column_name
+			FROM pg_index i
+			JOIN pg_class ic ON ic.oid = i.indexrelid
+			CROSS JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+			WHERE i.indrelid = to_regclass(quote_ident(current_schema()) || '.' || quote_ident(?)) AND NOT i.indisprimary
+			ORDER BY ic.relname, k.ord
+		`,
+		foreignKeys: `
+			SELECT c.conname AS id, c.conname AS name, a.attname AS column_name, rc.relname AS referenced_table,
+				ra.attname AS referenced_column, c.confupdtype AS on_update, c.confdeltype AS on_delete
+			FROM pg_constraint c
+			CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, refattnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+			JOIN pg_class rc ON rc.oid = c.confrelid
+			JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refattnum
+			WHERE c.contype = 'f' AND c.conrelid = to_regclass(quote_ident(current_schema()) || '.' || quote_ident(?))
+			ORDER BY c.conname, k.ord
+		`,
+		views: `
+			SELECT table_name AS name, view_definition AS definition
+			FROM information_schema.views
+			WHERE table_schema = current_schema()
+			ORDER BY table_name
+		`,
+		actions: map[string]string{
+			"a": "NO ACTION",
+			"r": "RESTRICT",
+			"c": "CASCADE",
+			"n": "SET NULL",
+			"d": "SET DEFAULT",
+		},
+	}
+
+	// https://dev.mysql.com/doc/refman/8.0/en/information-schema.html
+	mysqlInspectorDialect = inspectorDialect{
+		tables: `
+			SELECT table_name AS name FROM information_schema.tables
+			WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'
+			ORDER BY table_name
+		`,
+		columns: `
+			SELECT column_name AS name, column_type AS type, is_nullable = 'YES' AS nullable,
+				column_default AS default_value, ordinal_position AS position
+			FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = ?
+			ORDER BY ordinal_position
+		`,
+		primaryKey: `
+			SELECT column_name AS name FROM information_schema.key_column_usage
+			WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = 'PRIMARY'
+			ORDER BY ordinal_position
+		`,
+		indexes: `
+			SELECT index_name AS name, non_unique = 0 AS is_unique, column_name AS column_name
+			FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = ? AND index_name <> 'PRIMARY'
+			ORDER BY index_name, seq_in_index
+		`,
+		foreignKeys: `
+			SELECT k.constraint_name AS id, k.constraint_name AS name, k.column_name AS column_name,
+				k.referenced_table_name AS referenced_table, k.referenced_column_name AS referenced_column,
+				r.update_rule AS on_update, r.delete_rule AS on_delete
+			FROM information_schema.key_column_usage k
+			JOIN information_schema.referential_constraints r
+				ON r.constraint_schema = k.constraint_schema AND r.constraint_name = k.constraint_name
+			WHERE k.table_schema = DATABASE() AND k.table_name = ? AND k.referenced_table_name IS NOT NULL
+			ORDER BY k.constraint_name, k.ordinal_position
+		`,
+		views: `
+			SELECT table_name AS name, view_definition AS definition
+			FROM information_schema.views
+			WHERE table_schema = DATABASE()
+			ORDER BY table_name
+		`,
+	}
+)
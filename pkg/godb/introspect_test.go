@@ -0,0 +1,92 @@
+package godb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Inspector(t *testing.T) {
+	ddl := `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY,
+			email VARCHAR(255) NOT NULL,
+			name TEXT DEFAULT 'unknown'
+		);
+		CREATE UNIQUE INDEX users_email_idx ON users (email);
+		CREATE TABLE orders (
+			user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+			number INTEGER NOT NULL,
+			notes TEXT,
+			PRIMARY KEY (user_id, number)
+		);
+		CREATE INDEX orders_notes_idx ON orders (notes, number);
+		CREATE VIEW user_emails AS SELECT id, email FROM users;
+	`
+
+	db := newSQLiteTestDB(t, ddl)
+	inspector, err := NewInspector(db)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	unknown := "'unknown'"
+
+	names, err := inspector.TableNames(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orders", "users"}, names)
+
+	schema, err := inspector.Schema(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Schema{
+		Tables: []Table{
+			{
+				Name: "orders",
+				Columns: []Column{
+					{Name: "user_id", Type: "INTEGER", Position: 1},
+					{Name: "number", Type: "INTEGER", Position: 2},
+					{Name: "notes", Type: "TEXT", Nullable: true, Position: 3},
+				},
+				PrimaryKey: []string{"user_id", "number"},
+				Indexes:    []Index{{Name: "orders_notes_idx", Columns: []string{"notes", "number"}}},
+				ForeignKeys: []ForeignKey{{
+					Columns:           []string{"user_id"},
+					ReferencedTable:   "users",
+					ReferencedColumns: []string{"id"},
+					OnUpdate:          "NO ACTION",
+					OnDelete:          "CASCADE",
+				}},
+			},
+			{
+				Name: "users",
+				Columns: []Column{
+					{Name: "id", Type: "INTEGER", Position: 1},
+					{Name: "email", Type: "VARCHAR(255)", Position: 2},
+					{Name: "name", Type: "TEXT", Nullable: true, Default: &unknown, Position: 3},
+				},
+				PrimaryKey:  []string{"id"},
+				Indexes:     []Index{{Name: "users_email_idx", Columns: []string{"email"}, Unique: true}},
+				ForeignKeys: []ForeignKey{},
+			},
+		},
+		Views: []View{{
+			Name:       "user_emails",
+			Definition: "CREATE VIEW user_emails AS SELECT id, email FROM users",
+			Columns: []Column{
+				{Name: "id", Type: "INTEGER", Nullable: true, Position: 1},
+				{Name: "email", Type: "VARCHAR(255)", Nullable: true, Position: 2},
+			},
+		}},
+	}, schema)
+
+	data, err := json.Marshal(schema.Tables[1].Columns[2])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "name", "type": "TEXT", "nullable": true, "default": "'unknown'", "position": 3}`, string(data))
+
+	_, err = inspector.Table(ctx, "unknown_table")
+	assert.ErrorIs(t, err, ErrTableNotFound)
+
+	_, err = NewInspector(&DBMock{CallbackDriverName: func() string { return "oracle" }})
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
@@ -3,8 +3,11 @@ package godb
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"reflect"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 // Defines the named statement
@@ -61,6 +64,7 @@ type NamedStmt interface {
 type customNamedStmt struct {
 	testErr   error
 	namedStmt *sqlx.NamedStmt
+	hooks     queryHooks
 }
 
 // pushTestError
@@ -75,6 +79,45 @@ func (c *customNamedStmt) popTestError() error {
 	return err
 }
 
+// queryString returns the named query reported to the hooks
+func (c *customNamedStmt) queryString() string {
+	if c.namedStmt == nil {
+		return ""
+	}
+	return c.namedStmt.QueryString
+}
+
+// bindArgs binds arg to the parameters of the named statement and encodes them for its database
+func (c *customNamedStmt) bindArgs(arg interface{}) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(c.namedStmt.Params))
+	if values, ok := arg.(map[string]interface{}); ok {
+		for _, name := range c.namedStmt.Params {
+			value, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("could not find name %s in %#v", name, arg)
+			}
+			args = append(args, value)
+		}
+		return c.hooks.encodeArgs(args)
+	}
+
+	value := reflect.ValueOf(arg)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported named argument type %T", arg)
+	}
+
+	for index, traversal := range c.namedStmt.Stmt.Mapper.TraversalsByName(value.Type(), c.namedStmt.Params) {
+		if len(traversal) == 0 {
+			return nil, fmt.Errorf("could not find name %s in %#v", c.namedStmt.Params[index], arg)
+		}
+		args = append(args, reflectx.FieldByIndexesReadOnly(value, traversal).Interface())
+	}
+	return c.hooks.encodeArgs(args)
+}
+
 // Close
 func (c *customNamedStmt) Close() error {
 	return c.namedStmt.Close()
@@ -82,68 +125,86 @@ func (c *customNamedStmt) Close() error {
 
 // Exec
 func (c *customNamedStmt) Exec(arg interface{}) (sql.Result, error) {
-	return c.namedStmt.Exec(arg)
+	return c.ExecContext(context.Background(), arg)
 }
 
 // ExecContext
 func (c *customNamedStmt) ExecContext(ctx context.Context, arg interface{}) (sql.Result, error) {
-	return c.namedStmt.ExecContext(ctx, arg)
+	return c.hooks.exec(ctx, OperationNamedExec, c.queryString(), []interface{}{arg},
+		func(ctx context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			args, err := c.bindArgs(namedArg(args))
+			if err != nil {
+				return nil, err
+			}
+			return c.namedStmt.Stmt.ExecContext(ctx, args...)
+		},
+	)
 }
 
 // Get
 func (c *customNamedStmt) Get(dest interface{}, arg interface{}) error {
-	return c.namedStmt.Get(dest, arg)
+	return c.GetContext(context.Background(), dest, arg)
 }
 
 // GetContext
 func (c *customNamedStmt) GetContext(ctx context.Context, dest interface{}, arg interface{}) error {
-	return c.namedStmt.GetContext(ctx, dest, arg)
+	return c.hooks.scan(ctx, OperationGet, dest, c.queryString(), []interface{}{arg},
+		func(ctx context.Context, dest interface{}, _ string, args ...interface{}) error {
+			args, err := c.bindArgs(namedArg(args))
+			if err != nil {
+				return err
+			}
+			return c.namedStmt.Stmt.GetContext(ctx, dest, args...)
+		},
+	)
 }
 
 // MustExec
 func (c *customNamedStmt) MustExec(arg interface{}) sql.Result {
-	return c.namedStmt.MustExec(arg)
+	return mustExec(c.ExecContext(context.Background(), arg))
 }
 
 // MustExecContext
 func (c *customNamedStmt) MustExecContext(ctx context.Context, arg interface{}) sql.Result {
-	return c.namedStmt.MustExecContext(ctx, arg)
+	return mustExec(c.ExecContext(ctx, arg))
 }
 
 // QueryRow
 func (c *customNamedStmt) QueryRow(arg interface{}) Row {
-	return &customRow{row: c.namedStmt.QueryRowx(arg)}
+	return c.QueryRowContext(context.Background(), arg)
 }
 
 // QueryRowContext
 func (c *customNamedStmt) QueryRowContext(ctx context.Context, arg interface{}) Row {
-	return &customRow{row: c.namedStmt.QueryRowxContext(ctx, arg)}
+	result := &customRow{}
+	err := c.hooks.run(ctx, OperationQueryRow, c.queryString(), []interface{}{arg}, func(ctx context.Context, event *QueryEvent) error {
+		args, err := c.bindArgs(namedArg(event.Args))
+		if err != nil {
+			return err
+		}
+
+		result.ctx, result.cancel = ctx, event.cancel
+		result.row = c.namedStmt.Stmt.QueryRowxContext(ctx, args...)
+		return result.row.Err()
+	})
+	if result.row == nil {
+		return &errorRow{err: err}
+	}
+	return result
 }
 
 // Query
 func (c *customNamedStmt) Query(arg interface{}) (Rows, error) {
-	if rows, err := c.query(arg); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
-}
-
-// query
-func (c *customNamedStmt) query(arg interface{}) (*sqlx.Rows, error) {
-	if c.testErr != nil {
-		return &sqlx.Rows{}, c.popTestError()
-	}
-	return c.namedStmt.Queryx(arg)
+	return c.QueryContext(context.Background(), arg)
 }
 
 // QueryContext
 func (c *customNamedStmt) QueryContext(ctx context.Context, arg interface{}) (Rows, error) {
-	if rows, err := c.queryContext(ctx, arg); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	return c.hooks.rows(ctx, OperationNamedQuery, c.queryString(), []interface{}{arg},
+		func(ctx context.Context, _ string, args ...interface{}) (*sqlx.Rows, error) {
+			return c.queryContext(ctx, namedArg(args))
+		},
+	)
 }
 
 // queryContext
@@ -151,17 +212,30 @@ func (c *customNamedStmt) queryContext(ctx context.Context, arg interface{}) (*s
 	if c.testErr != nil {
 		return &sqlx.Rows{}, c.popTestError()
 	}
-	return c.namedStmt.QueryxContext(ctx, arg)
+
+	args, err := c.bindArgs(arg)
+	if err != nil {
+		return nil, err
+	}
+	return c.namedStmt.Stmt.QueryxContext(ctx, args...)
 }
 
 // Select
 func (c *customNamedStmt) Select(dest interface{}, arg interface{}) error {
-	return c.namedStmt.Select(dest, arg)
+	return c.SelectContext(context.Background(), dest, arg)
 }
 
 // SelectContext
 func (c *customNamedStmt) SelectContext(ctx context.Context, dest interface{}, arg interface{}) error {
-	return c.namedStmt.SelectContext(ctx, dest, arg)
+	return c.hooks.scan(ctx, OperationSelect, dest, c.queryString(), []interface{}{arg},
+		func(ctx context.Context, dest interface{}, _ string, args ...interface{}) error {
+			args, err := c.bindArgs(namedArg(args))
+			if err != nil {
+				return err
+			}
+			return c.namedStmt.Stmt.SelectContext(ctx, dest, args...)
+		},
+	)
 }
 
 // Unsafe
@@ -0,0 +1,237 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+const (
+	defaultVersionColumn = "version"
+	defaultKeyColumn     = "id"
+	// nextVersionParam is the named parameter holding the next version timestamp
+	nextVersionParam = "godb_next_version"
+)
+
+// NamedExecer defines the operations used by UpdateVersioned.
+// It is implemented by DB and Tx.
+type NamedExecer interface {
+	DriverName() string
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// VersionConfig defines the optimistic locking configs of a table
+type VersionConfig struct {
+	// Table is the updated table.
+	Table string
+	// KeyColumns identify the updated row. Defaults to "id".
+	KeyColumns []string
+	// Columns are the updated columns. Defaults to every db field of the struct except the
+	// key and version columns. Embedded structs are flattened, unless they are nil pointers,
+	// and nested structs skipped.
+	Columns []string
+	// VersionColumn is checked and changed by every update. Defaults to "version".
+	VersionColumn string
+	// Timestamp sets the version column to the current time instead of incrementing it,
+	// for updated_at columns. The column should store at least microseconds.
+	Timestamp bool
+}
+
+// UpdateVersioned Updates a row only if its version column still holds the version of arg,
+// and changes the version in the same statement. It returns ErrStaleUpdate if the row was
+// changed or deleted since arg was read.
+//
+// Integer versions are incremented and time.Time versions are set to the current UTC time,
+// truncated to microseconds; other version types are rejected. When arg is a pointer its
+// version field is updated as well, so it can be updated again. The fields are mapped with
+// the mapper of db.
+func UpdateVersioned(ctx context.Context, db NamedExecer, config VersionConfig, arg interface{}) error {
+	if config.Table == "" {
+		return fmt.Errorf("%w. table is required", ErrInvalidVersionConfig)
+	}
+
+	if len(config.KeyColumns) == 0 {
+		config.KeyColumns = []string{defaultKeyColumn}
+	}
+
+	if config.VersionColumn == "" {
+		config.VersionColumn = defaultVersionColumn
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(arg))
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("%w. expected a struct, got %T", ErrInvalidVersionConfig, arg)
+	}
+
+	fields := namedExecerMapper(db).TypeMap(value.Type())
+	version := fields.GetByPath(config.VersionColumn)
+	if version == nil {
+		return fmt.Errorf("%w. %T has no %s field", ErrInvalidVersionConfig, arg, config.VersionColumn)
+	}
+
+	if !isVersionType(version.Field.Type, config.Timestamp) {
+		return fmt.Errorf("%w. unsupported %s version field type %s", ErrInvalidVersionConfig, config.VersionColumn, version.Field.Type)
+	}
+
+	params := map[string]interface{}{}
+	for _, field := range fields.Index {
+		if fieldValue, ok := readField(value, field.Index); ok {
+			params[field.Path] = fieldValue.Interface()
+		}
+	}
+
+	for _, column := range config.KeyColumns {
+		if _, ok := params[column]; !ok {
+			return fmt.Errorf("%w. %T has no %s field", ErrInvalidVersionConfig, arg, column)
+		}
+	}
+
+	if len(config.Columns) == 0 {
+		config.Columns = updatableColumns(fields, config, params)
+	}
+
+	var nextVersion time.Time
+	if config.Timestamp {
+		nextVersion = time.Now().UTC().Truncate(time.Microsecond)
+		params[nextVersionParam] = nextVersion
+	}
+
+	result, err := db.NamedExecContext(ctx, versionedUpdateQuery(db.DriverName(), config), params)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrStaleUpdate
+	}
+
+	if target := reflect.ValueOf(arg); target.Kind() == reflect.Pointer {
+		setVersion(reflectx.FieldByIndexes(target.Elem(), version.Index), nextVersion)
+	}
+	return nil
+}
+
+// namedExecerMapper returns the mapper of db, or the default one if db has none
+func namedExecerMapper(db NamedExecer) *reflectx.Mapper {
+	var mapper *reflectx.Mapper
+	switch db := db.(type) {
+	case interface{ Safe() *sqlx.DB }:
+		if safe := db.Safe(); safe != nil {
+			mapper = safe.Mapper
+		}
+	case interface{ Safe() *sqlx.Tx }:
+		if safe := db.Safe(); safe != nil {
+			mapper = safe.Mapper
+		}
+	}
+
+	if mapper == nil {
+		mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+	}
+	return mapper
+}
+
+// isVersionType returns if a version field of fieldType can be updated. Timestamp versions
+// must be a time.Time and the others an integer.
+func isVersionType(fieldType reflect.Type, timestamp bool) bool {
+	if timestamp {
+		return fieldType == reflect.TypeOf(time.Time{})
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// updatableColumns returns the default updated columns. The fields of nil embedded
+// pointers are not updated.
+func updatableColumns(fields *reflectx.StructMap, config VersionConfig, params map[string]interface{}) []string {
+	skipped := map[string]bool{config.VersionColumn: true}
+	for _, column := range config.KeyColumns {
+		skipped[column] = true
+	}
+
+	columns := []string{}
+	for _, field := range fields.Index {
+		if field.Embedded || field.Name == "" || strings.Contains(field.Path, ".") || skipped[field.Path] {
+			continue
+		}
+
+		if _, ok := params[field.Path]; !ok {
+			continue
+		}
+		columns = append(columns, field.Path)
+	}
+	return columns
+}
+
+// versionedUpdateQuery returns the UPDATE statement of an optimistic update
+func versionedUpdateQuery(driverName string, config VersionConfig) string {
+	dbType, _ := driverDBType(driverName)
+
+	version := quoteIdentifier(dbType, config.VersionColumn)
+	assignments := make([]string, 0, len(config.Columns)+1)
+	for _, column := range config.Columns {
+		assignments = append(assignments, fmt.Sprintf("%s = :%s", quoteIdentifier(dbType, column), column))
+	}
+
+	if config.Timestamp {
+		assignments = append(assignments, fmt.Sprintf("%s = :%s", version, nextVersionParam))
+	} else {
+		assignments = append(assignments, fmt.Sprintf("%s = %s + 1", version, version))
+	}
+
+	conditions := make([]string, 0, len(config.KeyColumns)+1)
+	for _, column := range config.KeyColumns {
+		conditions = append(conditions, fmt.Sprintf("%s = :%s", quoteIdentifier(dbType, column), column))
+	}
+	conditions = append(conditions, fmt.Sprintf("%s = :%s", version, config.VersionColumn))
+
+	return fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s",
+		quoteQualified(dbType, config.Table),
+		strings.Join(assignments, ", "),
+		strings.Join(conditions, " AND "),
+	)
+}
+
+// readField returns the field at index, or false if it is inside a nil embedded pointer
+func readField(value reflect.Value, index []int) (reflect.Value, bool) {
+	for _, position := range index {
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(position)
+	}
+	return value, true
+}
+
+// setVersion sets the version field after an update. See isVersionType.
+func setVersion(field reflect.Value, nextVersion time.Time) {
+	switch {
+	case field.Type() == reflect.TypeOf(nextVersion):
+		field.Set(reflect.ValueOf(nextVersion))
+	case field.CanInt():
+		field.SetInt(field.Int() + 1)
+	default:
+		field.SetUint(field.Uint() + 1)
+	}
+}
@@ -0,0 +1,166 @@
+package godb
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UpdateVersioned(t *testing.T) {
+	type Audit struct {
+		UpdatedBy string `db:"updated_by"`
+	}
+
+	type account struct {
+		ID      int    `db:"id"`
+		Name    string `db:"name"`
+		Balance int    `db:"balance"`
+		Version int    `db:"version"`
+		*Audit
+	}
+
+	type document struct {
+		Tenant    string    `db:"tenant"`
+		Code      string    `db:"code"`
+		Body      string    `db:"body"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+
+	ddl := `
+		CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT, balance INTEGER, version INTEGER NOT NULL, updated_by TEXT);
+		INSERT INTO accounts VALUES (1, 'John Doe', 100, 1, NULL);
+		CREATE TABLE documents (tenant TEXT, code TEXT, body TEXT, updated_at DATETIME, PRIMARY KEY (tenant, code));
+	`
+
+	ctx := context.Background()
+	accounts := VersionConfig{Table: "accounts"}
+
+	t.Run("should increment the version", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+
+		var first, second account
+		assert.NoError(t, db.Get(&first, "SELECT id, name, balance, version FROM accounts WHERE id = 1"))
+		assert.NoError(t, db.Get(&second, "SELECT id, name, balance, version FROM accounts WHERE id = 1"))
+
+		first.Balance = 150
+		first.Audit = &Audit{UpdatedBy: "jane"}
+		assert.NoError(t, UpdateVersioned(ctx, db, accounts, &first))
+		assert.Equal(t, 2, first.Version, "the version should be updated in memory")
+
+		second.Balance = 50
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, accounts, second), ErrStaleUpdate)
+
+		first.Balance = 200
+		assert.NoError(t, UpdateVersioned(ctx, db, VersionConfig{Table: "main.accounts", Columns: []string{"balance"}}, &first))
+
+		var stored account
+		assert.NoError(t, db.Get(&stored, "SELECT * FROM accounts WHERE id = 1"))
+		assert.Equal(t, account{ID: 1, Name: "John Doe", Balance: 200, Version: 3, Audit: &Audit{UpdatedBy: "jane"}}, stored)
+	})
+
+	t.Run("should update within a transaction", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		data := account{ID: 1, Name: "Jim Doe", Version: 1}
+		assert.NoError(t, UpdateVersioned(ctx, tx, accounts, &data))
+		assert.ErrorIs(t, UpdateVersioned(ctx, tx, accounts, account{ID: 1, Version: 1}), ErrStaleUpdate)
+		assert.ErrorIs(t, UpdateVersioned(ctx, tx, accounts, account{ID: 2, Version: 1}), ErrStaleUpdate, "missing rows should be stale")
+		assert.NoError(t, tx.Commit())
+
+		var version int
+		assert.NoError(t, db.Get(&version, "SELECT version FROM accounts WHERE id = 1"))
+		assert.Equal(t, 2, version)
+	})
+
+	t.Run("should use timestamp versions", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+		documents := VersionConfig{Table: "documents", KeyColumns: []string{"tenant", "code"}, VersionColumn: "updated_at", Timestamp: true}
+
+		created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		_, err := db.NamedExec("INSERT INTO documents VALUES (:tenant, :code, :body, :updated_at)", document{
+			Tenant: "acme", Code: "terms", Body: "v1", UpdatedAt: created,
+		})
+		assert.NoError(t, err)
+
+		data := document{Tenant: "acme", Code: "terms", Body: "v2", UpdatedAt: created}
+		assert.NoError(t, UpdateVersioned(ctx, db, documents, &data))
+		assert.True(t, data.UpdatedAt.After(created))
+
+		stale := document{Tenant: "acme", Code: "terms", Body: "v3", UpdatedAt: created}
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, documents, stale), ErrStaleUpdate)
+
+		data.Body = "v4"
+		assert.NoError(t, UpdateVersioned(ctx, db, documents, &data))
+
+		var stored document
+		assert.NoError(t, db.Get(&stored, "SELECT * FROM documents"))
+		assert.Equal(t, "v4", stored.Body)
+		assert.True(t, data.UpdatedAt.Equal(stored.UpdatedAt))
+	})
+
+	t.Run("should use the mapper of the database", func(t *testing.T) {
+		db := newSQLiteTestDB(t, `
+			CREATE TABLE ledgers (id INTEGER PRIMARY KEY, owner_name TEXT, version INTEGER NOT NULL);
+			INSERT INTO ledgers VALUES (1, 'John Doe', 1);
+		`)
+		words := regexp.MustCompile("([a-z0-9])([A-Z])")
+		db.MapperFunc(func(name string) string {
+			return strings.ToLower(words.ReplaceAllString(name, "${1}_${2}"))
+		})
+
+		type ledger struct {
+			ID        int
+			OwnerName string
+			Version   int
+		}
+
+		data := ledger{ID: 1, OwnerName: "Jane Doe", Version: 1}
+		assert.NoError(t, UpdateVersioned(ctx, db, VersionConfig{Table: "ledgers"}, &data))
+		assert.Equal(t, 2, data.Version)
+
+		var stored ledger
+		assert.NoError(t, db.Get(&stored, "SELECT * FROM ledgers WHERE id = 1"))
+		assert.Equal(t, data, stored)
+	})
+
+	t.Run("should validate the config", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, VersionConfig{}, account{}), ErrInvalidVersionConfig)
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, accounts, []account{}), ErrInvalidVersionConfig)
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, VersionConfig{Table: "accounts", VersionColumn: "revision"}, account{}), ErrInvalidVersionConfig)
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, VersionConfig{Table: "accounts", KeyColumns: []string{"code"}}, account{}), ErrInvalidVersionConfig)
+
+		type nullable struct {
+			ID      int         `db:"id"`
+			Version Null[int64] `db:"version"`
+		}
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, accounts, &nullable{ID: 1}), ErrInvalidVersionConfig, "unsupported versions should be rejected")
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, VersionConfig{Table: "accounts", Timestamp: true}, &account{ID: 1}), ErrInvalidVersionConfig)
+		assert.ErrorIs(t, UpdateVersioned(ctx, db, VersionConfig{Table: "documents", KeyColumns: []string{"tenant", "code"}, VersionColumn: "updated_at"}, document{}), ErrInvalidVersionConfig)
+
+		var version int
+		assert.NoError(t, db.Get(&version, "SELECT version FROM accounts WHERE id = 1"))
+		assert.Equal(t, 1, version, "rejected updates should not be executed")
+	})
+}
+
+func Test_versionedUpdateQuery(t *testing.T) {
+	config := VersionConfig{Table: "app.accounts", KeyColumns: []string{"id"}, Columns: []string{"name"}, VersionColumn: "version"}
+	assert.Equal(t,
+		"UPDATE `app`.`accounts` SET `name` = :name, `version` = `version` + 1 WHERE `id` = :id AND `version` = :version",
+		versionedUpdateQuery("mysql", config),
+	)
+
+	config.Timestamp = true
+	assert.Equal(t,
+		`UPDATE "app"."accounts" SET "name" = :name, "version" = :godb_next_version WHERE "id" = :id AND "version" = :version`,
+		versionedUpdateQuery("postgres", config),
+	)
+}
@@ -0,0 +1,502 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// Tag options read by Repository from the godb struct tag
+const (
+	// TagTable sets the table name, as in `godb:"table:users"`. It is usually set on a blank field.
+	TagTable = "table"
+	// TagPrimaryKey marks a primary key column. Defaults to the id column.
+	TagPrimaryKey = "pk"
+	// TagAuto marks a column generated by the database, such as a serial primary key.
+	// It is never inserted or updated, and auto primary keys are read back after inserts.
+	TagAuto = "auto"
+	// TagReadOnly marks a column that is read but never inserted or updated.
+	TagReadOnly = "readonly"
+	// TagSoftDelete marks the nullable timestamp set by Delete instead of deleting the row.
+	TagSoftDelete = "soft_delete"
+)
+
+// Filter operators
+const (
+	OpEq        = "="
+	OpNotEq     = "<>"
+	OpLt        = "<"
+	OpLte       = "<="
+	OpGt        = ">"
+	OpGte       = ">="
+	OpLike      = "LIKE"
+	OpIn        = "IN"
+	OpIsNull    = "IS NULL"
+	OpIsNotNull = "IS NOT NULL"
+)
+
+// Queryer defines the operations used by Repository.
+// It is implemented by DB and Tx.
+type Queryer interface {
+	DriverName() string
+	Rebind(query string) string
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// Filter defines a condition of List. Value must be a slice for OpIn and is ignored
+// by OpIsNull and OpIsNotNull.
+type Filter struct {
+	Column   string
+	Operator string
+	Value    interface{}
+}
+
+// Sort defines an order of List
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// ListOptions defines the List configs
+type ListOptions struct {
+	// Filters are combined with AND.
+	Filters []Filter
+	Sort    []Sort
+	// Limit is the maximum amount of rows. Zero means no limit.
+	Limit  int
+	Offset int
+	// WithDeleted includes the soft deleted rows.
+	WithDeleted bool
+}
+
+// repositoryColumn defines a column of a Repository
+type repositoryColumn struct {
+	name       string
+	index      []int
+	primaryKey bool
+	auto       bool
+	readOnly   bool
+	softDelete bool
+}
+
+// Repository implements the common CRUD operations of a table mapped to the struct T.
+//
+// Columns are read from the db tag and the table and column options from the godb tag:
+//
+//	type User struct {
+//		_         struct{}        `godb:"table:users"`
+//		ID        int64           `db:"id" godb:"pk,auto"`
+//		Email     string          `db:"email"`
+//		CreatedAt time.Time       `db:"created_at" godb:"readonly"`
+//		DeletedAt Null[time.Time] `db:"deleted_at" godb:"soft_delete"`
+//	}
+//
+// Embedded structs are flattened and nested structs are not mapped.
+type Repository[T any] struct {
+	db         Queryer
+	dbType     DBType
+	table      string
+	columns    []repositoryColumn
+	byName     map[string]repositoryColumn
+	softDelete *repositoryColumn
+}
+
+// NewRepository Returns the Repository of T using db. The SQL dialect is read from
+// the driver of db.
+func NewRepository[T any](db Queryer) (*Repository[T], error) {
+	dbType, ok := driverDBType(db.DriverName())
+	if !ok {
+		return nil, ErrInvalidDBType
+	}
+
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	if entityType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w. %s is not a struct", ErrInvalidRepository, entityType)
+	}
+
+	repository := &Repository[T]{db: db, dbType: dbType, byName: map[string]repositoryColumn{}}
+	for index := 0; index < entityType.NumField(); index++ {
+		for _, option := range tagOptions(entityType.Field(index).Tag) {
+			if table, ok := strings.CutPrefix(option, TagTable+":"); ok {
+				repository.table = table
+			}
+		}
+	}
+
+	if repository.table == "" {
+		return nil, fmt.Errorf("%w. %s has no table tag", ErrInvalidRepository, entityType)
+	}
+
+	hasPrimaryKey := false
+	fields := reflectx.NewMapperFunc("db", sqlx.NameMapper).TypeMap(entityType)
+	for _, field := range fields.Index {
+		if field.Embedded || field.Name == "" || strings.Contains(field.Path, ".") {
+			continue
+		}
+
+		column := repositoryColumn{name: field.Path, index: field.Index}
+		for _, option := range tagOptions(field.Field.Tag) {
+			switch option {
+			case TagPrimaryKey:
+				column.primaryKey = true
+			case TagAuto:
+				column.auto = true
+			case TagReadOnly:
+				column.readOnly = true
+			case TagSoftDelete:
+				column.softDelete = true
+			}
+		}
+
+		hasPrimaryKey = hasPrimaryKey || column.primaryKey
+		repository.columns = append(repository.columns, column)
+	}
+
+	for index := range repository.columns {
+		column := &repository.columns[index]
+		if !hasPrimaryKey && column.name == defaultKeyColumn {
+			column.primaryKey = true
+			hasPrimaryKey = true
+		}
+
+		if column.softDelete {
+			repository.softDelete = column
+		}
+		repository.byName[column.name] = *column
+	}
+
+	if !hasPrimaryKey {
+		return nil, fmt.Errorf("%w. %s has no primary key", ErrInvalidRepository, entityType)
+	}
+	return repository, nil
+}
+
+// tagOptions returns the options of a godb tag
+func tagOptions(tag reflect.StructTag) []string {
+	value, ok := tag.Lookup("godb")
+	if !ok || value == "" {
+		return nil
+	}
+
+	options := strings.Split(value, ",")
+	for index := range options {
+		options[index] = strings.TrimSpace(options[index])
+	}
+	return options
+}
+
+// With Returns a copy of the repository using db, such as a transaction
+func (r *Repository[T]) With(db Queryer) *Repository[T] {
+	copied := *r
+	copied.db = db
+	return &copied
+}
+
+// Table Returns the table name
+func (r *Repository[T]) Table() string {
+	return r.table
+}
+
+// FindByID Returns the row with the given primary key values, in the order of the
+// primary key fields. It returns sql.ErrNoRows if there is no such row or it is soft deleted.
+func (r *Repository[T]) FindByID(ctx context.Context, id ...interface{}) (T, error) {
+	var entity T
+
+	conditions, args, err := r.primaryKeyConditions(id)
+	if err != nil {
+		return entity, err
+	}
+
+	if r.softDelete != nil {
+		conditions = append(conditions, r.quote(r.softDelete.name)+" IS NULL")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", r.selectColumns(), r.quoteTable(), strings.Join(conditions, " AND "))
+	err = r.db.GetContext(ctx, &entity, r.db.Rebind(query), args...)
+	return entity, err
+}
+
+// List Returns the rows matching the options. Soft deleted rows are skipped unless
+// ListOptions.WithDeleted is set.
+func (r *Repository[T]) List(ctx context.Context, options ListOptions) ([]T, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if r.softDelete != nil && !options.WithDeleted {
+		conditions = append(conditions, r.quote(r.softDelete.name)+" IS NULL")
+	}
+
+	for _, filter := range options.Filters {
+		condition, filterArgs, err := r.filterCondition(filter)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "SELECT %s FROM %s", r.selectColumns(), r.quoteTable())
+	if len(conditions) > 0 {
+		builder.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+
+	if len(options.Sort) > 0 {
+		orders := make([]string, len(options.Sort))
+		for index, sort := range options.Sort {
+			if _, ok := r.byName[sort.Column]; !ok {
+				return nil, fmt.Errorf("%s. %w", sort.Column, ErrUnknownColumn)
+			}
+
+			orders[index] = r.quote(sort.Column)
+			if sort.Desc {
+				orders[index] += " DESC"
+			}
+		}
+		builder.WriteString(" ORDER BY " + strings.Join(orders, ", "))
+	}
+
+	switch {
+	case options.Limit > 0:
+		fmt.Fprintf(&builder, " LIMIT %d", options.Limit)
+	case options.Offset > 0 && r.dbType == MySQLDB:
+		// MySQL does not support OFFSET without LIMIT
+		builder.WriteString(" LIMIT 18446744073709551615")
+	case options.Offset > 0 && r.dbType == SQLiteDB:
+		builder.WriteString(" LIMIT -1")
+	}
+
+	if options.Offset > 0 {
+		fmt.Fprintf(&builder, " OFFSET %d", options.Offset)
+	}
+
+	entities := []T{}
+	err := r.db.SelectContext(ctx, &entities, r.db.Rebind(builder.String()), args...)
+	return entities, err
+}
+
+// Insert Inserts a row. Auto and read-only columns are not inserted, and an auto primary
+// key is read back into entity.
+func (r *Repository[T]) Insert(ctx context.Context, entity *T) error {
+	value := reflect.ValueOf(entity).Elem()
+
+	columns := []string{}
+	args := []interface{}{}
+	var generated *repositoryColumn
+	for index, column := range r.columns {
+		if column.auto && column.primaryKey {
+			generated = &r.columns[index]
+		}
+
+		if column.auto || column.readOnly {
+			continue
+		}
+
+		columns = append(columns, r.quote(column.name))
+		args = append(args, fieldArg(value, column.index))
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		r.quoteTable(),
+		strings.Join(columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+	)
+
+	if len(columns) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", r.quoteTable())
+		if r.dbType == MySQLDB {
+			query = fmt.Sprintf("INSERT INTO %s () VALUES ()", r.quoteTable())
+		}
+	}
+
+	if generated == nil {
+		_, err := r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+		return err
+	}
+
+	field := reflectx.FieldByIndexes(value, generated.index)
+	if r.dbType == PostgresDB {
+		query += " RETURNING " + r.quote(generated.name)
+		return r.db.GetContext(ctx, field.Addr().Interface(), r.db.Rebind(query), args...)
+	}
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case field.CanInt():
+		field.SetInt(id)
+	case field.CanUint():
+		field.SetUint(uint64(id))
+	}
+	return nil
+}
+
+// Update Updates every column of a row except the primary key, auto, read-only and soft
+// delete columns. It returns sql.ErrNoRows if no row was affected. Since MySQL does not
+// count rows whose values did not change, updates without changes also return it there.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	value := reflect.ValueOf(entity).Elem()
+
+	assignments := []string{}
+	args := []interface{}{}
+	id := []interface{}{}
+	for _, column := range r.columns {
+		if column.primaryKey {
+			id = append(id, fieldArg(value, column.index))
+			continue
+		}
+
+		if column.auto || column.readOnly || column.softDelete {
+			continue
+		}
+
+		assignments = append(assignments, r.quote(column.name)+" = ?")
+		args = append(args, fieldArg(value, column.index))
+	}
+
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	conditions, idArgs, err := r.primaryKeyConditions(id)
+	if err != nil {
+		return err
+	}
+
+	if r.softDelete != nil {
+		conditions = append(conditions, r.quote(r.softDelete.name)+" IS NULL")
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", r.quoteTable(), strings.Join(assignments, ", "), strings.Join(conditions, " AND "))
+	return r.exec(ctx, query, append(args, idArgs...)...)
+}
+
+// Delete Deletes the row with the given primary key values, or sets its soft delete column
+// to the current time. It returns sql.ErrNoRows if there is no such row.
+func (r *Repository[T]) Delete(ctx context.Context, id ...interface{}) error {
+	conditions, args, err := r.primaryKeyConditions(id)
+	if err != nil {
+		return err
+	}
+
+	if r.softDelete == nil {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s", r.quoteTable(), strings.Join(conditions, " AND "))
+		return r.exec(ctx, query, args...)
+	}
+
+	conditions = append(conditions, r.quote(r.softDelete.name)+" IS NULL")
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s", r.quoteTable(), r.quote(r.softDelete.name), strings.Join(conditions, " AND "))
+	return r.exec(ctx, query, append([]interface{}{time.Now().UTC()}, args...)...)
+}
+
+// exec executes a statement that must affect a row
+func (r *Repository[T]) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// primaryKeyConditions returns the conditions matching the primary key values
+func (r *Repository[T]) primaryKeyConditions(id []interface{}) ([]string, []interface{}, error) {
+	conditions := []string{}
+	for _, column := range r.columns {
+		if column.primaryKey {
+			conditions = append(conditions, r.quote(column.name)+" = ?")
+		}
+	}
+
+	if len(id) != len(conditions) {
+		return nil, nil, fmt.Errorf("%w. expected %d primary key values, got %d", ErrInvalidRepository, len(conditions), len(id))
+	}
+	return conditions, id, nil
+}
+
+// filterCondition returns the condition of a filter
+func (r *Repository[T]) filterCondition(filter Filter) (string, []interface{}, error) {
+	if _, ok := r.byName[filter.Column]; !ok {
+		return "", nil, fmt.Errorf("%s. %w", filter.Column, ErrUnknownColumn)
+	}
+
+	column := r.quote(filter.Column)
+	switch filter.Operator {
+	case OpEq, OpNotEq, OpLt, OpLte, OpGt, OpGte, OpLike:
+		return fmt.Sprintf("%s %s ?", column, filter.Operator), []interface{}{filter.Value}, nil
+	case OpIsNull, OpIsNotNull:
+		return fmt.Sprintf("%s %s", column, filter.Operator), nil, nil
+	case OpIn:
+		values := reflect.ValueOf(filter.Value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return "", nil, fmt.Errorf("%w. %s IN requires a slice", ErrInvalidFilter, filter.Column)
+		}
+
+		// An empty IN list is invalid SQL and matches nothing
+		if values.Len() == 0 {
+			return "1 = 0", nil, nil
+		}
+
+		args := make([]interface{}, values.Len())
+		for index := range args {
+			args[index] = values.Index(index).Interface()
+		}
+		return fmt.Sprintf("%s IN (%s)", column, strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")), args, nil
+	default:
+		return "", nil, fmt.Errorf("%w. unknown operator %q", ErrInvalidFilter, filter.Operator)
+	}
+}
+
+// selectColumns returns the selected columns
+func (r *Repository[T]) selectColumns() string {
+	columns := make([]string, len(r.columns))
+	for index, column := range r.columns {
+		columns[index] = r.quote(column.name)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// quote quotes a column name
+func (r *Repository[T]) quote(name string) string {
+	return quoteIdentifier(r.dbType, name)
+}
+
+// quoteTable quotes the table name, which can be qualified with its schema
+func (r *Repository[T]) quoteTable() string {
+	return quoteQualified(r.dbType, r.table)
+}
+
+// fieldArg returns the value of a field as a query argument. Fields of nil embedded
+// pointers are NULL.
+func fieldArg(value reflect.Value, index []int) interface{} {
+	field, ok := readField(value, index)
+	if !ok {
+		return nil
+	}
+	return field.Interface()
+}
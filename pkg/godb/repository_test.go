@@ -0,0 +1,196 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type repositoryUser struct {
+	_         struct{}        `godb:"table:users"`
+	ID        int64           `db:"id" godb:"pk,auto"`
+	Email     string          `db:"email"`
+	Age       int             `db:"age"`
+	CreatedAt string          `db:"created_at" godb:"readonly"`
+	DeletedAt Null[time.Time] `db:"deleted_at" godb:"soft_delete"`
+}
+
+type repositoryMembership struct {
+	_      struct{} `godb:"table:memberships"`
+	Team   string   `db:"team" godb:"pk"`
+	Member string   `db:"member" godb:"pk"`
+	Role   string   `db:"role"`
+}
+
+func Test_Repository(t *testing.T) {
+	ddl := `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			email TEXT NOT NULL,
+			age INTEGER NOT NULL,
+			created_at TEXT NOT NULL DEFAULT 'now',
+			deleted_at DATETIME
+		);
+		CREATE TABLE memberships (team TEXT, member TEXT, role TEXT, PRIMARY KEY (team, member));
+	`
+
+	ctx := context.Background()
+
+	t.Run("should insert, find, update and soft delete", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+		users, err := NewRepository[repositoryUser](db)
+		assert.NoError(t, err)
+		assert.Equal(t, "users", users.Table())
+
+		for _, email := range []string{"john@doe.com", "jane@doe.com", "jim@doe.com"} {
+			user := repositoryUser{Email: email, Age: len(email), CreatedAt: "ignored"}
+			assert.NoError(t, users.Insert(ctx, &user))
+			assert.NotZero(t, user.ID, "the generated key should be read back")
+		}
+
+		user, err := users.FindByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "john@doe.com", user.Email)
+		assert.Equal(t, "now", user.CreatedAt, "read-only columns should not be inserted")
+
+		user.Age = 40
+		user.CreatedAt = "changed"
+		assert.NoError(t, users.Update(ctx, &user))
+		user, err = users.FindByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 40, user.Age)
+		assert.Equal(t, "now", user.CreatedAt, "read-only columns should not be updated")
+
+		assert.NoError(t, users.Delete(ctx, 2))
+		assert.ErrorIs(t, users.Delete(ctx, 2), sql.ErrNoRows)
+		_, err = users.FindByID(ctx, 2)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.ErrorIs(t, users.Update(ctx, &repositoryUser{ID: 2, Email: "jane@doe.com"}), sql.ErrNoRows)
+
+		list, err := users.List(ctx, ListOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, list, 2)
+
+		list, err = users.List(ctx, ListOptions{WithDeleted: true, Sort: []Sort{{Column: "email"}}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"jane@doe.com", "jim@doe.com", "john@doe.com"}, userEmails(list))
+		assert.True(t, list[0].DeletedAt.Valid)
+	})
+
+	t.Run("should filter, sort and page", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+		users, err := NewRepository[repositoryUser](db)
+		assert.NoError(t, err)
+
+		for index, email := range []string{"a@doe.com", "b@doe.com", "c@doe.com", "d@test.com"} {
+			assert.NoError(t, users.Insert(ctx, &repositoryUser{Email: email, Age: 20 + index}))
+		}
+
+		tests := []struct {
+			name     string
+			options  ListOptions
+			expected []string
+		}{
+			{
+				name:     "comparison",
+				options:  ListOptions{Filters: []Filter{{Column: "age", Operator: OpGte, Value: 21}}, Sort: []Sort{{Column: "age", Desc: true}}},
+				expected: []string{"d@test.com", "c@doe.com", "b@doe.com"},
+			},
+			{
+				name:     "like and in",
+				options:  ListOptions{Filters: []Filter{{Column: "email", Operator: OpLike, Value: "%@doe.com"}, {Column: "id", Operator: OpIn, Value: []int{1, 3, 4}}}},
+				expected: []string{"a@doe.com", "c@doe.com"},
+			},
+			{
+				name:     "empty in",
+				options:  ListOptions{Filters: []Filter{{Column: "id", Operator: OpIn, Value: []int{}}}},
+				expected: []string{},
+			},
+			{
+				name:     "limit and offset",
+				options:  ListOptions{Sort: []Sort{{Column: "id"}}, Limit: 2, Offset: 1},
+				expected: []string{"b@doe.com", "c@doe.com"},
+			},
+			{
+				name:     "offset without limit",
+				options:  ListOptions{Sort: []Sort{{Column: "id"}}, Offset: 3},
+				expected: []string{"d@test.com"},
+			},
+			{
+				name:     "null",
+				options:  ListOptions{Filters: []Filter{{Column: "deleted_at", Operator: OpIsNotNull}}},
+				expected: []string{},
+			},
+		}
+
+		for _, test := range tests {
+			list, err := users.List(ctx, test.options)
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.expected, userEmails(list), test.name)
+		}
+
+		_, err = users.List(ctx, ListOptions{Filters: []Filter{{Column: "email; DROP TABLE users", Operator: OpEq}}})
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+		_, err = users.List(ctx, ListOptions{Sort: []Sort{{Column: "password"}}})
+		assert.ErrorIs(t, err, ErrUnknownColumn)
+		_, err = users.List(ctx, ListOptions{Filters: []Filter{{Column: "age", Operator: "BETWEEN"}}})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+		_, err = users.List(ctx, ListOptions{Filters: []Filter{{Column: "age", Operator: OpIn, Value: 1}}})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+
+	t.Run("should use composite keys within a transaction", func(t *testing.T) {
+		db := newSQLiteTestDB(t, ddl)
+		memberships, err := NewRepository[repositoryMembership](db)
+		assert.NoError(t, err)
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		inTx := memberships.With(tx)
+		assert.NoError(t, inTx.Insert(ctx, &repositoryMembership{Team: "core", Member: "john", Role: "owner"}))
+		assert.NoError(t, inTx.Insert(ctx, &repositoryMembership{Team: "core", Member: "jane", Role: "member"}))
+		assert.NoError(t, inTx.Update(ctx, &repositoryMembership{Team: "core", Member: "jane", Role: "admin"}))
+		assert.NoError(t, inTx.Delete(ctx, "core", "john"))
+		assert.NoError(t, tx.Commit())
+
+		membership, err := memberships.FindByID(ctx, "core", "jane")
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", membership.Role)
+		_, err = memberships.FindByID(ctx, "core", "john")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		_, err = memberships.FindByID(ctx, "core")
+		assert.ErrorIs(t, err, ErrInvalidRepository)
+	})
+
+	t.Run("should validate the model", func(t *testing.T) {
+		db := newSQLiteTestDB(t, "")
+
+		type noTable struct {
+			ID int `db:"id"`
+		}
+
+		type noKey struct {
+			_    struct{} `godb:"table:things"`
+			Name string   `db:"name"`
+		}
+
+		_, err := NewRepository[noTable](db)
+		assert.ErrorIs(t, err, ErrInvalidRepository)
+		_, err = NewRepository[noKey](db)
+		assert.ErrorIs(t, err, ErrInvalidRepository)
+		_, err = NewRepository[int](db)
+		assert.ErrorIs(t, err, ErrInvalidRepository)
+	})
+}
+
+// userEmails returns the emails of users
+func userEmails(users []repositoryUser) []string {
+	emails := []string{}
+	for _, user := range users {
+		emails = append(emails, user.Email)
+	}
+	return emails
+}
@@ -1,6 +1,7 @@
 package godb
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/jmoiron/sqlx"
@@ -30,7 +31,17 @@ type Row interface {
 
 // customRow implements the Row interface
 type customRow struct {
-	row *sqlx.Row
+	row    *sqlx.Row
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// release releases the query timeout once the row is scanned
+func (c *customRow) release(err error) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return timeoutError(c.ctx, err)
 }
 
 // ColumnTypes
@@ -45,25 +56,67 @@ func (c *customRow) Columns() ([]string, error) {
 
 // Err
 func (c *customRow) Err() error {
-	return c.row.Err()
+	return timeoutError(c.ctx, c.row.Err())
 }
 
 // Scan
 func (c *customRow) Scan(dest ...interface{}) error {
-	return c.row.Scan(dest...)
+	return c.release(c.row.Scan(dest...))
 }
 
 // MapScan
 func (c *customRow) MapScan(dest map[string]interface{}) error {
-	return c.row.MapScan(dest)
+	return c.release(c.row.MapScan(dest))
 }
 
 // SliceScan
 func (c *customRow) SliceScan() ([]interface{}, error) {
-	return c.row.SliceScan()
+	values, err := c.row.SliceScan()
+	return values, c.release(err)
 }
 
 // StructScan
 func (c *customRow) StructScan(dest interface{}) error {
-	return c.row.StructScan(dest)
+	return c.release(c.row.StructScan(dest))
+}
+
+// errorRow is a Row that returns the same error for every operation.
+// It is used when a Row can not be created, since QueryRow does not return an error.
+type errorRow struct {
+	err error
+}
+
+// ColumnTypes
+func (e *errorRow) ColumnTypes() ([]*sql.ColumnType, error) {
+	return nil, e.err
+}
+
+// Columns
+func (e *errorRow) Columns() ([]string, error) {
+	return nil, e.err
+}
+
+// Err
+func (e *errorRow) Err() error {
+	return e.err
+}
+
+// MapScan
+func (e *errorRow) MapScan(dest map[string]interface{}) error {
+	return e.err
+}
+
+// Scan
+func (e *errorRow) Scan(dest ...interface{}) error {
+	return e.err
+}
+
+// SliceScan
+func (e *errorRow) SliceScan() ([]interface{}, error) {
+	return nil, e.err
+}
+
+// StructScan
+func (e *errorRow) StructScan(dest interface{}) error {
+	return e.err
 }
@@ -1,6 +1,7 @@
 package godb
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/jmoiron/sqlx"
@@ -118,11 +119,21 @@ type Rows interface {
 
 // customRows implements the Rows interface
 type customRows struct {
-	rows *sqlx.Rows
+	rows   *sqlx.Rows
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// release releases the query timeout
+func (r *customRows) release() {
+	if r.cancel != nil {
+		r.cancel()
+	}
 }
 
 // Close
 func (r *customRows) Close() error {
+	defer r.release()
 	return r.rows.Close()
 }
 
@@ -138,12 +149,16 @@ func (r *customRows) Columns() ([]string, error) {
 
 // Err
 func (r *customRows) Err() error {
-	return r.rows.Err()
+	return timeoutError(r.ctx, r.rows.Err())
 }
 
 // Next
 func (r *customRows) Next() bool {
-	return r.rows.Next()
+	if !r.rows.Next() {
+		r.release()
+		return false
+	}
+	return true
 }
 
 // NextResultSet
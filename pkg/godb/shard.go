@@ -0,0 +1,650 @@
+package godb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultShardReplicas = 128
+
+// ShardStrategy defines how shard keys are mapped to shards
+type ShardStrategy interface {
+	// Shard returns the shard of a key.
+	Shard(key string) (string, error)
+	// Shards returns the shard names in alphabetical order.
+	Shards() []string
+	// AddShard returns a copy of the strategy including shard, and the plan
+	// describing the keys moved to it. The strategy itself is not changed.
+	AddShard(shard string) (ShardStrategy, RebalancePlan, error)
+}
+
+// ShardConfig defines the sharded database configs
+type ShardConfig struct {
+	// Shards are the databases by shard name.
+	Shards map[string]DB
+	// Strategy maps the shard keys to the shards. Its shards must match Shards.
+	// Defaults to a ConsistentHash of the Shards.
+	Strategy ShardStrategy
+}
+
+// ShardedDB defines a database split across several shards by a shard key
+type ShardedDB interface {
+	// Shard returns the database of a shard key.
+	Shard(key string) (DB, error)
+	// ShardName returns the name of the shard of a shard key.
+	ShardName(key string) (string, error)
+	// Shards returns the shard names in alphabetical order.
+	Shards() []string
+	// FanOut calls fn concurrently for every shard. Every shard is called even if others
+	// fail, and the failures are returned as a *FanOutError.
+	FanOut(ctx context.Context, fn func(ctx context.Context, shard string, db DB) error) error
+	// ExecAll executes the query on every shard and returns the total of rows affected.
+	ExecAll(ctx context.Context, query string, args ...interface{}) (int64, error)
+	// PlanShard returns the rebalancing plan of adding a shard without adding it.
+	PlanShard(shard string) (RebalancePlan, error)
+	// AddShard adds a shard and routes the keys of its plan to it.
+	//
+	// To add a shard without downtime, copy the keys of the PlanShard plan from their
+	// source shards to the new one, call AddShard and finally delete the moved keys from
+	// the source shards. Writes to the moved keys between the copy and AddShard are lost,
+	// so they should be paused or copied again.
+	AddShard(shard string, db DB) (RebalancePlan, error)
+	// Close closes every shard.
+	Close() error
+}
+
+// ShardError defines the error of a shard
+type ShardError struct {
+	Shard string
+	Err   error
+}
+
+// Error
+func (se *ShardError) Error() string {
+	return fmt.Sprintf("shard %s: %s", se.Shard, se.Err)
+}
+
+// Unwrap
+func (se *ShardError) Unwrap() error {
+	return se.Err
+}
+
+// FanOutError defines the errors of the shards that failed during a fan-out
+type FanOutError struct {
+	Errors []*ShardError
+}
+
+// Error
+func (foe *FanOutError) Error() string {
+	messages := make([]string, len(foe.Errors))
+	for index, err := range foe.Errors {
+		messages[index] = err.Error()
+	}
+	return fmt.Sprintf("%d shard(s) failed. %s", len(foe.Errors), strings.Join(messages, "; "))
+}
+
+// Unwrap
+func (foe *FanOutError) Unwrap() []error {
+	errs := make([]error, len(foe.Errors))
+	for index, err := range foe.Errors {
+		errs[index] = err
+	}
+	return errs
+}
+
+// NewShardedDB Creates a new sharded database
+func NewShardedDB(config ShardConfig) (ShardedDB, error) {
+	if len(config.Shards) == 0 {
+		return nil, fmt.Errorf("%w. at least one shard is required", ErrInvalidShardConfig)
+	}
+
+	names := make([]string, 0, len(config.Shards))
+	shards := make(map[string]DB, len(config.Shards))
+	for name, db := range config.Shards {
+		if db == nil {
+			return nil, fmt.Errorf("%w. shard %s has no database", ErrInvalidShardConfig, name)
+		}
+		names = append(names, name)
+		shards[name] = db
+	}
+	sort.Strings(names)
+
+	if config.Strategy == nil {
+		strategy, err := NewConsistentHash(0, names...)
+		if err != nil {
+			return nil, err
+		}
+		config.Strategy = strategy
+	}
+
+	if strategyShards := config.Strategy.Shards(); strings.Join(strategyShards, "\x00") != strings.Join(names, "\x00") {
+		return nil, fmt.Errorf("%w. strategy shards %v do not match %v", ErrInvalidShardConfig, strategyShards, names)
+	}
+	return &shardedDB{shards: shards, strategy: config.Strategy}, nil
+}
+
+// SelectAll Executes the query on every shard concurrently and merges the results in the
+// shard order. The results of the shards that succeeded are returned with the *FanOutError
+// of the ones that failed.
+func SelectAll[T any](ctx context.Context, db ShardedDB, query string, args ...interface{}) ([]T, error) {
+	var mutex sync.Mutex
+	results := map[string][]T{}
+
+	err := db.FanOut(ctx, func(ctx context.Context, shard string, db DB) error {
+		var dest []T
+		if err := db.SelectContext(ctx, &dest, query, args...); err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		results[shard] = dest
+		return nil
+	})
+
+	merged := []T{}
+	for _, shard := range db.Shards() {
+		merged = append(merged, results[shard]...)
+	}
+	return merged, err
+}
+
+// shardedDB implements the ShardedDB interface
+type shardedDB struct {
+	mutex    sync.RWMutex
+	shards   map[string]DB
+	strategy ShardStrategy
+}
+
+// Shard
+func (sdb *shardedDB) Shard(key string) (DB, error) {
+	sdb.mutex.RLock()
+	defer sdb.mutex.RUnlock()
+
+	name, err := sdb.strategy.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+
+	db, ok := sdb.shards[name]
+	if !ok {
+		return nil, fmt.Errorf("%s. %w", name, ErrNoShard)
+	}
+	return db, nil
+}
+
+// ShardName
+func (sdb *shardedDB) ShardName(key string) (string, error) {
+	sdb.mutex.RLock()
+	defer sdb.mutex.RUnlock()
+	return sdb.strategy.Shard(key)
+}
+
+// Shards
+func (sdb *shardedDB) Shards() []string {
+	sdb.mutex.RLock()
+	defer sdb.mutex.RUnlock()
+	return sdb.strategy.Shards()
+}
+
+// FanOut
+func (sdb *shardedDB) FanOut(ctx context.Context, fn func(ctx context.Context, shard string, db DB) error) error {
+	sdb.mutex.RLock()
+	names := sdb.strategy.Shards()
+	shards := make([]DB, len(names))
+	for index, name := range names {
+		shards[index] = sdb.shards[name]
+	}
+	sdb.mutex.RUnlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(names))
+	for index := range names {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[index] = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			errs[index] = fn(ctx, names[index], shards[index])
+		}(index)
+	}
+	wg.Wait()
+
+	fanOutErr := &FanOutError{}
+	for index, err := range errs {
+		if err != nil {
+			fanOutErr.Errors = append(fanOutErr.Errors, &ShardError{Shard: names[index], Err: err})
+		}
+	}
+
+	if len(fanOutErr.Errors) > 0 {
+		return fanOutErr
+	}
+	return nil
+}
+
+// ExecAll
+func (sdb *shardedDB) ExecAll(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var mutex sync.Mutex
+	var total int64
+
+	err := sdb.FanOut(ctx, func(ctx context.Context, shard string, db DB) error {
+		result, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		total += affected
+		return nil
+	})
+	return total, err
+}
+
+// PlanShard
+func (sdb *shardedDB) PlanShard(shard string) (RebalancePlan, error) {
+	sdb.mutex.RLock()
+	defer sdb.mutex.RUnlock()
+
+	if _, ok := sdb.shards[shard]; ok {
+		return RebalancePlan{}, fmt.Errorf("%w. shard %s already exists", ErrInvalidShardConfig, shard)
+	}
+
+	_, plan, err := sdb.strategy.AddShard(shard)
+	return plan, err
+}
+
+// AddShard
+func (sdb *shardedDB) AddShard(shard string, db DB) (RebalancePlan, error) {
+	if db == nil {
+		return RebalancePlan{}, fmt.Errorf("%w. shard %s has no database", ErrInvalidShardConfig, shard)
+	}
+
+	sdb.mutex.Lock()
+	defer sdb.mutex.Unlock()
+
+	if _, ok := sdb.shards[shard]; ok {
+		return RebalancePlan{}, fmt.Errorf("%w. shard %s already exists", ErrInvalidShardConfig, shard)
+	}
+
+	strategy, plan, err := sdb.strategy.AddShard(shard)
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+
+	sdb.shards[shard] = db
+	sdb.strategy = strategy
+	return plan, nil
+}
+
+// Close
+func (sdb *shardedDB) Close() error {
+	sdb.mutex.RLock()
+	defer sdb.mutex.RUnlock()
+
+	var errs []error
+	for _, name := range sdb.strategy.Shards() {
+		if err := sdb.shards[name].Close(); err != nil {
+			errs = append(errs, &ShardError{Shard: name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RebalancePlan describes the keys moved to a new shard.
+//
+// Moves are inclusive ranges of key hashes when Hashed is set, see ShardKeyHash,
+// or of numeric keys otherwise.
+type RebalancePlan struct {
+	Shard  string          `json:"shard"`
+	Hashed bool            `json:"hashed"`
+	Moves  []RebalanceMove `json:"moves"`
+}
+
+// RebalanceMove describes a range of keys moved between shards
+type RebalanceMove struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// Moved Returns the move of a key or false if the key stays in its shard
+func (rp RebalancePlan) Moved(key string) (RebalanceMove, bool) {
+	position, err := rp.position(key)
+	if err != nil {
+		return RebalanceMove{}, false
+	}
+
+	for _, move := range rp.Moves {
+		if position >= move.Start && position <= move.End {
+			return move, true
+		}
+	}
+	return RebalanceMove{}, false
+}
+
+// position returns the position of a key in the move ranges
+func (rp RebalancePlan) position(key string) (uint64, error) {
+	if rp.Hashed {
+		return ShardKeyHash(key), nil
+	}
+	return parseRangeKey(key)
+}
+
+// String describes the steps of the plan
+func (rp RebalancePlan) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "add shard %s: %d range(s) to move\n", rp.Shard, len(rp.Moves))
+
+	kind := "keys"
+	if rp.Hashed {
+		kind = "keys with hash"
+	}
+
+	for index, move := range rp.Moves {
+		fmt.Fprintf(&builder, "%d. copy %s in [%d, %d] from %s to %s\n", index+1, kind, move.Start, move.End, move.From, move.To)
+	}
+	fmt.Fprintf(&builder, "%d. route the moved keys to %s\n", len(rp.Moves)+1, rp.Shard)
+	fmt.Fprintf(&builder, "%d. delete the moved keys from their source shards\n", len(rp.Moves)+2)
+	return builder.String()
+}
+
+// ShardKeyHash Returns the hash of a shard key used by ConsistentHash.
+// It is FNV-1a followed by the MurmurHash3 finalizer, which spreads similar keys
+// such as sequential IDs across the whole ring.
+func ShardKeyHash(key string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+
+	sum := hash.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	sum *= 0xc4ceb9fe1a85ec53
+	sum ^= sum >> 33
+	return sum
+}
+
+// ringPoint defines a virtual node of a consistent hash ring
+type ringPoint struct {
+	hash  uint64
+	shard string
+}
+
+// ConsistentHash maps keys to shards using a consistent hash ring, so adding a shard only
+// moves the keys it takes over. Every shard is placed on the ring several times to
+// spread the keys evenly.
+type ConsistentHash struct {
+	replicas int
+	shards   []string
+	ring     []ringPoint
+}
+
+// NewConsistentHash Returns a consistent hash ring with the given shards.
+// Replicas is the amount of points of each shard on the ring. Defaults to 128.
+func NewConsistentHash(replicas int, shards ...string) (*ConsistentHash, error) {
+	if replicas <= 0 {
+		replicas = defaultShardReplicas
+	}
+
+	ch := &ConsistentHash{replicas: replicas}
+	for _, shard := range shards {
+		if err := ch.add(shard); err != nil {
+			return nil, err
+		}
+	}
+	ch.sort()
+	return ch, nil
+}
+
+// Shard
+func (ch *ConsistentHash) Shard(key string) (string, error) {
+	if len(ch.ring) == 0 {
+		return "", ErrNoShard
+	}
+	return ch.owner(ShardKeyHash(key)), nil
+}
+
+// Shards
+func (ch *ConsistentHash) Shards() []string {
+	return append([]string{}, ch.shards...)
+}
+
+// AddShard
+func (ch *ConsistentHash) AddShard(shard string) (ShardStrategy, RebalancePlan, error) {
+	next := &ConsistentHash{
+		replicas: ch.replicas,
+		shards:   append([]string{}, ch.shards...),
+		ring:     append([]ringPoint{}, ch.ring...),
+	}
+	if err := next.add(shard); err != nil {
+		return nil, RebalancePlan{}, err
+	}
+	next.sort()
+
+	plan := RebalancePlan{Shard: shard, Hashed: true, Moves: []RebalanceMove{}}
+	if len(ch.ring) == 0 {
+		return next, plan, nil
+	}
+
+	// Every point owns the hashes after the previous point, up to its own hash
+	for index, point := range next.ring {
+		if point.shard != shard {
+			continue
+		}
+
+		from := ch.owner(point.hash)
+		if index == 0 {
+			previous := next.ring[len(next.ring)-1].hash
+			if previous < math.MaxUint64 {
+				plan.addMove(RebalanceMove{From: from, To: shard, Start: previous + 1, End: math.MaxUint64})
+			}
+			plan.addMove(RebalanceMove{From: from, To: shard, Start: 0, End: point.hash})
+			continue
+		}
+
+		plan.addMove(RebalanceMove{From: from, To: shard, Start: next.ring[index-1].hash + 1, End: point.hash})
+	}
+
+	sort.Slice(plan.Moves, func(i, j int) bool { return plan.Moves[i].Start < plan.Moves[j].Start })
+	return next, plan, nil
+}
+
+// add adds the points of a shard. The ring must be sorted afterwards.
+func (ch *ConsistentHash) add(shard string) error {
+	if shard == "" {
+		return fmt.Errorf("%w. empty shard name", ErrInvalidShardConfig)
+	}
+
+	for _, current := range ch.shards {
+		if current == shard {
+			return fmt.Errorf("%w. duplicated shard %s", ErrInvalidShardConfig, shard)
+		}
+	}
+
+	ch.shards = append(ch.shards, shard)
+	for replica := 0; replica < ch.replicas; replica++ {
+		ch.ring = append(ch.ring, ringPoint{hash: ShardKeyHash(shard + "#" + strconv.Itoa(replica)), shard: shard})
+	}
+	return nil
+}
+
+// sort sorts the shards and the ring
+func (ch *ConsistentHash) sort() {
+	sort.Strings(ch.shards)
+	sort.Slice(ch.ring, func(i, j int) bool {
+		if ch.ring[i].hash == ch.ring[j].hash {
+			return ch.ring[i].shard < ch.ring[j].shard
+		}
+		return ch.ring[i].hash < ch.ring[j].hash
+	})
+}
+
+// owner returns the shard of the first point at or after hash
+func (ch *ConsistentHash) owner(hash uint64) string {
+	index := sort.Search(len(ch.ring), func(i int) bool { return ch.ring[i].hash >= hash })
+	if index == len(ch.ring) {
+		index = 0
+	}
+	return ch.ring[index].shard
+}
+
+// addMove adds a move, merging it with the previous one when they are contiguous
+func (rp *RebalancePlan) addMove(move RebalanceMove) {
+	if move.Start > move.End {
+		return
+	}
+
+	if last := len(rp.Moves) - 1; last >= 0 {
+		previous := &rp.Moves[last]
+		if previous.From == move.From && previous.To == move.To && previous.End < math.MaxUint64 && previous.End+1 == move.Start {
+			previous.End = move.End
+			return
+		}
+	}
+	rp.Moves = append(rp.Moves, move)
+}
+
+// ShardRange defines an inclusive range of numeric shard keys
+type ShardRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Shard string `json:"shard"`
+}
+
+// RangeMap maps numeric keys, such as sequential customer IDs, to shards by key range.
+// Keys outside every range have no shard.
+type RangeMap struct {
+	ranges []ShardRange
+}
+
+// NewRangeMap Returns a range map. The ranges can not overlap.
+func NewRangeMap(ranges ...ShardRange) (*RangeMap, error) {
+	rm := &RangeMap{ranges: append([]ShardRange{}, ranges...)}
+	sort.Slice(rm.ranges, func(i, j int) bool { return rm.ranges[i].Start < rm.ranges[j].Start })
+
+	for index, shardRange := range rm.ranges {
+		if shardRange.Shard == "" || shardRange.Start > shardRange.End {
+			return nil, fmt.Errorf("%w. invalid range %+v", ErrInvalidShardConfig, shardRange)
+		}
+
+		if index > 0 && rm.ranges[index-1].End >= shardRange.Start {
+			return nil, fmt.Errorf("%w. range %+v overlaps %+v", ErrInvalidShardConfig, shardRange, rm.ranges[index-1])
+		}
+	}
+	return rm, nil
+}
+
+// Ranges Returns the ranges sorted by key
+func (rm *RangeMap) Ranges() []ShardRange {
+	return append([]ShardRange{}, rm.ranges...)
+}
+
+// Shard
+func (rm *RangeMap) Shard(key string) (string, error) {
+	position, err := parseRangeKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	index := sort.Search(len(rm.ranges), func(i int) bool { return rm.ranges[i].End >= position })
+	if index == len(rm.ranges) || rm.ranges[index].Start > position {
+		return "", fmt.Errorf("%s. %w", key, ErrNoShard)
+	}
+	return rm.ranges[index].Shard, nil
+}
+
+// Shards
+func (rm *RangeMap) Shards() []string {
+	seen := map[string]bool{}
+	shards := []string{}
+	for _, shardRange := range rm.ranges {
+		if !seen[shardRange.Shard] {
+			seen[shardRange.Shard] = true
+			shards = append(shards, shardRange.Shard)
+		}
+	}
+	sort.Strings(shards)
+	return shards
+}
+
+// AddShard splits the widest range in half and moves its upper half to the new shard.
+// Use Split to choose the moved range.
+func (rm *RangeMap) AddShard(shard string) (ShardStrategy, RebalancePlan, error) {
+	if len(rm.ranges) == 0 {
+		return nil, RebalancePlan{}, fmt.Errorf("%w. no range to split", ErrInvalidShardConfig)
+	}
+
+	widest := rm.ranges[0]
+	for _, shardRange := range rm.ranges[1:] {
+		if shardRange.End-shardRange.Start > widest.End-widest.Start {
+			widest = shardRange
+		}
+	}
+
+	if widest.Start == widest.End {
+		return nil, RebalancePlan{}, fmt.Errorf("%w. no range to split", ErrInvalidShardConfig)
+	}
+	return rm.Split(widest.Start+(widest.End-widest.Start)/2+1, shard)
+}
+
+// Split Returns a copy of the range map where the keys from start to the end of its range
+// belong to shard, and the plan moving them
+func (rm *RangeMap) Split(start uint64, shard string) (ShardStrategy, RebalancePlan, error) {
+	for _, current := range rm.Shards() {
+		if current == shard {
+			return nil, RebalancePlan{}, fmt.Errorf("%w. duplicated shard %s", ErrInvalidShardConfig, shard)
+		}
+	}
+
+	ranges := []ShardRange{}
+	plan := RebalancePlan{Shard: shard, Moves: []RebalanceMove{}}
+	for _, shardRange := range rm.ranges {
+		if start <= shardRange.Start || start > shardRange.End {
+			ranges = append(ranges, shardRange)
+			continue
+		}
+
+		ranges = append(ranges,
+			ShardRange{Start: shardRange.Start, End: start - 1, Shard: shardRange.Shard},
+			ShardRange{Start: start, End: shardRange.End, Shard: shard},
+		)
+		plan.Moves = append(plan.Moves, RebalanceMove{From: shardRange.Shard, To: shard, Start: start, End: shardRange.End})
+	}
+
+	if len(plan.Moves) == 0 {
+		return nil, RebalancePlan{}, fmt.Errorf("%w. %d does not split a range", ErrInvalidShardConfig, start)
+	}
+
+	next, err := NewRangeMap(ranges...)
+	if err != nil {
+		return nil, RebalancePlan{}, err
+	}
+	return next, plan, nil
+}
+
+// parseRangeKey parses a numeric shard key
+func parseRangeKey(key string) (uint64, error) {
+	position, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q. %w", key, ErrInvalidShardKey)
+	}
+	return position, nil
+}
@@ -0,0 +1,247 @@
+package godb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConsistentHash(t *testing.T) {
+	ring, err := NewConsistentHash(0, "shard-c", "shard-a", "shard-b")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shard-a", "shard-b", "shard-c"}, ring.Shards())
+
+	keys := make([]string, 3000)
+	before := map[string]string{}
+	counts := map[string]int{}
+	for index := range keys {
+		keys[index] = fmt.Sprintf("customer-%d", index)
+		shard, err := ring.Shard(keys[index])
+		assert.NoError(t, err)
+		before[keys[index]] = shard
+		counts[shard]++
+	}
+
+	for shard, count := range counts {
+		assert.Greater(t, count, 600, "keys should be spread evenly, %s got %d", shard, count)
+	}
+
+	next, plan, err := ring.AddShard("shard-d")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shard-a", "shard-b", "shard-c", "shard-d"}, next.Shards())
+	assert.Equal(t, []string{"shard-a", "shard-b", "shard-c"}, ring.Shards(), "the ring should not be changed")
+	assert.True(t, plan.Hashed)
+
+	moved := 0
+	for _, key := range keys {
+		shard, err := next.Shard(key)
+		assert.NoError(t, err)
+
+		move, ok := plan.Moved(key)
+		if shard == before[key] {
+			assert.False(t, ok, "%s should not move", key)
+			continue
+		}
+
+		moved++
+		assert.True(t, ok, "%s should move", key)
+		assert.Equal(t, RebalanceMove{From: before[key], To: "shard-d", Start: move.Start, End: move.End}, move)
+	}
+	assert.InDelta(t, 750, moved, 250, "about a quarter of the keys should move")
+
+	_, _, err = next.AddShard("shard-a")
+	assert.ErrorIs(t, err, ErrInvalidShardConfig)
+	_, err = NewConsistentHash(10, "")
+	assert.ErrorIs(t, err, ErrInvalidShardConfig)
+
+	empty, err := NewConsistentHash(10)
+	assert.NoError(t, err)
+	_, err = empty.Shard("customer-1")
+	assert.ErrorIs(t, err, ErrNoShard)
+}
+
+func Test_RangeMap(t *testing.T) {
+	ranges, err := NewRangeMap(
+		ShardRange{Start: 1000, End: 2999, Shard: "shard-b"},
+		ShardRange{Start: 0, End: 999, Shard: "shard-a"},
+		ShardRange{Start: 5000, End: 5999, Shard: "shard-a"},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shard-a", "shard-b"}, ranges.Shards())
+
+	tests := []struct {
+		key      string
+		expected string
+		err      error
+	}{
+		{key: "0", expected: "shard-a"},
+		{key: "999", expected: "shard-a"},
+		{key: "1000", expected: "shard-b"},
+		{key: "5500", expected: "shard-a"},
+		{key: "3000", err: ErrNoShard},
+		{key: "customer-1", err: ErrInvalidShardKey},
+	}
+
+	for _, test := range tests {
+		shard, err := ranges.Shard(test.key)
+		assert.ErrorIs(t, err, test.err, test.key)
+		assert.Equal(t, test.expected, shard, test.key)
+	}
+
+	next, plan, err := ranges.AddShard("shard-c")
+	assert.NoError(t, err)
+	assert.Equal(t, RebalancePlan{
+		Shard: "shard-c",
+		Moves: []RebalanceMove{{From: "shard-b", To: "shard-c", Start: 2000, End: 2999}},
+	}, plan)
+	assert.Equal(t, []ShardRange{
+		{Start: 0, End: 999, Shard: "shard-a"},
+		{Start: 1000, End: 1999, Shard: "shard-b"},
+		{Start: 2000, End: 2999, Shard: "shard-c"},
+		{Start: 5000, End: 5999, Shard: "shard-a"},
+	}, next.(*RangeMap).Ranges())
+
+	move, ok := plan.Moved("2500")
+	assert.True(t, ok)
+	assert.Equal(t, "shard-b", move.From)
+	_, ok = plan.Moved("1500")
+	assert.False(t, ok)
+
+	_, _, err = ranges.Split(3000, "shard-c")
+	assert.ErrorIs(t, err, ErrInvalidShardConfig, "splits should be inside a range")
+	_, _, err = ranges.Split(1500, "shard-a")
+	assert.ErrorIs(t, err, ErrInvalidShardConfig, "shards should not be duplicated")
+
+	_, err = NewRangeMap(ShardRange{Start: 0, End: 10, Shard: "shard-a"}, ShardRange{Start: 10, End: 20, Shard: "shard-b"})
+	assert.ErrorIs(t, err, ErrInvalidShardConfig)
+	_, err = NewRangeMap(ShardRange{Start: 10, End: 0, Shard: "shard-a"})
+	assert.ErrorIs(t, err, ErrInvalidShardConfig)
+}
+
+type shardCustomer struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func Test_ShardedDB(t *testing.T) {
+	ddl := "CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT)"
+	ranges, err := NewRangeMap(
+		ShardRange{Start: 0, End: 99, Shard: "shard-a"},
+		ShardRange{Start: 100, End: 199, Shard: "shard-b"},
+	)
+	assert.NoError(t, err)
+
+	sdb, err := NewShardedDB(ShardConfig{
+		Shards:   map[string]DB{"shard-a": newSQLiteTestDB(t, ddl), "shard-b": newSQLiteTestDB(t, ddl)},
+		Strategy: ranges,
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	for _, id := range []int{150, 1, 120, 2} {
+		db, err := sdb.Shard(strconv.Itoa(id))
+		assert.NoError(t, err)
+		_, err = db.NamedExecContext(ctx, "INSERT INTO customers (id, name) VALUES (:id, :name)", shardCustomer{ID: id, Name: fmt.Sprintf("customer %d", id)})
+		assert.NoError(t, err)
+	}
+
+	name, err := sdb.ShardName("150")
+	assert.NoError(t, err)
+	assert.Equal(t, "shard-b", name)
+
+	customers, err := SelectAll[shardCustomer](ctx, sdb, "SELECT * FROM customers ORDER BY id")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 120, 150}, customerIDs(customers))
+
+	affected, err := sdb.ExecAll(ctx, "UPDATE customers SET name = upper(name)")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), affected)
+
+	plan, err := sdb.PlanShard("shard-c")
+	assert.NoError(t, err)
+	assert.Equal(t, "add shard shard-c: 1 range(s) to move\n"+
+		"1. copy keys in [50, 99] from shard-a to shard-c\n"+
+		"2. route the moved keys to shard-c\n"+
+		"3. delete the moved keys from their source shards\n", plan.String())
+	assert.Equal(t, []string{"shard-a", "shard-b"}, sdb.Shards(), "planning should not add the shard")
+
+	added, err := sdb.AddShard("shard-c", newSQLiteTestDB(t, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, plan, added)
+	assert.Equal(t, []string{"shard-a", "shard-b", "shard-c"}, sdb.Shards())
+
+	customers, err = SelectAll[shardCustomer](ctx, sdb, "SELECT * FROM customers ORDER BY id")
+	assert.Equal(t, []int{1, 2, 120, 150}, customerIDs(customers), "the shards that succeeded should be returned")
+
+	var fanOutErr *FanOutError
+	assert.True(t, errors.As(err, &fanOutErr))
+	assert.Len(t, fanOutErr.Errors, 1)
+	assert.Equal(t, "shard-c", fanOutErr.Errors[0].Shard)
+	assert.ErrorContains(t, err, "no such table")
+
+	err = sdb.FanOut(ctx, func(ctx context.Context, shard string, db DB) error {
+		if shard == "shard-a" {
+			panic("boom")
+		}
+		return nil
+	})
+	assert.ErrorContains(t, err, "shard shard-a: panic: boom")
+
+	_, err = sdb.AddShard("shard-a", newSQLiteTestDB(t, ""))
+	assert.ErrorIs(t, err, ErrInvalidShardConfig)
+	_, err = sdb.Shard("1000")
+	assert.ErrorIs(t, err, ErrNoShard)
+	assert.NoError(t, sdb.Close())
+}
+
+func Test_NewShardedDB(t *testing.T) {
+	db := newSQLiteTestDB(t, "")
+	ranges, err := NewRangeMap(ShardRange{Start: 0, End: 99, Shard: "shard-b"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		config ShardConfig
+		err    error
+	}{
+		{
+			name:   "should default to consistent hashing",
+			config: ShardConfig{Shards: map[string]DB{"shard-a": db, "shard-b": db}},
+		},
+		{
+			name:   "should require shards",
+			config: ShardConfig{},
+			err:    ErrInvalidShardConfig,
+		},
+		{
+			name:   "should require databases",
+			config: ShardConfig{Shards: map[string]DB{"shard-a": nil}},
+			err:    ErrInvalidShardConfig,
+		},
+		{
+			name:   "should require matching strategy shards",
+			config: ShardConfig{Shards: map[string]DB{"shard-a": db}, Strategy: ranges},
+			err:    ErrInvalidShardConfig,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewShardedDB(test.config)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+// customerIDs returns the IDs of customers
+func customerIDs(customers []shardCustomer) []int {
+	ids := []int{}
+	for _, customer := range customers {
+		ids = append(ids, customer.ID)
+	}
+	return ids
+}
@@ -69,6 +69,8 @@ type Stmt interface {
 type customStmt struct {
 	stmt    *sqlx.Stmt
 	testErr error
+	hooks   queryHooks
+	query   string
 }
 
 // pushTestError
@@ -90,68 +92,66 @@ func (c *customStmt) Close() error {
 
 // Exec
 func (c *customStmt) Exec(args ...any) (sql.Result, error) {
-	return c.stmt.Exec(args...)
+	return c.ExecContext(context.Background(), args...)
 }
 
 // ExecContext
 func (c *customStmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
-	return c.stmt.ExecContext(ctx, args...)
+	return c.hooks.exec(ctx, OperationExec, c.query, args,
+		func(ctx context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			return c.stmt.ExecContext(ctx, args...)
+		},
+	)
 }
 
 // Get
 func (c *customStmt) Get(dest interface{}, args ...interface{}) error {
-	return c.stmt.Get(dest, args...)
+	return c.GetContext(context.Background(), dest, args...)
 }
 
 // GetContext
 func (c *customStmt) GetContext(ctx context.Context, dest interface{}, args ...interface{}) error {
-	return c.stmt.GetContext(ctx, dest, args...)
+	return c.hooks.scan(ctx, OperationGet, dest, c.query, args,
+		func(ctx context.Context, dest interface{}, _ string, args ...interface{}) error {
+			return c.stmt.GetContext(ctx, dest, args...)
+		},
+	)
 }
 
 // MustExec
 func (c *customStmt) MustExec(args ...interface{}) sql.Result {
-	return c.stmt.MustExec(args...)
+	return mustExec(c.ExecContext(context.Background(), args...))
 }
 
 // MustExecContext
 func (c *customStmt) MustExecContext(ctx context.Context, args ...interface{}) sql.Result {
-	return c.stmt.MustExecContext(ctx, args...)
+	return mustExec(c.ExecContext(ctx, args...))
 }
 
 // QueryRow
 func (c *customStmt) QueryRow(args ...interface{}) Row {
-	return &customRow{row: c.stmt.QueryRowx(args...)}
+	return c.QueryRowContext(context.Background(), args...)
 }
 
 // QueryRowContext
 func (c *customStmt) QueryRowContext(ctx context.Context, args ...interface{}) Row {
-	return &customRow{row: c.stmt.QueryRowxContext(ctx, args...)}
+	return c.hooks.row(ctx, c.query, args, func(ctx context.Context, _ string, args ...interface{}) *sqlx.Row {
+		return c.stmt.QueryRowxContext(ctx, args...)
+	})
 }
 
 // Query
 func (c *customStmt) Query(args ...interface{}) (Rows, error) {
-	if rows, err := c.query(args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
-}
-
-// query
-func (c *customStmt) query(args ...interface{}) (*sqlx.Rows, error) {
-	if c.testErr != nil {
-		return &sqlx.Rows{}, c.popTestError()
-	}
-	return c.stmt.Queryx(args...)
+	return c.QueryContext(context.Background(), args...)
 }
 
 // QueryContext
 func (c *customStmt) QueryContext(ctx context.Context, args ...interface{}) (Rows, error) {
-	if rows, err := c.queryContext(ctx, args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	return c.hooks.rows(ctx, OperationQuery, c.query, args,
+		func(ctx context.Context, _ string, args ...interface{}) (*sqlx.Rows, error) {
+			return c.queryContext(ctx, args...)
+		},
+	)
 }
 
 // queryContext
@@ -164,12 +164,16 @@ func (c *customStmt) queryContext(ctx context.Context, args ...interface{}) (*sq
 
 // Select
 func (c *customStmt) Select(dest interface{}, args ...interface{}) error {
-	return c.stmt.Select(dest, args...)
+	return c.SelectContext(context.Background(), dest, args...)
 }
 
 // SelectContext
 func (c *customStmt) SelectContext(ctx context.Context, dest interface{}, args ...interface{}) error {
-	return c.stmt.SelectContext(ctx, dest, args...)
+	return c.hooks.scan(ctx, OperationSelect, dest, c.query, args,
+		func(ctx context.Context, dest interface{}, _ string, args ...interface{}) error {
+			return c.stmt.SelectContext(ctx, dest, args...)
+		},
+	)
 }
 
 // Unsafe
@@ -0,0 +1,625 @@
+package godb
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultStmtCacheSize = 128
+)
+
+var (
+	// invalidStmtMessages are the driver messages telling that a prepared statement
+	// can no longer be used and must be prepared again
+	invalidStmtMessages = []string{
+		"statement is closed",
+		"needs to be re-prepared",
+		"cached plan must not change result type",
+		"unknown prepared statement handler",
+	}
+)
+
+// StmtCacheDB defines a database that reuses prepared statements
+type StmtCacheDB interface {
+	DB
+
+	// ClearStmtCache closes and removes every cached statement.
+	ClearStmtCache() error
+}
+
+// NewStmtCacheDB Returns a DB that transparently prepares and reuses statements per query text.
+//
+// Exec, Get, Select, Query, QueryRow and NamedExec calls use a cached godb.Stmt or godb.NamedStmt.
+// Exec calls without arguments and batch NamedExec calls are executed directly.
+// At most size statements are kept; the least recently used one is closed when the cache is full.
+// Statements invalidated by a connection error or a schema change are prepared again and
+// the call is retried once. Transactions begun from the returned DB bind the cached statements
+// using Tx.StmtContext and Tx.NamedStmtContext, and prepare on the transaction the ones not
+// cached yet, or evicted from the cache while the transaction uses them.
+func NewStmtCacheDB(db DB, size int) StmtCacheDB {
+	if size <= 0 {
+		size = defaultStmtCacheSize
+	}
+
+	return &stmtCacheDB{
+		DB:    db,
+		size:  size,
+		order: list.New(),
+		stmts: map[string]*list.Element{},
+	}
+}
+
+// stmtCacheEntry defines a cached statement
+type stmtCacheEntry struct {
+	key       string
+	stmt      Stmt
+	namedStmt NamedStmt
+}
+
+// close closes the cached statement
+func (e *stmtCacheEntry) close() error {
+	if e.namedStmt != nil {
+		return e.namedStmt.Close()
+	}
+	return e.stmt.Close()
+}
+
+// stmtCacheDB implements the StmtCacheDB interface
+type stmtCacheDB struct {
+	DB
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	stmts map[string]*list.Element
+}
+
+// lookup returns the cached entry for key moving it to the front. Must be called holding the lock.
+func (sdb *stmtCacheDB) lookup(key string) (*stmtCacheEntry, bool) {
+	if element, ok := sdb.stmts[key]; ok {
+		sdb.order.MoveToFront(element)
+		return element.Value.(*stmtCacheEntry), true
+	}
+	return nil, false
+}
+
+// cached returns the cached entry for key, if any
+func (sdb *stmtCacheDB) cached(key string) (*stmtCacheEntry, bool) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+	return sdb.lookup(key)
+}
+
+// store caches entry, closing the least recently used statements if needed.
+// If another goroutine cached the same key first, the cached entry is returned and entry is closed.
+func (sdb *stmtCacheDB) store(entry *stmtCacheEntry) *stmtCacheEntry {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	if cached, ok := sdb.lookup(entry.key); ok {
+		_ = entry.close()
+		return cached
+	}
+
+	sdb.stmts[entry.key] = sdb.order.PushFront(entry)
+	for sdb.order.Len() > sdb.size {
+		element := sdb.order.Back()
+		sdb.order.Remove(element)
+		evicted := element.Value.(*stmtCacheEntry)
+		delete(sdb.stmts, evicted.key)
+		_ = evicted.close()
+	}
+	return entry
+}
+
+// evict removes entry from the cache and closes it
+func (sdb *stmtCacheDB) evict(entry *stmtCacheEntry) {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	if element, ok := sdb.stmts[entry.key]; ok && element.Value.(*stmtCacheEntry) == entry {
+		sdb.order.Remove(element)
+		delete(sdb.stmts, entry.key)
+	}
+	_ = entry.close()
+}
+
+// stmt returns the cached statement for query, preparing it if needed
+func (sdb *stmtCacheDB) stmt(ctx context.Context, query string) (*stmtCacheEntry, error) {
+	key := "stmt:" + query
+
+	if entry, ok := sdb.cached(key); ok {
+		return entry, nil
+	}
+
+	stmt, err := sdb.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return sdb.store(&stmtCacheEntry{key: key, stmt: stmt}), nil
+}
+
+// namedStmt returns the cached named statement for query, preparing it if needed
+func (sdb *stmtCacheDB) namedStmt(ctx context.Context, query string) (*stmtCacheEntry, error) {
+	key := "named:" + query
+
+	if entry, ok := sdb.cached(key); ok {
+		return entry, nil
+	}
+
+	namedStmt, err := sdb.DB.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return sdb.store(&stmtCacheEntry{key: key, namedStmt: namedStmt}), nil
+}
+
+// withStmt runs fn using the cached statement for query. If the statement is no longer
+// valid it is prepared again and fn is retried once.
+func (sdb *stmtCacheDB) withStmt(ctx context.Context, query string, fn func(stmt Stmt) error) error {
+	entry, err := sdb.stmt(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(entry.stmt); err != nil && isInvalidStmtError(err) {
+		sdb.evict(entry)
+		if entry, err = sdb.stmt(ctx, query); err != nil {
+			return err
+		}
+		return fn(entry.stmt)
+	}
+	return err
+}
+
+// withNamedStmt runs fn using the cached named statement for query. If the statement is
+// no longer valid it is prepared again and fn is retried once.
+func (sdb *stmtCacheDB) withNamedStmt(ctx context.Context, query string, fn func(stmt NamedStmt) error) error {
+	entry, err := sdb.namedStmt(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(entry.namedStmt); err != nil && isInvalidStmtError(err) {
+		sdb.evict(entry)
+		if entry, err = sdb.namedStmt(ctx, query); err != nil {
+			return err
+		}
+		return fn(entry.namedStmt)
+	}
+	return err
+}
+
+// ClearStmtCache
+func (sdb *stmtCacheDB) ClearStmtCache() error {
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	var errs []error
+	for _, element := range sdb.stmts {
+		errs = append(errs, element.Value.(*stmtCacheEntry).close())
+	}
+
+	sdb.order.Init()
+	sdb.stmts = map[string]*list.Element{}
+	return errors.Join(errs...)
+}
+
+// Close
+func (sdb *stmtCacheDB) Close() error {
+	return errors.Join(sdb.ClearStmtCache(), sdb.DB.Close())
+}
+
+// Exec
+func (sdb *stmtCacheDB) Exec(query string, args ...any) (sql.Result, error) {
+	return sdb.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext
+func (sdb *stmtCacheDB) ExecContext(ctx context.Context, query string, args ...any) (result sql.Result, err error) {
+	// Scripts without arguments, such as DDL, may contain several statements and can not be prepared
+	if len(args) == 0 {
+		return sdb.DB.ExecContext(ctx, query)
+	}
+
+	err = sdb.withStmt(ctx, query, func(stmt Stmt) error {
+		result, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	return result, err
+}
+
+// MustExec
+func (sdb *stmtCacheDB) MustExec(query string, args ...interface{}) sql.Result {
+	return sdb.MustExecContext(context.Background(), query, args...)
+}
+
+// MustExecContext
+func (sdb *stmtCacheDB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
+	result, err := sdb.ExecContext(ctx, query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// Get
+func (sdb *stmtCacheDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return sdb.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext
+func (sdb *stmtCacheDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return sdb.withStmt(ctx, query, func(stmt Stmt) error {
+		return stmt.GetContext(ctx, dest, args...)
+	})
+}
+
+// Select
+func (sdb *stmtCacheDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return sdb.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext
+func (sdb *stmtCacheDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return sdb.withStmt(ctx, query, func(stmt Stmt) error {
+		return stmt.SelectContext(ctx, dest, args...)
+	})
+}
+
+// Query
+func (sdb *stmtCacheDB) Query(query string, args ...interface{}) (Rows, error) {
+	return sdb.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext
+func (sdb *stmtCacheDB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows Rows, err error) {
+	err = sdb.withStmt(ctx, query, func(stmt Stmt) error {
+		rows, err = stmt.QueryContext(ctx, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow
+func (sdb *stmtCacheDB) QueryRow(query string, args ...interface{}) Row {
+	return sdb.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext
+func (sdb *stmtCacheDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row Row) {
+	_ = sdb.withStmt(ctx, query, func(stmt Stmt) error {
+		row = stmt.QueryRowContext(ctx, args...)
+		return row.Err()
+	})
+
+	if row == nil {
+		return sdb.DB.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// NamedExec
+func (sdb *stmtCacheDB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return sdb.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext
+func (sdb *stmtCacheDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (result sql.Result, err error) {
+	// Batch inserts expand the query according to the amount of items
+	if isBatchArg(arg) {
+		return sdb.DB.NamedExecContext(ctx, query, arg)
+	}
+
+	err = sdb.withNamedStmt(ctx, query, func(stmt NamedStmt) error {
+		result, err = stmt.ExecContext(ctx, arg)
+		return err
+	})
+	return result, err
+}
+
+// BeginTx
+func (sdb *stmtCacheDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := sdb.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return tx, err
+	}
+	return sdb.newStmtCacheTx(tx), nil
+}
+
+// Begin
+func (sdb *stmtCacheDB) Begin() (Tx, error) {
+	tx, err := sdb.DB.Begin()
+	if err != nil {
+		return tx, err
+	}
+	return sdb.newStmtCacheTx(tx), nil
+}
+
+// MustBegin
+func (sdb *stmtCacheDB) MustBegin() Tx {
+	return sdb.newStmtCacheTx(sdb.DB.MustBegin())
+}
+
+// MustBeginTx
+func (sdb *stmtCacheDB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) Tx {
+	return sdb.newStmtCacheTx(sdb.DB.MustBeginTx(ctx, opts))
+}
+
+// newStmtCacheTx wraps tx so it uses the cached statements
+func (sdb *stmtCacheDB) newStmtCacheTx(tx Tx) Tx {
+	return &stmtCacheTx{
+		Tx:         tx,
+		sdb:        sdb,
+		stmts:      map[string]Stmt{},
+		namedStmts: map[string]NamedStmt{},
+	}
+}
+
+// stmtCacheTx binds the statements cached by the database into a transaction.
+// Statements not cached yet are prepared on the transaction, so the transaction never
+// waits for a second connection. They are closed by the driver when the transaction ends.
+type stmtCacheTx struct {
+	Tx
+	sdb        *stmtCacheDB
+	mu         sync.Mutex
+	stmts      map[string]Stmt
+	namedStmts map[string]NamedStmt
+}
+
+// stmt returns the transaction version of the statement for query
+func (st *stmtCacheTx) stmt(ctx context.Context, query string) (Stmt, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if stmt, ok := st.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	var (
+		stmt Stmt
+		err  error
+	)
+
+	if entry, ok := st.sdb.cached("stmt:" + query); ok {
+		stmt = st.Tx.StmtContext(ctx, entry.stmt)
+	} else if stmt, err = st.Tx.PrepareContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	st.stmts[query] = stmt
+	return stmt, nil
+}
+
+// prepare prepares query on the transaction, replacing its bound statement
+func (st *stmtCacheTx) prepare(ctx context.Context, query string) (Stmt, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	stmt, err := st.Tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	st.stmts[query] = stmt
+	return stmt, nil
+}
+
+// withStmt runs fn using the transaction version of the statement for query. If the statement
+// is no longer valid, such as when the cached one was evicted and closed, it is prepared on the
+// transaction and fn is retried once.
+func (st *stmtCacheTx) withStmt(ctx context.Context, query string, fn func(stmt Stmt) error) error {
+	stmt, err := st.stmt(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(stmt); err != nil && isInvalidStmtError(err) {
+		if stmt, err = st.prepare(ctx, query); err != nil {
+			return err
+		}
+		return fn(stmt)
+	}
+	return err
+}
+
+// namedStmt returns the transaction version of the named statement for query
+func (st *stmtCacheTx) namedStmt(ctx context.Context, query string) (NamedStmt, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if namedStmt, ok := st.namedStmts[query]; ok {
+		return namedStmt, nil
+	}
+
+	var (
+		namedStmt NamedStmt
+		err       error
+	)
+
+	if entry, ok := st.sdb.cached("named:" + query); ok {
+		namedStmt = st.Tx.NamedStmtContext(ctx, entry.namedStmt)
+	} else if namedStmt, err = st.Tx.PrepareNamedContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	st.namedStmts[query] = namedStmt
+	return namedStmt, nil
+}
+
+// prepareNamed prepares the named query on the transaction, replacing its bound statement
+func (st *stmtCacheTx) prepareNamed(ctx context.Context, query string) (NamedStmt, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	namedStmt, err := st.Tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	st.namedStmts[query] = namedStmt
+	return namedStmt, nil
+}
+
+// withNamedStmt runs fn using the transaction version of the named statement for query. If the
+// statement is no longer valid it is prepared on the transaction and fn is retried once.
+func (st *stmtCacheTx) withNamedStmt(ctx context.Context, query string, fn func(stmt NamedStmt) error) error {
+	namedStmt, err := st.namedStmt(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(namedStmt); err != nil && isInvalidStmtError(err) {
+		if namedStmt, err = st.prepareNamed(ctx, query); err != nil {
+			return err
+		}
+		return fn(namedStmt)
+	}
+	return err
+}
+
+// Exec
+func (st *stmtCacheTx) Exec(query string, args ...any) (sql.Result, error) {
+	return st.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext
+func (st *stmtCacheTx) ExecContext(ctx context.Context, query string, args ...any) (result sql.Result, err error) {
+	if len(args) == 0 {
+		return st.Tx.ExecContext(ctx, query)
+	}
+
+	err = st.withStmt(ctx, query, func(stmt Stmt) error {
+		result, err = stmt.ExecContext(ctx, args...)
+		return err
+	})
+	return result, err
+}
+
+// MustExec
+func (st *stmtCacheTx) MustExec(query string, args ...interface{}) sql.Result {
+	return st.MustExecContext(context.Background(), query, args...)
+}
+
+// MustExecContext
+func (st *stmtCacheTx) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
+	result, err := st.ExecContext(ctx, query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// Get
+func (st *stmtCacheTx) Get(dest interface{}, query string, args ...interface{}) error {
+	return st.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext
+func (st *stmtCacheTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return st.withStmt(ctx, query, func(stmt Stmt) error {
+		return stmt.GetContext(ctx, dest, args...)
+	})
+}
+
+// Select
+func (st *stmtCacheTx) Select(dest interface{}, query string, args ...interface{}) error {
+	return st.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext
+func (st *stmtCacheTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return st.withStmt(ctx, query, func(stmt Stmt) error {
+		return stmt.SelectContext(ctx, dest, args...)
+	})
+}
+
+// Query
+func (st *stmtCacheTx) Query(query string, args ...interface{}) (Rows, error) {
+	return st.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext
+func (st *stmtCacheTx) QueryContext(ctx context.Context, query string, args ...interface{}) (rows Rows, err error) {
+	err = st.withStmt(ctx, query, func(stmt Stmt) error {
+		rows, err = stmt.QueryContext(ctx, args...)
+		return err
+	})
+
+	if rows == nil {
+		return &customRows{}, err
+	}
+	return rows, err
+}
+
+// QueryRow
+func (st *stmtCacheTx) QueryRow(query string, args ...interface{}) Row {
+	return st.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext
+func (st *stmtCacheTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row Row) {
+	_ = st.withStmt(ctx, query, func(stmt Stmt) error {
+		row = stmt.QueryRowContext(ctx, args...)
+		return row.Err()
+	})
+
+	if row == nil {
+		return st.Tx.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// NamedExec
+func (st *stmtCacheTx) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return st.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext
+func (st *stmtCacheTx) NamedExecContext(ctx context.Context, query string, arg interface{}) (result sql.Result, err error) {
+	if isBatchArg(arg) {
+		return st.Tx.NamedExecContext(ctx, query, arg)
+	}
+
+	err = st.withNamedStmt(ctx, query, func(stmt NamedStmt) error {
+		result, err = stmt.ExecContext(ctx, arg)
+		return err
+	})
+	return result, err
+}
+
+// isInvalidStmtError checks if err means that a prepared statement must be prepared again
+func isInvalidStmtError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	message := err.Error()
+	for _, invalidMessage := range invalidStmtMessages {
+		if strings.Contains(message, invalidMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBatchArg checks if arg is a slice or array used for batch named queries
+func isBatchArg(arg interface{}) bool {
+	value := reflect.ValueOf(arg)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return value.Type().Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
@@ -0,0 +1,218 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StmtCacheDB(t *testing.T) {
+	type customData struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	ddl := `
+		CREATE TABLE custom_table (
+			id INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL
+		);
+		INSERT INTO custom_table (id, name) VALUES (1, 'John Doe'), (2, 'Jane Doe');
+	`
+
+	tests := []struct {
+		name   string
+		assert func(t *testing.T, db *stmtCacheDB)
+	}{
+		{
+			name: "should reuse statements",
+			assert: func(t *testing.T, db *stmtCacheDB) {
+				var data customData
+				for i := 0; i < 3; i++ {
+					assert.NoError(t, db.Get(&data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				}
+				assert.Equal(t, "John Doe", data.Name)
+				assert.Equal(t, 1, db.order.Len(), "statement should be cached once")
+
+				var list []customData
+				assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+				assert.Len(t, list, 2)
+
+				rows, err := db.Query("SELECT name FROM custom_table")
+				assert.NoError(t, err)
+				assert.NoError(t, rows.Close())
+
+				var name string
+				assert.NoError(t, db.QueryRow("SELECT name FROM custom_table WHERE id = ?", 2).Scan(&name))
+				assert.Equal(t, "Jane Doe", name)
+
+				_, err = db.NamedExec("UPDATE custom_table SET name = :name WHERE id = :id", customData{ID: 1, Name: "Johnny"})
+				assert.NoError(t, err)
+				_ = db.MustExec("UPDATE custom_table SET name = ? WHERE id = ?", "Janet", 2)
+				assert.Equal(t, 3, db.order.Len(), "should keep at most 3 statements")
+
+				assert.NoError(t, db.Get(&data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, "Johnny", data.Name)
+			},
+		},
+		{
+			name: "should prepare closed statements again",
+			assert: func(t *testing.T, db *stmtCacheDB) {
+				var data customData
+				query := "SELECT * FROM custom_table WHERE id = ?"
+				assert.NoError(t, db.Get(&data, query, 1))
+
+				entry, err := db.stmt(context.Background(), query)
+				assert.NoError(t, err)
+				assert.NoError(t, entry.stmt.Close())
+
+				assert.NoError(t, db.Get(&data, query, 2))
+				assert.Equal(t, "Jane Doe", data.Name)
+
+				newEntry, err := db.stmt(context.Background(), query)
+				assert.NoError(t, err)
+				assert.NotSame(t, entry, newEntry, "statement should be prepared again")
+
+				var name string
+				assert.NoError(t, newEntry.stmt.Close())
+				assert.NoError(t, db.QueryRow("SELECT name FROM custom_table WHERE id = ?", 1).Scan(&name))
+				entry, err = db.stmt(context.Background(), "SELECT name FROM custom_table WHERE id = ?")
+				assert.NoError(t, err)
+				assert.NoError(t, entry.stmt.Close())
+				assert.NoError(t, db.QueryRow("SELECT name FROM custom_table WHERE id = ?", 2).Scan(&name), "QueryRow should prepare closed statements again")
+				assert.Equal(t, "Jane Doe", name)
+			},
+		},
+		{
+			name: "should prepare evicted statements on transactions",
+			assert: func(t *testing.T, db *stmtCacheDB) {
+				query := "SELECT * FROM custom_table WHERE id = ?"
+				namedQuery := "UPDATE custom_table SET name = :name WHERE id = :id"
+				var data customData
+				assert.NoError(t, db.Get(&data, query, 1))
+				_, err := db.NamedExec(namedQuery, customData{ID: 1, Name: "John Doe"})
+				assert.NoError(t, err)
+
+				tx, err := db.Begin()
+				assert.NoError(t, err)
+
+				// The cached statements are evicted and closed while the transaction binds them
+				entry, err := db.stmt(context.Background(), query)
+				assert.NoError(t, err)
+				assert.NoError(t, entry.close())
+				namedEntry, err := db.namedStmt(context.Background(), namedQuery)
+				assert.NoError(t, err)
+				assert.NoError(t, namedEntry.close())
+
+				_, err = tx.NamedExec(namedQuery, customData{ID: 1, Name: "Johnny"})
+				assert.NoError(t, err, "evicted statements should be prepared on the transaction")
+				assert.NoError(t, tx.Get(&data, query, 1), "evicted statements should be prepared on the transaction")
+				assert.Equal(t, "Johnny", data.Name)
+
+				assert.NoError(t, db.ClearStmtCache())
+				assert.NoError(t, tx.Get(&data, query, 1))
+				_, err = tx.NamedExec(namedQuery, customData{ID: 2, Name: "Janet"})
+				assert.NoError(t, err)
+
+				var name string
+				assert.NoError(t, tx.QueryRow("SELECT name FROM custom_table WHERE id = ?", 2).Scan(&name))
+				assert.Equal(t, "Janet", name)
+				assert.NoError(t, tx.Rollback())
+
+				// Drivers may fail the statements bound from closed ones
+				prepared := 0
+				mockTx := db.newStmtCacheTx(&TxMock{
+					CallbackStmtContext: func(ctx context.Context, stmt interface{}) Stmt {
+						return &StmtMock{Error: errors.New("sql: statement is closed")}
+					},
+					CallbackPrepareContext: func(ctx context.Context, query string) (Stmt, error) {
+						prepared++
+						return &StmtMock{}, nil
+					},
+				})
+				assert.NoError(t, db.Get(&data, query, 1))
+				assert.NoError(t, mockTx.Get(&data, query, 1))
+				assert.NoError(t, mockTx.Get(&data, query, 1))
+				assert.Equal(t, 1, prepared, "the statement should be prepared on the transaction once")
+			},
+		},
+		{
+			name: "should bind cached statements into transactions",
+			assert: func(t *testing.T, db *stmtCacheDB) {
+				_, err := db.Exec("UPDATE custom_table SET name = ? WHERE id = ?", "John Doe", 1)
+				assert.NoError(t, err)
+
+				tx, err := db.Begin()
+				assert.NoError(t, err)
+
+				_, err = tx.Exec("UPDATE custom_table SET name = ? WHERE id = ?", "Johnny", 1)
+				assert.NoError(t, err)
+				_, err = tx.NamedExec("UPDATE custom_table SET name = :name WHERE id = :id", customData{ID: 2, Name: "Janet"})
+				assert.NoError(t, err)
+				_, err = tx.NamedExec(
+					"INSERT INTO custom_table (id, name) VALUES (:id, :name)",
+					[]customData{{ID: 3, Name: "Jack"}, {ID: 4, Name: "Jill"}},
+				)
+				assert.NoError(t, err)
+
+				var list []customData
+				assert.NoError(t, tx.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+				assert.Equal(t, []customData{{1, "Johnny"}, {2, "Janet"}, {3, "Jack"}, {4, "Jill"}}, list)
+				assert.NoError(t, tx.Rollback())
+
+				var data customData
+				assert.NoError(t, db.Get(&data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.Equal(t, "John Doe", data.Name, "transaction should be rolled back")
+				assert.Equal(t, 2, db.order.Len(), "statements prepared on the transaction should not be cached")
+			},
+		},
+		{
+			name: "should clear the cache",
+			assert: func(t *testing.T, db *stmtCacheDB) {
+				var data customData
+				assert.NoError(t, db.Get(&data, "SELECT * FROM custom_table WHERE id = ?", 1))
+				assert.NoError(t, db.ClearStmtCache())
+				assert.Equal(t, 0, db.order.Len())
+
+				assert.Error(t, db.Get(&data, "SELECT * FROM unknown_table"), "should return prepare errors")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := NewStmtCacheDB(newSQLiteTestDB(t, ddl), 3)
+			test.assert(t, db.(*stmtCacheDB))
+		})
+	}
+}
+
+func Test_StmtCacheDBConfig(t *testing.T) {
+	db, err := NewDB(DBConfig{
+		User:             "admin",
+		Password:         "qwerty",
+		Database:         "test-db",
+		DatabaseType:     SQLiteDB,
+		ConnectTimeout:   time.Millisecond * 500,
+		ConnectionParams: SQLiteDefaultParams,
+		StmtCacheSize:    10,
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &stmtCacheDB{}, db)
+	assert.NoError(t, db.Close())
+}
+
+func Test_IsInvalidStmtError(t *testing.T) {
+	assert.True(t, isInvalidStmtError(sql.ErrConnDone))
+	assert.True(t, isInvalidStmtError(errors.New("sql: statement is closed")))
+	assert.True(t, isInvalidStmtError(errors.New("pq: cached plan must not change result type")))
+	assert.False(t, isInvalidStmtError(sql.ErrNoRows))
+
+	assert.True(t, isBatchArg([]map[string]interface{}{}))
+	assert.False(t, isBatchArg([]byte{}))
+	assert.False(t, isBatchArg(&struct{}{}))
+}
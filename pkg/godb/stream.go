@@ -0,0 +1,596 @@
+package godb
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamFormat defines the file formats supported by Export and Import
+type StreamFormat string
+
+const (
+	// FormatCSV is RFC 4180 CSV with a header line holding the column names.
+	FormatCSV StreamFormat = "csv"
+	// FormatNDJSON is newline delimited JSON with one object per row.
+	FormatNDJSON StreamFormat = "ndjson"
+
+	defaultImportBatchSize = 1000
+)
+
+// ExportOptions defines the export configs
+type ExportOptions struct {
+	Format StreamFormat
+	// NoHeader skips the CSV header line.
+	NoHeader bool
+	// TimeFormat formats time values. Defaults to time.RFC3339Nano.
+	TimeFormat string
+	// NullValue is the CSV text of NULL values. Defaults to an empty string.
+	NullValue string
+}
+
+// ImportOptions defines the import configs
+type ImportOptions struct {
+	Format StreamFormat
+	// Table is the destination table. It can be qualified with its schema.
+	Table string
+	// Columns maps the CSV header or NDJSON fields to the table columns. Fields that are not
+	// mapped are skipped. When empty every CSV field is loaded into the column with the same
+	// name, and NDJSON rows are loaded using the fields of the first row.
+	Columns map[string]string
+	// BatchSize is the amount of rows inserted by each transaction. Defaults to 1000.
+	BatchSize int
+	// NullValue is the CSV text loaded as NULL. CSV values are never NULL when it is empty.
+	NullValue string
+}
+
+// Export Writes every row to w as CSV or NDJSON and returns the amount of rows written.
+// Values are formatted by their column type: numbers and booleans are written as they
+// are, binary columns are base64 encoded and JSON columns are embedded in NDJSON rows.
+// The rows are not closed.
+func Export(w io.Writer, rows Rows, options ExportOptions) (int64, error) {
+	if options.TimeFormat == "" {
+		options.TimeFormat = time.RFC3339Nano
+	}
+
+	columns, err := exportColumns(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	var writer rowWriter
+	switch options.Format {
+	case FormatCSV:
+		writer = &csvRowWriter{writer: csv.NewWriter(w), options: options}
+	case FormatNDJSON:
+		writer = &ndjsonRowWriter{writer: bufio.NewWriter(w), options: options}
+	default:
+		return 0, fmt.Errorf("%q. %w", options.Format, ErrUnsupportedFormat)
+	}
+
+	if err := writer.header(columns); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return count, err
+		}
+
+		if err := writer.row(columns, values); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, writer.flush()
+}
+
+// Import Loads the CSV or NDJSON rows read from r into a table and returns the amount of
+// rows inserted. Every batch is inserted and committed by its own transaction, so the
+// batches committed before an error are kept. Nested NDJSON objects and lists are
+// stored as JSON, and the values of binary columns are base64 decoded, as written by Export.
+func Import(ctx context.Context, db DB, r io.Reader, options ImportOptions) (int64, error) {
+	if options.Table == "" {
+		return 0, fmt.Errorf("%w. table is required", ErrInvalidImport)
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportBatchSize
+	}
+
+	var reader rowReader
+	switch options.Format {
+	case FormatCSV:
+		reader = &csvRowReader{reader: csv.NewReader(r), options: options}
+	case FormatNDJSON:
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		reader = &ndjsonRowReader{decoder: decoder, options: options}
+	default:
+		return 0, fmt.Errorf("%q. %w", options.Format, ErrUnsupportedFormat)
+	}
+
+	columns, err := reader.columns()
+	if errors.Is(err, io.EOF) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("%w. no columns to import", ErrInvalidImport)
+	}
+
+	kinds, err := importColumnKinds(ctx, db, options.Table, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	query := insertQuery(db, options.Table, columns)
+	batch := make([][]interface{}, 0, options.BatchSize)
+
+	var count int64
+	for {
+		values, err := reader.row()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err == nil {
+			err = decodeBinaryValues(kinds, values)
+		}
+
+		if err != nil {
+			return count, fmt.Errorf("row %d: %w", count+int64(len(batch))+1, err)
+		}
+
+		if batch = append(batch, values); len(batch) == options.BatchSize {
+			if err := insertBatch(ctx, db, query, batch); err != nil {
+				return count, err
+			}
+			count += int64(len(batch))
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := insertBatch(ctx, db, query, batch); err != nil {
+			return count, err
+		}
+		count += int64(len(batch))
+	}
+	return count, nil
+}
+
+// insertQuery returns the INSERT statement of the imported columns
+func insertQuery(db DB, table string, columns []string) string {
+	quotedTable, quotedColumns := quoteImportNames(db, table, columns)
+	return db.Rebind(fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		quotedTable,
+		quotedColumns,
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+	))
+}
+
+// importColumnKinds returns the kinds of the imported columns, read from the table
+func importColumnKinds(ctx context.Context, db DB, table string, columns []string) ([]columnKind, error) {
+	quotedTable, quotedColumns := quoteImportNames(db, table, columns)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", quotedColumns, quotedTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exported, err := exportColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := make([]columnKind, len(exported))
+	for index, column := range exported {
+		kinds[index] = column.kind
+	}
+	return kinds, nil
+}
+
+// quoteImportNames returns the quoted table and the quoted column list
+func quoteImportNames(db DB, table string, columns []string) (string, string) {
+	dbType, _ := driverDBType(db.DriverName())
+
+	quoted := make([]string, len(columns))
+	for index, column := range columns {
+		quoted[index] = quoteIdentifier(dbType, column)
+	}
+	return quoteQualified(dbType, table), strings.Join(quoted, ", ")
+}
+
+// decodeBinaryValues decodes the base64 values of the binary columns written by Export
+func decodeBinaryValues(kinds []columnKind, values []interface{}) error {
+	for index, value := range values {
+		text, ok := value.(string)
+		if !ok || kinds[index] != binaryColumn {
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return fmt.Errorf("%w. column %d is not base64 encoded", ErrInvalidImport, index+1)
+		}
+		values[index] = data
+	}
+	return nil
+}
+
+// insertBatch inserts a batch of rows within a transaction
+func insertBatch(ctx context.Context, db DB, query string, batch [][]interface{}) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, values := range batch {
+		if _, err = stmt.ExecContext(ctx, values...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// columnKind defines how the values of a column are formatted
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	numberColumn
+	boolColumn
+	jsonColumn
+	binaryColumn
+)
+
+// numberTypes are the prefixes of numeric type names
+var numberTypes = []string{"INT", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT", "DEC", "NUMERIC", "FLOAT", "DOUBLE", "REAL"}
+
+// exportColumn defines a column of the exported rows
+type exportColumn struct {
+	name string
+	kind columnKind
+}
+
+// exportColumns returns the columns of rows
+func exportColumns(rows Rows) ([]exportColumn, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]exportColumn, len(columnTypes))
+	for index, columnType := range columnTypes {
+		columns[index] = exportColumn{name: columnType.Name(), kind: kindOf(columnType.DatabaseTypeName())}
+	}
+	return columns, nil
+}
+
+// kindOf returns the kind of a database type name, such as "INT8" on Postgres,
+// "UNSIGNED BIGINT" on MySQL or the declared type on SQLite
+func kindOf(typeName string) columnKind {
+	typeName = strings.TrimPrefix(strings.ToUpper(typeName), "UNSIGNED ")
+	switch {
+	case strings.HasPrefix(typeName, "JSON"):
+		return jsonColumn
+	case strings.HasPrefix(typeName, "BOOL"):
+		return boolColumn
+	case strings.Contains(typeName, "BLOB"), strings.Contains(typeName, "BINARY"), typeName == "BYTEA":
+		return binaryColumn
+	case strings.HasPrefix(typeName, "INTERVAL"):
+		return textColumn
+	}
+
+	for _, prefix := range numberTypes {
+		if strings.HasPrefix(typeName, prefix) {
+			return numberColumn
+		}
+	}
+	return textColumn
+}
+
+// text formats a value as text. It returns false for NULL values.
+func (c exportColumn) text(value interface{}, timeFormat string) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case []byte:
+		if c.kind == binaryColumn {
+			return base64.StdEncoding.EncodeToString(v), true
+		}
+		return string(v), true
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case time.Time:
+		return v.Format(timeFormat), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// json formats a value as JSON
+func (c exportColumn) json(value interface{}, timeFormat string) ([]byte, error) {
+	text, ok := c.text(value, timeFormat)
+	if !ok {
+		return []byte("null"), nil
+	}
+
+	switch value.(type) {
+	case int64, float64, bool:
+		return []byte(text), nil
+	}
+
+	switch c.kind {
+	case numberColumn:
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return []byte(text), nil
+		}
+	case boolColumn:
+		if flag, err := strconv.ParseBool(text); err == nil {
+			return []byte(strconv.FormatBool(flag)), nil
+		}
+	case jsonColumn:
+		if json.Valid([]byte(text)) {
+			return []byte(text), nil
+		}
+	}
+	return json.Marshal(text)
+}
+
+// rowWriter defines the writers of exported rows
+type rowWriter interface {
+	header(columns []exportColumn) error
+	row(columns []exportColumn, values []interface{}) error
+	flush() error
+}
+
+// csvRowWriter writes CSV rows
+type csvRowWriter struct {
+	writer  *csv.Writer
+	options ExportOptions
+	record  []string
+}
+
+// header
+func (c *csvRowWriter) header(columns []exportColumn) error {
+	c.record = make([]string, len(columns))
+	if c.options.NoHeader {
+		return nil
+	}
+
+	for index, column := range columns {
+		c.record[index] = column.name
+	}
+	return c.writer.Write(c.record)
+}
+
+// row
+func (c *csvRowWriter) row(columns []exportColumn, values []interface{}) error {
+	for index, column := range columns {
+		text, ok := column.text(values[index], c.options.TimeFormat)
+		if !ok {
+			text = c.options.NullValue
+		}
+		c.record[index] = text
+	}
+	return c.writer.Write(c.record)
+}
+
+// flush
+func (c *csvRowWriter) flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// ndjsonRowWriter writes NDJSON rows
+type ndjsonRowWriter struct {
+	writer  *bufio.Writer
+	options ExportOptions
+	keys    [][]byte
+}
+
+// header
+func (n *ndjsonRowWriter) header(columns []exportColumn) error {
+	n.keys = make([][]byte, len(columns))
+	for index, column := range columns {
+		key, err := json.Marshal(column.name)
+		if err != nil {
+			return err
+		}
+		n.keys[index] = key
+	}
+	return nil
+}
+
+// row writes the columns in the query order
+func (n *ndjsonRowWriter) row(columns []exportColumn, values []interface{}) error {
+	_ = n.writer.WriteByte('{')
+	for index, column := range columns {
+		value, err := column.json(values[index], n.options.TimeFormat)
+		if err != nil {
+			return err
+		}
+
+		if index > 0 {
+			_ = n.writer.WriteByte(',')
+		}
+		_, _ = n.writer.Write(n.keys[index])
+		_ = n.writer.WriteByte(':')
+		_, _ = n.writer.Write(value)
+	}
+	_, err := n.writer.WriteString("}\n")
+	return err
+}
+
+// flush
+func (n *ndjsonRowWriter) flush() error {
+	return n.writer.Flush()
+}
+
+// rowReader defines the readers of imported rows
+type rowReader interface {
+	// columns returns the table columns of the imported values or io.EOF if there are no rows
+	columns() ([]string, error)
+	// row returns the values of the next row or io.EOF
+	row() ([]interface{}, error)
+}
+
+// csvRowReader reads CSV rows
+type csvRowReader struct {
+	reader  *csv.Reader
+	options ImportOptions
+	// fields are the indexes of the imported CSV fields
+	fields []int
+}
+
+// columns
+func (c *csvRowReader) columns() ([]string, error) {
+	header, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{}
+	for index, field := range header {
+		column := field
+		if len(c.options.Columns) > 0 {
+			var ok bool
+			if column, ok = c.options.Columns[field]; !ok {
+				continue
+			}
+		}
+
+		c.fields = append(c.fields, index)
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// row
+func (c *csvRowReader) row() ([]interface{}, error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(c.fields))
+	for index, field := range c.fields {
+		if c.options.NullValue != "" && record[field] == c.options.NullValue {
+			continue
+		}
+		values[index] = record[field]
+	}
+	return values, nil
+}
+
+// ndjsonRowReader reads NDJSON rows
+type ndjsonRowReader struct {
+	decoder *json.Decoder
+	options ImportOptions
+	// fields are the names of the imported NDJSON fields
+	fields []string
+	// first is the first row, read to find the fields when there is no column mapping
+	first map[string]interface{}
+}
+
+// columns
+func (n *ndjsonRowReader) columns() ([]string, error) {
+	if len(n.options.Columns) == 0 {
+		if err := n.decoder.Decode(&n.first); err != nil {
+			return nil, err
+		}
+
+		for field := range n.first {
+			n.fields = append(n.fields, field)
+		}
+		sort.Strings(n.fields)
+		return n.fields, nil
+	}
+
+	for field := range n.options.Columns {
+		n.fields = append(n.fields, field)
+	}
+	sort.Strings(n.fields)
+
+	columns := make([]string, len(n.fields))
+	for index, field := range n.fields {
+		columns[index] = n.options.Columns[field]
+	}
+	return columns, nil
+}
+
+// row
+func (n *ndjsonRowReader) row() ([]interface{}, error) {
+	data := n.first
+	if data != nil {
+		n.first = nil
+	} else if err := n.decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(n.fields))
+	for index, field := range n.fields {
+		value, err := importValue(data[field])
+		if err != nil {
+			return nil, err
+		}
+		values[index] = value
+	}
+	return values, nil
+}
+
+// importValue converts a decoded NDJSON value into a query argument
+func importValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if number, err := v.Int64(); err == nil {
+			return number, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,234 @@
+package godb
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Export(t *testing.T) {
+	db := newSQLiteTestDB(t, `
+		CREATE TABLE custom_table (
+			id INTEGER PRIMARY KEY,
+			name TEXT,
+			score REAL,
+			active BOOLEAN,
+			data JSON,
+			payload BLOB,
+			created DATETIME
+		);
+		INSERT INTO custom_table VALUES (1, 'John "JD" Doe', 9.5, 1, '{"tags": ["a"]}', x'0102', '2024-01-02 03:04:05');
+		INSERT INTO custom_table (id, name) VALUES (2, '12');
+	`)
+
+	tests := []struct {
+		name     string
+		options  ExportOptions
+		expected string
+	}{
+		{
+			name:    "should export CSV",
+			options: ExportOptions{Format: FormatCSV, NullValue: "NULL"},
+			expected: "id,name,score,active,data,payload,created\n" +
+				`1,"John ""JD"" Doe",9.5,true,"{""tags"": [""a""]}",AQI=,2024-01-02T03:04:05Z` + "\n" +
+				"2,12,NULL,NULL,NULL,NULL,NULL\n",
+		},
+		{
+			name:    "should export CSV without header",
+			options: ExportOptions{Format: FormatCSV, NoHeader: true, TimeFormat: "2006-01-02"},
+			expected: `1,"John ""JD"" Doe",9.5,true,"{""tags"": [""a""]}",AQI=,2024-01-02` + "\n" +
+				"2,12,,,,,\n",
+		},
+		{
+			name:    "should export NDJSON",
+			options: ExportOptions{Format: FormatNDJSON},
+			expected: `{"id":1,"name":"John \"JD\" Doe","score":9.5,"active":true,"data":{"tags": ["a"]},"payload":"AQI=","created":"2024-01-02T03:04:05Z"}` + "\n" +
+				`{"id":2,"name":"12","score":null,"active":null,"data":null,"payload":null,"created":null}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := db.Query("SELECT * FROM custom_table ORDER BY id")
+			assert.NoError(t, err)
+			defer rows.Close()
+
+			var buffer bytes.Buffer
+			count, err := Export(&buffer, rows, test.options)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, test.expected, buffer.String())
+		})
+	}
+
+	rows, err := db.Query("SELECT * FROM custom_table")
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	_, err = Export(&bytes.Buffer{}, rows, ExportOptions{Format: "xml"})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func Test_Import(t *testing.T) {
+	type customData struct {
+		ID    int          `db:"id"`
+		Name  string       `db:"name"`
+		Email Null[string] `db:"email"`
+	}
+
+	ddl := "CREATE TABLE custom_table (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT)"
+
+	tests := []struct {
+		name     string
+		input    string
+		options  ImportOptions
+		count    int64
+		err      error
+		expected []customData
+	}{
+		{
+			name:    "should import CSV",
+			input:   "id,name,email\n1,John Doe,john@doe.com\n2,Jane Doe,-\n3,Jim Doe,\n",
+			options: ImportOptions{Format: FormatCSV, Table: "custom_table", NullValue: "-", BatchSize: 2},
+			count:   3,
+			expected: []customData{
+				{ID: 1, Name: "John Doe", Email: NewNull("john@doe.com")},
+				{ID: 2, Name: "Jane Doe"},
+				{ID: 3, Name: "Jim Doe", Email: NewNull("")},
+			},
+		},
+		{
+			name:  "should import mapped CSV columns",
+			input: "code,full_name,age\n1,John Doe,42\n",
+			options: ImportOptions{
+				Format:  FormatCSV,
+				Table:   "main.custom_table",
+				Columns: map[string]string{"code": "id", "full_name": "name"},
+			},
+			count:    1,
+			expected: []customData{{ID: 1, Name: "John Doe"}},
+		},
+		{
+			name:     "should import NDJSON",
+			input:    `{"id": 1, "name": "John Doe", "email": null}` + "\n" + `{"id": 2, "name": "Jane Doe", "email": "jane@doe.com"}`,
+			options:  ImportOptions{Format: FormatNDJSON, Table: "custom_table"},
+			count:    2,
+			expected: []customData{{ID: 1, Name: "John Doe"}, {ID: 2, Name: "Jane Doe", Email: NewNull("jane@doe.com")}},
+		},
+		{
+			name:  "should import mapped NDJSON fields",
+			input: `{"code": 1, "profile": {"name": "John"}}`,
+			options: ImportOptions{
+				Format:  FormatNDJSON,
+				Table:   "custom_table",
+				Columns: map[string]string{"code": "id", "profile": "name"},
+			},
+			count:    1,
+			expected: []customData{{ID: 1, Name: `{"name":"John"}`}},
+		},
+		{
+			name:     "should import nothing from empty input",
+			options:  ImportOptions{Format: FormatNDJSON, Table: "custom_table"},
+			expected: []customData{},
+		},
+		{
+			name:     "should keep the committed batches",
+			input:    "id,name\n1,John Doe\n2,Jane Doe\n2,Jim Doe\n",
+			options:  ImportOptions{Format: FormatCSV, Table: "custom_table", BatchSize: 2},
+			count:    2,
+			err:      assert.AnError,
+			expected: []customData{{ID: 1, Name: "John Doe"}, {ID: 2, Name: "Jane Doe"}},
+		},
+		{
+			name:     "should report invalid rows",
+			input:    "{\"id\": 1, \"name\": \"John Doe\"}\n{\"id\": 2,",
+			options:  ImportOptions{Format: FormatNDJSON, Table: "custom_table"},
+			err:      assert.AnError,
+			expected: []customData{},
+		},
+		{
+			name:     "should require mapped columns",
+			input:    "code\n1\n",
+			options:  ImportOptions{Format: FormatCSV, Table: "custom_table", Columns: map[string]string{"id": "id"}},
+			err:      ErrInvalidImport,
+			expected: []customData{},
+		},
+		{
+			name:     "should require a table",
+			options:  ImportOptions{Format: FormatCSV},
+			err:      ErrInvalidImport,
+			expected: []customData{},
+		},
+		{
+			name:     "should reject unknown formats",
+			options:  ImportOptions{Format: "xml", Table: "custom_table"},
+			err:      ErrUnsupportedFormat,
+			expected: []customData{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newSQLiteTestDB(t, ddl)
+
+			count, err := Import(context.Background(), db, strings.NewReader(test.input), test.options)
+			switch test.err {
+			case nil:
+				assert.NoError(t, err)
+			case assert.AnError:
+				assert.Error(t, err)
+			default:
+				assert.ErrorIs(t, err, test.err)
+			}
+			assert.Equal(t, test.count, count)
+
+			list := []customData{}
+			assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+			assert.Equal(t, test.expected, list)
+		})
+	}
+}
+
+func Test_ExportImportRoundTrip(t *testing.T) {
+	type customData struct {
+		ID      int    `db:"id"`
+		Payload []byte `db:"payload"`
+	}
+
+	ddl := "CREATE TABLE custom_table (id INTEGER PRIMARY KEY, payload BLOB)"
+	expected := []customData{{ID: 1, Payload: []byte{0x00, 0xff, 'a', '\n'}}, {ID: 2}}
+
+	for _, format := range []StreamFormat{FormatCSV, FormatNDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			source := newSQLiteTestDB(t, ddl)
+			for _, data := range expected {
+				_, err := source.NamedExec("INSERT INTO custom_table (id, payload) VALUES (:id, :payload)", data)
+				assert.NoError(t, err)
+			}
+
+			rows, err := source.Query("SELECT * FROM custom_table ORDER BY id")
+			assert.NoError(t, err)
+			defer rows.Close()
+
+			var buffer bytes.Buffer
+			_, err = Export(&buffer, rows, ExportOptions{Format: format, NullValue: "-"})
+			assert.NoError(t, err)
+
+			target := newSQLiteTestDB(t, ddl)
+			count, err := Import(context.Background(), target, &buffer, ImportOptions{Format: format, Table: "custom_table", NullValue: "-"})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), count)
+
+			list := []customData{}
+			assert.NoError(t, target.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+			assert.Equal(t, expected, list, "binary columns should be decoded")
+		})
+	}
+
+	db := newSQLiteTestDB(t, ddl)
+	_, err := Import(context.Background(), db, strings.NewReader("id,payload\n1,not base64!\n"), ImportOptions{Format: FormatCSV, Table: "custom_table"})
+	assert.ErrorIs(t, err, ErrInvalidImport)
+}
@@ -0,0 +1,940 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// TenantDatabaseMode routes each tenant to its own connection pool
+	TenantDatabaseMode TenantMode = iota + 1
+	// TenantSchemaMode selects the tenant schema on a connection checked out from a shared pool
+	TenantSchemaMode
+)
+
+const (
+	// TenantContextKey is the gocontext key read by the default tenant resolver
+	TenantContextKey = "tenant-id"
+
+	defaultTenantIdleTimeout = time.Minute * 10
+)
+
+type TenantMode uint
+
+// TenantConfig defines the multi-tenant database configs
+type TenantConfig struct {
+	Mode TenantMode
+	// DatabaseType is the type of every tenant database.
+	DatabaseType DBType
+	// Resolver returns the tenant of a context.
+	// Defaults to the gocontext value stored under TenantContextKey, see WithTenant.
+	Resolver func(ctx context.Context) (string, bool)
+
+	// DBConfig returns the connection config of a tenant. Required by TenantDatabaseMode.
+	DBConfig func(tenant string) (DBConfig, error)
+	// OnOpen is called after the pool of a tenant is opened. Used by TenantDatabaseMode.
+	OnOpen func(tenant string, db DB)
+	// IdleTimeout is how long an unused tenant pool is kept open. Used by TenantDatabaseMode.
+	// Defaults to ten minutes.
+	IdleTimeout time.Duration
+
+	// DB is the shared database. Required by TenantSchemaMode.
+	DB DB
+	// Schema returns the schema of a tenant. Used by TenantSchemaMode.
+	// Defaults to the tenant itself.
+	Schema func(tenant string) string
+}
+
+// TenantDB defines a database that routes every query to the tenant found in the context.
+//
+// Queries without a tenant fail with ErrNoTenant. Since the methods without a context use
+// context.Background, they only work when the Resolver returns a default tenant.
+// In TenantDatabaseMode Safe and Unsafe return nil, since there is no single underlying database.
+type TenantDB interface {
+	DB
+
+	// Tenants returns the tenants with an open connection pool.
+	Tenants() []string
+	// CloseTenant closes the connection pool of a tenant.
+	CloseTenant(tenant string) error
+}
+
+// WithTenant Adds the tenant into the context using gocontext
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return gocontext.Add(ctx, TenantContextKey, tenant)
+}
+
+// tenantFromContext is the default tenant resolver
+func tenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := gocontext.Get[string](ctx, TenantContextKey)
+	return tenant, ok && tenant != ""
+}
+
+// NewTenantDB Creates a new tenant-aware database.
+//
+// In TenantDatabaseMode the pool of a tenant is opened on its first query and closed after
+// being idle for TenantConfig.IdleTimeout. In TenantSchemaMode every operation checks out a
+// connection from the shared database and selects the tenant schema on it (search_path on
+// Postgres, USE on MySQL) until the connection, rows, statement or transaction is closed.
+// The schema is then reset, or the connection discarded when it can not be reset, such as a
+// MySQL connection without a default database.
+//
+// Like sql.Row, a Row returned by QueryRow holds its tenant until it is consumed by Scan,
+// MapScan, SliceScan, StructScan or Err, so it must not be dropped. Err releases the tenant
+// unless the row can still be scanned from a schema mode connection.
+func NewTenantDB(config TenantConfig) (TenantDB, error) {
+	if !config.DatabaseType.isValid() {
+		return nil, ErrInvalidDBType
+	}
+
+	if config.Resolver == nil {
+		config.Resolver = tenantFromContext
+	}
+
+	tdb := &tenantDB{
+		config:   config,
+		pools:    map[string]*tenantPool{},
+		settings: map[string]func(db DB){},
+		done:     make(chan struct{}),
+	}
+
+	switch config.Mode {
+	case TenantDatabaseMode:
+		if config.DBConfig == nil {
+			return nil, fmt.Errorf("%w. DBConfig is required", ErrInvalidTenantConfig)
+		}
+
+		if tdb.config.IdleTimeout <= 0 {
+			tdb.config.IdleTimeout = defaultTenantIdleTimeout
+		}
+		go tdb.evictIdleLoop()
+	case TenantSchemaMode:
+		if config.DB == nil {
+			return nil, fmt.Errorf("%w. DB is required", ErrInvalidTenantConfig)
+		}
+
+		if config.DatabaseType != PostgresDB && config.DatabaseType != MySQLDB {
+			return nil, fmt.Errorf("%w. schema mode requires Postgres or MySQL", ErrInvalidTenantConfig)
+		}
+
+		if config.Schema == nil {
+			tdb.config.Schema = func(tenant string) string { return tenant }
+		}
+	default:
+		return nil, fmt.Errorf("%w. invalid mode", ErrInvalidTenantConfig)
+	}
+
+	return tdb, nil
+}
+
+// tenantTarget defines the operations routed to a tenant
+type tenantTarget interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	NamedQueryContext(ctx context.Context, query string, arg interface{}) (Rows, error)
+	PingContext(ctx context.Context) error
+	PrepareContext(ctx context.Context, query string) (Stmt, error)
+	PrepareNamedContext(ctx context.Context, query string) (NamedStmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) Row
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// tenantPool defines the connection pool of a tenant
+type tenantPool struct {
+	db       DB
+	err      error
+	ready    chan struct{}
+	active   int
+	lastUsed time.Time
+}
+
+// isReady checks if the pool finished opening
+func (tp *tenantPool) isReady() bool {
+	select {
+	case <-tp.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// tenantDB implements the TenantDB interface
+type tenantDB struct {
+	config    TenantConfig
+	mu        sync.Mutex
+	pools     map[string]*tenantPool
+	settings  map[string]func(db DB)
+	hooks     queryHooks
+	done      chan struct{}
+	closeOnce sync.Once
+	testErr   error
+}
+
+// acquire returns the target of the tenant found in ctx and a function releasing it
+func (tdb *tenantDB) acquire(ctx context.Context) (tenantTarget, func() error, error) {
+	tenant, ok := tdb.config.Resolver(ctx)
+	if !ok || tenant == "" {
+		return nil, nil, ErrNoTenant
+	}
+
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.acquireSchema(ctx, tenant)
+	}
+	return tdb.acquirePool(tenant)
+}
+
+// acquirePool returns the pool of tenant, opening it if needed
+func (tdb *tenantDB) acquirePool(tenant string) (tenantTarget, func() error, error) {
+	tdb.mu.Lock()
+	pool, ok := tdb.pools[tenant]
+	if !ok {
+		pool = &tenantPool{ready: make(chan struct{})}
+		tdb.pools[tenant] = pool
+	}
+	pool.active++
+	pool.lastUsed = time.Now()
+	tdb.mu.Unlock()
+
+	if !ok {
+		tdb.openPool(tenant, pool)
+	}
+	<-pool.ready
+
+	release := func() error {
+		tdb.mu.Lock()
+		defer tdb.mu.Unlock()
+		pool.active--
+		pool.lastUsed = time.Now()
+		return nil
+	}
+
+	if pool.err != nil {
+		_ = release()
+		return nil, nil, pool.err
+	}
+	return pool.db, release, nil
+}
+
+// openPool opens the pool of a tenant
+func (tdb *tenantDB) openPool(tenant string, pool *tenantPool) {
+	defer close(pool.ready)
+
+	config, err := tdb.config.DBConfig(tenant)
+	if err == nil {
+		pool.db, err = NewDB(config)
+	}
+
+	if err != nil {
+		pool.err = err
+		tdb.mu.Lock()
+		if tdb.pools[tenant] == pool {
+			delete(tdb.pools, tenant)
+		}
+		tdb.mu.Unlock()
+		return
+	}
+
+	tdb.mu.Lock()
+	for _, apply := range tdb.settings {
+		apply(pool.db)
+	}
+	pool.db.AddHook(tdb.hooks.hooks...)
+	tdb.mu.Unlock()
+
+	if tdb.config.OnOpen != nil {
+		tdb.config.OnOpen(tenant, pool.db)
+	}
+}
+
+// acquireSchema checks out a connection and selects the tenant schema on it
+func (tdb *tenantDB) acquireSchema(ctx context.Context, tenant string) (tenantTarget, func() error, error) {
+	conn, err := tdb.config.DB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schema := quoteIdentifier(tdb.config.DatabaseType, tdb.config.Schema(tenant))
+	statement := fmt.Sprintf("SET search_path TO %s", schema)
+	reset := "RESET search_path"
+	if tdb.config.DatabaseType == MySQLDB {
+		statement = fmt.Sprintf("USE %s", schema)
+
+		// MySQL can not reset USE, so the default database is selected again on release
+		var database sql.NullString
+		if err = conn.GetContext(ctx, &database, "SELECT DATABASE()"); err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+
+		reset = ""
+		if database.Valid {
+			reset = fmt.Sprintf("USE %s", quoteIdentifier(MySQLDB, database.String))
+		}
+	}
+
+	if _, err = conn.ExecContext(ctx, statement); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+
+	release := func() error {
+		discard := reset == ""
+		if !discard {
+			_, err := conn.ExecContext(context.Background(), reset)
+			discard = err != nil
+		}
+
+		if discard {
+			// Discard the connection so the tenant schema is not leaked to the pool
+			_ = conn.Raw(func(driverConn any) error { return driver.ErrBadConn })
+		}
+		return conn.Close()
+	}
+	return &schemaConn{Conn: conn, db: tdb.config.DB}, release, nil
+}
+
+// evictIdleLoop closes the idle tenant pools until the database is closed
+func (tdb *tenantDB) evictIdleLoop() {
+	ticker := time.NewTicker(tdb.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tdb.done:
+			return
+		case <-ticker.C:
+			tdb.evictIdle()
+		}
+	}
+}
+
+// evictIdle closes the pools unused for longer than the idle timeout
+func (tdb *tenantDB) evictIdle() {
+	now := time.Now()
+	idle := []DB{}
+
+	tdb.mu.Lock()
+	for tenant, pool := range tdb.pools {
+		if !pool.isReady() || pool.active > 0 || now.Sub(pool.lastUsed) < tdb.config.IdleTimeout {
+			continue
+		}
+		delete(tdb.pools, tenant)
+		idle = append(idle, pool.db)
+	}
+	tdb.mu.Unlock()
+
+	for _, db := range idle {
+		_ = db.Close()
+	}
+}
+
+// applySetting applies a pool setting to the open pools and to the ones opened later
+func (tdb *tenantDB) applySetting(name string, apply func(db DB)) {
+	if tdb.config.Mode == TenantSchemaMode {
+		apply(tdb.config.DB)
+		return
+	}
+
+	tdb.mu.Lock()
+	defer tdb.mu.Unlock()
+
+	tdb.settings[name] = apply
+	for _, pool := range tdb.pools {
+		if pool.isReady() && pool.err == nil {
+			apply(pool.db)
+		}
+	}
+}
+
+// readyPools returns the open pools by tenant
+func (tdb *tenantDB) readyPools() map[string]DB {
+	tdb.mu.Lock()
+	defer tdb.mu.Unlock()
+
+	pools := map[string]DB{}
+	for tenant, pool := range tdb.pools {
+		if pool.isReady() && pool.err == nil {
+			pools[tenant] = pool.db
+		}
+	}
+	return pools
+}
+
+// run calls fn with the target of the tenant found in ctx
+func (tdb *tenantDB) run(ctx context.Context, fn func(target tenantTarget) error) error {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = release()
+	}()
+	return fn(target)
+}
+
+// Tenants
+func (tdb *tenantDB) Tenants() []string {
+	tenants := []string{}
+	for tenant := range tdb.readyPools() {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// CloseTenant
+func (tdb *tenantDB) CloseTenant(tenant string) error {
+	tdb.mu.Lock()
+	pool, ok := tdb.pools[tenant]
+	if ok && pool.isReady() {
+		delete(tdb.pools, tenant)
+	}
+	tdb.mu.Unlock()
+
+	if !ok || !pool.isReady() || pool.err != nil {
+		return nil
+	}
+	return pool.db.Close()
+}
+
+// pushTestError
+func (tdb *tenantDB) pushTestError(err error) {
+	tdb.testErr = err
+}
+
+// popTestError
+func (tdb *tenantDB) popTestError() error {
+	err := tdb.testErr
+	tdb.testErr = nil
+	return err
+}
+
+// Close
+func (tdb *tenantDB) Close() error {
+	tdb.closeOnce.Do(func() {
+		close(tdb.done)
+	})
+
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.config.DB.Close()
+	}
+
+	errs := []error{}
+	for tenant := range tdb.readyPools() {
+		errs = append(errs, tdb.CloseTenant(tenant))
+	}
+	return errors.Join(errs...)
+}
+
+// Driver
+func (tdb *tenantDB) Driver() driver.Driver {
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.config.DB.Driver()
+	}
+
+	// sql.Open does not connect, it only looks up the registered driver
+	db, err := sql.Open(tdb.DriverName(), "")
+	if err != nil {
+		return nil
+	}
+	defer db.Close()
+	return db.Driver()
+}
+
+// Exec
+func (tdb *tenantDB) Exec(query string, args ...any) (sql.Result, error) {
+	return tdb.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext
+func (tdb *tenantDB) ExecContext(ctx context.Context, query string, args ...any) (result sql.Result, err error) {
+	err = tdb.run(ctx, func(target tenantTarget) error {
+		result, err = target.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// Ping
+func (tdb *tenantDB) Ping() error {
+	return tdb.PingContext(context.Background())
+}
+
+// PingContext
+func (tdb *tenantDB) PingContext(ctx context.Context) error {
+	return tdb.run(ctx, func(target tenantTarget) error {
+		return target.PingContext(ctx)
+	})
+}
+
+// SetConnMaxIdleTime
+func (tdb *tenantDB) SetConnMaxIdleTime(d time.Duration) {
+	tdb.applySetting("conn-max-idle-time", func(db DB) { db.SetConnMaxIdleTime(d) })
+}
+
+// SetConnMaxLifetime
+func (tdb *tenantDB) SetConnMaxLifetime(d time.Duration) {
+	tdb.applySetting("conn-max-lifetime", func(db DB) { db.SetConnMaxLifetime(d) })
+}
+
+// SetMaxIdleConns
+func (tdb *tenantDB) SetMaxIdleConns(n int) {
+	tdb.applySetting("max-idle-conns", func(db DB) { db.SetMaxIdleConns(n) })
+}
+
+// SetMaxOpenConns
+func (tdb *tenantDB) SetMaxOpenConns(n int) {
+	tdb.applySetting("max-open-conns", func(db DB) { db.SetMaxOpenConns(n) })
+}
+
+// Stats returns the sum of the stats of every open tenant pool
+func (tdb *tenantDB) Stats() sql.DBStats {
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.config.DB.Stats()
+	}
+
+	stats := sql.DBStats{}
+	for _, db := range tdb.readyPools() {
+		poolStats := db.Stats()
+		stats.MaxOpenConnections += poolStats.MaxOpenConnections
+		stats.OpenConnections += poolStats.OpenConnections
+		stats.InUse += poolStats.InUse
+		stats.Idle += poolStats.Idle
+		stats.WaitCount += poolStats.WaitCount
+		stats.WaitDuration += poolStats.WaitDuration
+		stats.MaxIdleClosed += poolStats.MaxIdleClosed
+		stats.MaxIdleTimeClosed += poolStats.MaxIdleTimeClosed
+		stats.MaxLifetimeClosed += poolStats.MaxLifetimeClosed
+	}
+	return stats
+}
+
+// BeginTx
+func (tdb *tenantDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &customTx{}, err
+	}
+
+	tx, err := target.BeginTx(ctx, opts)
+	if err != nil {
+		_ = release()
+		return tx, err
+	}
+	return &tenantTx{Tx: tx, releaser: newReleaser(release)}, nil
+}
+
+// Begin
+func (tdb *tenantDB) Begin() (Tx, error) {
+	return tdb.BeginTx(context.Background(), nil)
+}
+
+// BindNamed
+func (tdb *tenantDB) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.config.DB.BindNamed(query, arg)
+	}
+	return sqlx.BindNamed(sqlx.BindType(tdb.DriverName()), query, arg)
+}
+
+// Conn
+func (tdb *tenantDB) Conn(ctx context.Context) (Conn, error) {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &customConn{}, err
+	}
+
+	switch target := target.(type) {
+	case *schemaConn:
+		return &tenantConn{Conn: target.Conn, releaser: newReleaser(release)}, nil
+	case DB:
+		conn, err := target.Conn(ctx)
+		if err != nil {
+			_ = release()
+			return conn, err
+		}
+
+		return &tenantConn{Conn: conn, releaser: newReleaser(func() error {
+			return errors.Join(conn.Close(), release())
+		})}, nil
+	default:
+		_ = release()
+		return &customConn{}, ErrUnsupportedOperation
+	}
+}
+
+// DriverName
+func (tdb *tenantDB) DriverName() string {
+	return tdb.config.DatabaseType.String()
+}
+
+// Get
+func (tdb *tenantDB) Get(dest interface{}, query string, args ...interface{}) error {
+	return tdb.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext
+func (tdb *tenantDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tdb.run(ctx, func(target tenantTarget) error {
+		return target.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// AddHook
+func (tdb *tenantDB) AddHook(hooks ...QueryHook) {
+	if tdb.config.Mode == TenantSchemaMode {
+		tdb.config.DB.AddHook(hooks...)
+		return
+	}
+
+	tdb.mu.Lock()
+	defer tdb.mu.Unlock()
+
+	tdb.hooks = tdb.hooks.with(hooks...)
+	for _, pool := range tdb.pools {
+		if pool.isReady() && pool.err == nil {
+			pool.db.AddHook(hooks...)
+		}
+	}
+}
+
+// MapperFunc
+func (tdb *tenantDB) MapperFunc(mf func(string) string) {
+	tdb.applySetting("mapper-func", func(db DB) { db.MapperFunc(mf) })
+}
+
+// MustBegin
+func (tdb *tenantDB) MustBegin() Tx {
+	return tdb.MustBeginTx(context.Background(), nil)
+}
+
+// MustBeginTx
+func (tdb *tenantDB) MustBeginTx(ctx context.Context, opts *sql.TxOptions) Tx {
+	tx, err := tdb.BeginTx(ctx, opts)
+	if err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// MustExec
+func (tdb *tenantDB) MustExec(query string, args ...interface{}) sql.Result {
+	return tdb.MustExecContext(context.Background(), query, args...)
+}
+
+// MustExecContext
+func (tdb *tenantDB) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
+	result, err := tdb.ExecContext(ctx, query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// NamedExec
+func (tdb *tenantDB) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return tdb.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext
+func (tdb *tenantDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (result sql.Result, err error) {
+	err = tdb.run(ctx, func(target tenantTarget) error {
+		result, err = target.NamedExecContext(ctx, query, arg)
+		return err
+	})
+	return result, err
+}
+
+// NamedQuery
+func (tdb *tenantDB) NamedQuery(query string, arg interface{}) (Rows, error) {
+	return tdb.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext
+func (tdb *tenantDB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (Rows, error) {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &customRows{}, err
+	}
+
+	rows, err := target.NamedQueryContext(ctx, query, arg)
+	if err != nil {
+		_ = release()
+		return rows, err
+	}
+	return &tenantRows{Rows: rows, releaser: newReleaser(release)}, nil
+}
+
+// PrepareNamed
+func (tdb *tenantDB) PrepareNamed(query string) (NamedStmt, error) {
+	return tdb.PrepareNamedContext(context.Background(), query)
+}
+
+// PrepareNamedContext
+func (tdb *tenantDB) PrepareNamedContext(ctx context.Context, query string) (NamedStmt, error) {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &customNamedStmt{}, err
+	}
+
+	namedStmt, err := target.PrepareNamedContext(ctx, query)
+	if err != nil {
+		_ = release()
+		return namedStmt, err
+	}
+	return &tenantNamedStmt{NamedStmt: namedStmt, releaser: newReleaser(release)}, nil
+}
+
+// Prepare
+func (tdb *tenantDB) Prepare(query string) (Stmt, error) {
+	return tdb.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext
+func (tdb *tenantDB) PrepareContext(ctx context.Context, query string) (Stmt, error) {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &customStmt{}, err
+	}
+
+	stmt, err := target.PrepareContext(ctx, query)
+	if err != nil {
+		_ = release()
+		return stmt, err
+	}
+	return &tenantStmt{Stmt: stmt, releaser: newReleaser(release)}, nil
+}
+
+// QueryRow
+func (tdb *tenantDB) QueryRow(query string, args ...interface{}) Row {
+	return tdb.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext
+func (tdb *tenantDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &errorRow{err: err}
+	}
+	return &tenantRow{
+		Row:      target.QueryRowContext(ctx, query, args...),
+		releaser: newReleaser(release),
+		// The row is read from the connection released to the shared database
+		holdsConn: tdb.config.Mode == TenantSchemaMode,
+	}
+}
+
+// Query
+func (tdb *tenantDB) Query(query string, args ...interface{}) (Rows, error) {
+	return tdb.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext
+func (tdb *tenantDB) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	target, release, err := tdb.acquire(ctx)
+	if err != nil {
+		return &customRows{}, err
+	}
+
+	rows, err := target.QueryContext(ctx, query, args...)
+	if err != nil {
+		_ = release()
+		return rows, err
+	}
+	return &tenantRows{Rows: rows, releaser: newReleaser(release)}, nil
+}
+
+// Rebind
+func (tdb *tenantDB) Rebind(query string) string {
+	return sqlx.Rebind(sqlx.BindType(tdb.DriverName()), query)
+}
+
+// Select
+func (tdb *tenantDB) Select(dest interface{}, query string, args ...interface{}) error {
+	return tdb.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext
+func (tdb *tenantDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tdb.run(ctx, func(target tenantTarget) error {
+		return target.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+// Unsafe
+func (tdb *tenantDB) Unsafe() *sqlx.DB {
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.config.DB.Unsafe()
+	}
+	return nil
+}
+
+// Safe
+func (tdb *tenantDB) Safe() *sqlx.DB {
+	if tdb.config.Mode == TenantSchemaMode {
+		return tdb.config.DB.Safe()
+	}
+	return nil
+}
+
+// schemaConn adds the named operations to a connection using the shared database bind type
+type schemaConn struct {
+	Conn
+	db DB
+}
+
+// NamedExecContext
+func (sc *schemaConn) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	boundQuery, args, err := sc.db.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return sc.Conn.ExecContext(ctx, boundQuery, args...)
+}
+
+// NamedQueryContext
+func (sc *schemaConn) NamedQueryContext(ctx context.Context, query string, arg interface{}) (Rows, error) {
+	boundQuery, args, err := sc.db.BindNamed(query, arg)
+	if err != nil {
+		return &customRows{}, err
+	}
+	return sc.Conn.QueryContext(ctx, boundQuery, args...)
+}
+
+// PrepareNamedContext
+func (sc *schemaConn) PrepareNamedContext(ctx context.Context, query string) (NamedStmt, error) {
+	return &customNamedStmt{}, fmt.Errorf("%w. named statements require TenantDatabaseMode", ErrUnsupportedOperation)
+}
+
+// releaser calls a release function once
+type releaser struct {
+	once    sync.Once
+	release func() error
+}
+
+// newReleaser creates a new releaser
+func newReleaser(release func() error) *releaser {
+	return &releaser{release: release}
+}
+
+// done calls the release function if it was not called yet
+func (r *releaser) done() (err error) {
+	r.once.Do(func() {
+		err = r.release()
+	})
+	return err
+}
+
+// tenantRows releases the tenant when the rows are closed
+type tenantRows struct {
+	Rows
+	*releaser
+}
+
+// Close
+func (tr *tenantRows) Close() error {
+	return errors.Join(tr.Rows.Close(), tr.done())
+}
+
+// tenantRow releases the tenant when the row is scanned or its error checked
+type tenantRow struct {
+	Row
+	*releaser
+	holdsConn bool
+}
+
+// Err
+func (tr *tenantRow) Err() error {
+	err := tr.Row.Err()
+	if err != nil || !tr.holdsConn {
+		_ = tr.done()
+	}
+	return err
+}
+
+// Scan
+func (tr *tenantRow) Scan(dest ...interface{}) error {
+	defer tr.done()
+	return tr.Row.Scan(dest...)
+}
+
+// MapScan
+func (tr *tenantRow) MapScan(dest map[string]interface{}) error {
+	defer tr.done()
+	return tr.Row.MapScan(dest)
+}
+
+// SliceScan
+func (tr *tenantRow) SliceScan() ([]interface{}, error) {
+	defer tr.done()
+	return tr.Row.SliceScan()
+}
+
+// StructScan
+func (tr *tenantRow) StructScan(dest interface{}) error {
+	defer tr.done()
+	return tr.Row.StructScan(dest)
+}
+
+// tenantTx releases the tenant when the transaction ends
+type tenantTx struct {
+	Tx
+	*releaser
+}
+
+// Commit
+func (tt *tenantTx) Commit() error {
+	defer tt.done()
+	return tt.Tx.Commit()
+}
+
+// Rollback
+func (tt *tenantTx) Rollback() error {
+	defer tt.done()
+	return tt.Tx.Rollback()
+}
+
+// tenantStmt releases the tenant when the statement is closed
+type tenantStmt struct {
+	Stmt
+	*releaser
+}
+
+// Close
+func (ts *tenantStmt) Close() error {
+	return errors.Join(ts.Stmt.Close(), ts.done())
+}
+
+// tenantNamedStmt releases the tenant when the named statement is closed
+type tenantNamedStmt struct {
+	NamedStmt
+	*releaser
+}
+
+// Close
+func (ts *tenantNamedStmt) Close() error {
+	return errors.Join(ts.NamedStmt.Close(), ts.done())
+}
+
+// tenantConn releases the tenant when the connection is closed
+type tenantConn struct {
+	Conn
+	*releaser
+}
+
+// Close
+func (tc *tenantConn) Close() error {
+	return tc.done()
+}
@@ -0,0 +1,279 @@
+package godb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TenantDB(t *testing.T) {
+	type customData struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	newTenantDB := func(t *testing.T, idleTimeout time.Duration) TenantDB {
+		db, err := NewTenantDB(TenantConfig{
+			Mode:         TenantDatabaseMode,
+			DatabaseType: SQLiteDB,
+			IdleTimeout:  idleTimeout,
+			DBConfig: func(tenant string) (DBConfig, error) {
+				return DBConfig{
+					User:             "admin",
+					Password:         "qwerty",
+					Database:         tenant,
+					DatabaseType:     SQLiteDB,
+					ConnectTimeout:   time.Millisecond * 500,
+					ConnectionParams: SQLiteDefaultParams,
+				}, nil
+			},
+			OnOpen: func(tenant string, db DB) {
+				db.SetMaxOpenConns(1)
+				db.MustExec("CREATE TABLE custom_table (id INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL)")
+			},
+		})
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			_ = db.Close()
+		})
+		return db
+	}
+
+	tests := []struct {
+		name   string
+		assert func(t *testing.T)
+	}{
+		{
+			name: "should refuse queries without tenant",
+			assert: func(t *testing.T) {
+				db := newTenantDB(t, time.Minute)
+
+				_, err := db.Exec("DELETE FROM custom_table")
+				assert.ErrorIs(t, err, ErrNoTenant)
+
+				var data customData
+				assert.ErrorIs(t, db.Get(&data, "SELECT * FROM custom_table"), ErrNoTenant)
+				assert.ErrorIs(t, db.QueryRow("SELECT * FROM custom_table").Scan(&data), ErrNoTenant)
+				assert.ErrorIs(t, db.PingContext(WithTenant(context.Background(), "")), ErrNoTenant)
+				assert.Panics(t, func() { db.MustBegin() })
+				assert.Empty(t, db.Tenants())
+			},
+		},
+		{
+			name: "should route queries to the tenant pool",
+			assert: func(t *testing.T) {
+				db := newTenantDB(t, time.Minute)
+				ctxA := WithTenant(context.Background(), "tenant-a")
+				ctxB := WithTenant(context.Background(), "tenant-b")
+
+				_, err := db.NamedExecContext(ctxA, "INSERT INTO custom_table (id, name) VALUES (:id, :name)", customData{1, "John Doe"})
+				assert.NoError(t, err)
+
+				var count int
+				assert.NoError(t, db.GetContext(ctxA, &count, "SELECT COUNT(*) FROM custom_table"))
+				assert.Equal(t, 1, count)
+				assert.NoError(t, db.GetContext(ctxB, &count, "SELECT COUNT(*) FROM custom_table"))
+				assert.Equal(t, 0, count, "tenants should be isolated")
+				assert.Equal(t, []string{"tenant-a", "tenant-b"}, db.Tenants())
+
+				var list []customData
+				assert.NoError(t, db.SelectContext(ctxA, &list, "SELECT * FROM custom_table"))
+				assert.Equal(t, []customData{{1, "John Doe"}}, list)
+
+				rows, err := db.QueryContext(ctxA, "SELECT * FROM custom_table")
+				assert.NoError(t, err)
+				assert.Equal(t, 1, db.(*tenantDB).pools["tenant-a"].active, "rows should hold the tenant")
+				assert.NoError(t, rows.Close())
+				assert.Equal(t, 0, db.(*tenantDB).pools["tenant-a"].active)
+
+				var name string
+				assert.NoError(t, db.QueryRowContext(ctxA, "SELECT name FROM custom_table WHERE id = ?", 1).Scan(&name))
+				assert.Equal(t, "John Doe", name)
+				assert.Equal(t, 0, db.(*tenantDB).pools["tenant-a"].active)
+
+				row := db.QueryRowContext(ctxA, "SELECT name FROM custom_table WHERE id = ?", 1)
+				assert.Equal(t, 1, db.(*tenantDB).pools["tenant-a"].active, "rows should hold the tenant")
+				assert.NoError(t, row.Err())
+				assert.Equal(t, 0, db.(*tenantDB).pools["tenant-a"].active, "checking the row error should release the tenant")
+				assert.NoError(t, row.Scan(&name))
+				assert.Equal(t, "John Doe", name)
+				assert.Error(t, db.QueryRowContext(ctxA, "SELECT unknown FROM custom_table").Err())
+				assert.Equal(t, 0, db.(*tenantDB).pools["tenant-a"].active)
+
+				tx, err := db.BeginTx(ctxB, nil)
+				assert.NoError(t, err)
+				_, err = tx.Exec("INSERT INTO custom_table (id, name) VALUES (?, ?)", 2, "Jane Doe")
+				assert.NoError(t, err)
+				assert.NoError(t, tx.Commit())
+				assert.Equal(t, 0, db.(*tenantDB).pools["tenant-b"].active)
+
+				conn, err := db.Conn(ctxB)
+				assert.NoError(t, err)
+				assert.NoError(t, conn.GetContext(ctxB, &name, "SELECT name FROM custom_table WHERE id = ?", 2))
+				assert.Equal(t, "Jane Doe", name)
+				assert.NoError(t, conn.Close())
+
+				stmt, err := db.PrepareContext(ctxB, "SELECT name FROM custom_table WHERE id = ?")
+				assert.NoError(t, err)
+				assert.NoError(t, stmt.Get(&name, 2))
+				assert.NoError(t, stmt.Close())
+				assert.Equal(t, 0, db.(*tenantDB).pools["tenant-b"].active)
+
+				db.SetMaxIdleConns(1)
+				assert.Equal(t, 2, db.Stats().OpenConnections)
+				assert.Equal(t, "sqlite3", db.DriverName())
+				assert.NotNil(t, db.Driver())
+				assert.Equal(t, "SELECT ?", db.Rebind("SELECT ?"))
+
+				assert.NoError(t, db.CloseTenant("tenant-a"))
+				assert.Equal(t, []string{"tenant-b"}, db.Tenants())
+			},
+		},
+		{
+			name: "should close idle tenant pools",
+			assert: func(t *testing.T) {
+				db := newTenantDB(t, time.Millisecond*100)
+				ctx := WithTenant(context.Background(), "tenant-a")
+
+				assert.NoError(t, db.PingContext(ctx))
+				assert.Equal(t, []string{"tenant-a"}, db.Tenants())
+
+				time.Sleep(time.Millisecond * 300)
+				assert.Empty(t, db.Tenants(), "idle pool should be closed")
+
+				assert.NoError(t, db.PingContext(ctx), "pool should be opened again")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, test.assert)
+	}
+}
+
+func Test_TenantDBSchemaMode(t *testing.T) {
+	var (
+		statements []string
+		closed     int
+	)
+
+	connMock := &ConnMock{
+		CallbackExecContext: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+			statements = append(statements, query)
+			return &ResultMock{}, nil
+		},
+		CallbackClose: func() error {
+			closed++
+			return nil
+		},
+	}
+
+	dbMock := NewMockDB()
+	dbMock.CallbackConn = func(ctx context.Context) (Conn, error) {
+		return connMock, nil
+	}
+	dbMock.CallbackBindNamed = func(query string, arg interface{}) (string, []interface{}, error) {
+		return "UPDATE users SET name = $1", []interface{}{"John"}, nil
+	}
+
+	db, err := NewTenantDB(TenantConfig{
+		Mode:         TenantSchemaMode,
+		DatabaseType: PostgresDB,
+		DB:           dbMock,
+		Schema:       func(tenant string) string { return "tenant_" + tenant },
+	})
+	assert.NoError(t, err)
+
+	ctx := WithTenant(context.Background(), "acme")
+	_, err = db.ExecContext(ctx, "DELETE FROM users")
+	assert.NoError(t, err)
+	_, err = db.NamedExecContext(ctx, "UPDATE users SET name = :name", map[string]interface{}{"name": "John"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`SET search_path TO "tenant_acme"`,
+		"DELETE FROM users",
+		"RESET search_path",
+		`SET search_path TO "tenant_acme"`,
+		"UPDATE users SET name = $1",
+		"RESET search_path",
+	}, statements)
+	assert.Equal(t, 2, closed, "connections should be returned to the pool")
+
+	row := db.QueryRowContext(ctx, "SELECT name FROM users")
+	assert.NoError(t, row.Err())
+	assert.Equal(t, 2, closed, "rows that can be scanned should keep the connection")
+	assert.NoError(t, row.Scan())
+	assert.Equal(t, 3, closed)
+
+	connMock.CallbackQueryRowContext = func(ctx context.Context, query string, args ...interface{}) Row {
+		return &RowMock{Error: sql.ErrConnDone}
+	}
+	assert.ErrorIs(t, db.QueryRowContext(ctx, "SELECT name FROM users").Err(), sql.ErrConnDone)
+	assert.Equal(t, 4, closed, "failed rows should release the connection")
+
+	_, err = db.PrepareNamedContext(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+
+	_, err = NewTenantDB(TenantConfig{Mode: TenantSchemaMode, DatabaseType: SQLiteDB, DB: dbMock})
+	assert.ErrorIs(t, err, ErrInvalidTenantConfig)
+	_, err = NewTenantDB(TenantConfig{Mode: TenantDatabaseMode, DatabaseType: SQLiteDB})
+	assert.ErrorIs(t, err, ErrInvalidTenantConfig)
+	_, err = NewTenantDB(TenantConfig{DatabaseType: SQLiteDB})
+	assert.ErrorIs(t, err, ErrInvalidTenantConfig)
+	_, err = NewTenantDB(TenantConfig{})
+	assert.ErrorIs(t, err, ErrInvalidDBType)
+}
+
+func Test_TenantDBSchemaModeMySQL(t *testing.T) {
+	var (
+		current   sql.NullString
+		discarded int
+	)
+
+	// connMock keeps the current database of a pooled connection
+	connMock := &ConnMock{
+		CallbackGetContext: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+			*dest.(*sql.NullString) = current
+			return nil
+		},
+		CallbackExecContext: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+			if database, ok := strings.CutPrefix(query, "USE "); ok {
+				current = sql.NullString{String: strings.Trim(database, "`"), Valid: true}
+			}
+			return &ResultMock{}, nil
+		},
+		CallbackRaw: func(f func(driverConn any) error) error {
+			if err := f(nil); errors.Is(err, driver.ErrBadConn) {
+				discarded++
+			}
+			return nil
+		},
+		CallbackClose: func() error { return nil },
+	}
+
+	dbMock := NewMockDB()
+	dbMock.CallbackConn = func(ctx context.Context) (Conn, error) {
+		return connMock, nil
+	}
+
+	db, err := NewTenantDB(TenantConfig{Mode: TenantSchemaMode, DatabaseType: MySQLDB, DB: dbMock})
+	assert.NoError(t, err)
+
+	current = sql.NullString{String: "app", Valid: true}
+	_, err = db.ExecContext(WithTenant(context.Background(), "acme"), "DELETE FROM users")
+	assert.NoError(t, err)
+	assert.Equal(t, "app", current.String, "the default database should be selected again on release")
+	assert.Zero(t, discarded)
+
+	current = sql.NullString{}
+	_, err = db.ExecContext(WithTenant(context.Background(), "acme"), "DELETE FROM users")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, discarded, "connections without a default database should be discarded")
+}
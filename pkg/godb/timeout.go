@@ -0,0 +1,96 @@
+package godb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+const (
+	// https://www.postgresql.org/docs/current/errcodes-appendix.html
+	postgresQueryCanceled = "57014"
+	// https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+	mysqlQueryTimeout = 3024
+	// https://mariadb.com/kb/en/mariadb-error-codes/
+	mariaDBStatementTimeout = 1969
+)
+
+// queryTimeoutKey defines the context key of WithQueryTimeout
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout Returns a context that overrides the DBConfig.QueryTimeout of the
+// statements executed with it. A timeout lower or equal to zero disables it.
+// Contexts with a deadline are never changed, so WithQueryTimeout is only needed to
+// change the timeout of contexts without one.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// queryTimeoutHook applies the default query timeout to contexts without a deadline
+type queryTimeoutHook struct {
+	timeout time.Duration
+}
+
+// BeforeQuery
+func (qth queryTimeoutHook) BeforeQuery(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, nil
+	}
+
+	timeout := qth.timeout
+	if override, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+
+	if timeout <= 0 {
+		return ctx, nil
+	}
+
+	ctx, event.cancel = context.WithTimeout(ctx, timeout)
+	return ctx, nil
+}
+
+// AfterQuery
+func (qth queryTimeoutHook) AfterQuery(_ context.Context, event *QueryEvent) {
+	if event.cancel == nil {
+		return
+	}
+
+	// Rows and Row are read after the statement, so they release the timeout themselves
+	switch event.Operation {
+	case OperationQuery, OperationNamedQuery, OperationQueryRow:
+		if event.Err == nil {
+			return
+		}
+	}
+	event.cancel()
+}
+
+// timeoutError reports timeouts as ErrQueryTimeout when the context deadline expired or as
+// ErrStatementTimeout when the database canceled the statement, such as with the Postgres
+// statement_timeout or the MySQL max_execution_time. Other errors are returned as they are.
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrQueryTimeout) || errors.Is(err, ErrStatementTimeout) {
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || (ctx != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		return fmt.Errorf("%w. %w", ErrQueryTimeout, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == postgresQueryCanceled && strings.Contains(pqErr.Message, "statement timeout") {
+		return fmt.Errorf("%w. %w", ErrStatementTimeout, err)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlQueryTimeout || mysqlErr.Number == mariaDBStatementTimeout) {
+		return fmt.Errorf("%w. %w", ErrStatementTimeout, err)
+	}
+	return err
+}
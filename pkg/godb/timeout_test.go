@@ -0,0 +1,151 @@
+package godb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_QueryTimeout(t *testing.T) {
+	slowQuery := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT max(x) FROM c"
+
+	var deadlines []time.Duration
+	recorder := QueryHookFuncs{
+		Before: func(ctx context.Context, event *QueryEvent) (context.Context, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				deadlines = append(deadlines, 0)
+			} else {
+				deadlines = append(deadlines, time.Until(deadline).Round(time.Second))
+			}
+			return ctx, nil
+		},
+	}
+
+	db, err := NewDB(DBConfig{
+		User:             "admin",
+		Password:         "qwerty",
+		Database:         "test-db",
+		DatabaseType:     SQLiteDB,
+		ConnectTimeout:   time.Millisecond * 500,
+		ConnectionParams: SQLiteDefaultParams,
+		QueryTimeout:     time.Second * 10,
+		Hooks:            []QueryHook{recorder},
+	})
+	if err != nil {
+		assert.FailNow(t, "failed to create new db: %s", err.Error())
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	t.Run("should apply the timeout to contexts without a deadline", func(t *testing.T) {
+		deadlines = nil
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+		defer cancel()
+
+		var value int
+		assert.NoError(t, db.Get(&value, "SELECT 1"))
+		assert.NoError(t, db.GetContext(ctx, &value, "SELECT 1"))
+		assert.NoError(t, db.GetContext(WithQueryTimeout(context.Background(), time.Second*20), &value, "SELECT 1"))
+		assert.NoError(t, db.GetContext(WithQueryTimeout(context.Background(), 0), &value, "SELECT 1"))
+		assert.Equal(t, []time.Duration{time.Second * 10, time.Second * 30, time.Second * 20, 0}, deadlines)
+	})
+
+	t.Run("should keep rows readable after the statement", func(t *testing.T) {
+		rows, err := db.Query("SELECT 1 UNION ALL SELECT 2")
+		assert.NoError(t, err)
+
+		values := []int{}
+		for rows.Next() {
+			var value int
+			assert.NoError(t, rows.Scan(&value))
+			values = append(values, value)
+		}
+		assert.NoError(t, rows.Err())
+		assert.NoError(t, rows.Close())
+		assert.Equal(t, []int{1, 2}, values)
+
+		var value int
+		assert.NoError(t, db.QueryRow("SELECT 3").Scan(&value))
+		assert.Equal(t, 3, value)
+	})
+
+	t.Run("should report client timeouts", func(t *testing.T) {
+		ctx := WithQueryTimeout(context.Background(), time.Millisecond*50)
+
+		var value int
+		err := db.GetContext(ctx, &value, slowQuery)
+		assert.ErrorIs(t, err, ErrQueryTimeout)
+		assert.NotErrorIs(t, err, ErrStatementTimeout)
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+
+		err = tx.QueryRowContext(ctx, slowQuery).Scan(&value)
+		assert.ErrorIs(t, err, ErrQueryTimeout)
+	})
+}
+
+func Test_timeoutError(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	otherErr := errors.New("other error")
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		expected error
+	}{
+		{
+			name: "should ignore nil errors",
+		},
+		{
+			name:     "should report deadline errors as query timeouts",
+			err:      context.DeadlineExceeded,
+			expected: ErrQueryTimeout,
+		},
+		{
+			name:     "should report errors of expired contexts as query timeouts",
+			ctx:      expired,
+			err:      &pq.Error{Code: postgresQueryCanceled, Message: "canceling statement due to user request"},
+			expected: ErrQueryTimeout,
+		},
+		{
+			name:     "should report the Postgres statement_timeout as statement timeouts",
+			ctx:      context.Background(),
+			err:      &pq.Error{Code: postgresQueryCanceled, Message: "canceling statement due to statement timeout"},
+			expected: ErrStatementTimeout,
+		},
+		{
+			name:     "should report the MySQL max_execution_time as statement timeouts",
+			err:      &mysql.MySQLError{Number: mysqlQueryTimeout},
+			expected: ErrStatementTimeout,
+		},
+		{
+			name:     "should keep other errors",
+			ctx:      context.Background(),
+			err:      otherErr,
+			expected: otherErr,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := timeoutError(test.ctx, test.err)
+			if test.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, test.expected)
+			assert.ErrorIs(t, err, test.err, "the original error should be kept")
+		})
+	}
+}
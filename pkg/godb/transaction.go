@@ -91,10 +91,10 @@ type Tx interface {
 	// Any placeholder parameters are replaced with supplied args.
 	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	// Stmt returns a version of the prepared statement which runs within a transaction.  Provided
-	// stmt can be either *sql.Stmt or *sqlx.Stmt.
+	// stmt can be either *sql.Stmt, *sqlx.Stmt or godb.Stmt.
 	Stmt(stmt interface{}) Stmt
 	// StmtContext returns a version of the prepared statement which runs within a
-	// transaction. Provided stmt can be either *sql.Stmt or *sqlx.Stmt.
+	// transaction. Provided stmt can be either *sql.Stmt, *sqlx.Stmt or godb.Stmt.
 	StmtContext(ctx context.Context, stmt interface{}) Stmt
 	// Unsafe returns a version of Tx which will silently succeed to scan when
 	// columns in the SQL result have no fields in the destination struct.
@@ -107,6 +107,7 @@ type Tx interface {
 type customTx struct {
 	tx      *sqlx.Tx
 	testErr error
+	hooks   queryHooks
 }
 
 // pushTestError
@@ -128,12 +129,12 @@ func (c *customTx) Commit() error {
 
 // Exec
 func (c *customTx) Exec(query string, args ...any) (sql.Result, error) {
-	return c.tx.Exec(query, args...)
+	return c.ExecContext(context.Background(), query, args...)
 }
 
 // ExecContext
 func (c *customTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return c.tx.ExecContext(ctx, query, args...)
+	return c.hooks.exec(ctx, OperationExec, query, args, c.tx.ExecContext)
 }
 
 // Rollback
@@ -153,85 +154,82 @@ func (c *customTx) DriverName() string {
 
 // Get
 func (c *customTx) Get(dest interface{}, query string, args ...interface{}) error {
-	return c.tx.Get(dest, query, args...)
+	return c.GetContext(context.Background(), dest, query, args...)
 }
 
 // GetContext
 func (c *customTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return c.tx.GetContext(ctx, dest, query, args...)
+	return c.hooks.scan(ctx, OperationGet, dest, query, args, c.tx.GetContext)
 }
 
 // MustExec
 func (c *customTx) MustExec(query string, args ...interface{}) sql.Result {
-	return c.tx.MustExec(query, args...)
+	return mustExec(c.ExecContext(context.Background(), query, args...))
 }
 
 // MustExecContext
 func (c *customTx) MustExecContext(ctx context.Context, query string, args ...interface{}) sql.Result {
-	return c.tx.MustExecContext(ctx, query, args...)
+	return mustExec(c.ExecContext(ctx, query, args...))
 }
 
 // NamedExec
 func (c *customTx) NamedExec(query string, arg interface{}) (sql.Result, error) {
-	return c.tx.NamedExec(query, arg)
+	return c.NamedExecContext(context.Background(), query, arg)
 }
 
 // NamedExecContext
 func (c *customTx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
-	return c.tx.NamedExecContext(ctx, query, arg)
+	return c.hooks.exec(ctx, OperationNamedExec, query, []interface{}{arg},
+		func(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+			query, args, err := c.hooks.bindNamed(c.tx, query, namedArg(args))
+			if err != nil {
+				return nil, err
+			}
+			return c.tx.ExecContext(ctx, query, args...)
+		},
+	)
 }
 
 // NamedQuery
 func (c *customTx) NamedQuery(query string, arg interface{}) (Rows, error) {
-	if rows, err := c.namedQuery(query, arg); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	return c.hooks.rows(context.Background(), OperationNamedQuery, query, []interface{}{arg},
+		func(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+			return c.namedQuery(ctx, query, namedArg(args))
+		},
+	)
 }
 
 // namedQuery
-func (c *customTx) namedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
+func (c *customTx) namedQuery(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
 	if c.testErr != nil {
 		return &sqlx.Rows{}, c.popTestError()
 	}
-	return c.tx.NamedQuery(query, arg)
+
+	query, args, err := c.hooks.bindNamed(c.tx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return c.tx.QueryxContext(ctx, query, args...)
 }
 
 // NamedStmt
 func (c *customTx) NamedStmt(stmt NamedStmt) NamedStmt {
-	return &customNamedStmt{namedStmt: c.tx.NamedStmt(stmt.Safe())}
+	return &customNamedStmt{namedStmt: c.tx.NamedStmt(stmt.Safe()), hooks: c.hooks}
 }
 
 // NamedStmtContext
 func (c *customTx) NamedStmtContext(ctx context.Context, stmt NamedStmt) NamedStmt {
-	return &customNamedStmt{namedStmt: c.tx.NamedStmtContext(ctx, stmt.Safe())}
+	return &customNamedStmt{namedStmt: c.tx.NamedStmtContext(ctx, stmt.Safe()), hooks: c.hooks}
 }
 
 // PrepareNamed
 func (c *customTx) PrepareNamed(query string) (NamedStmt, error) {
-	if namedStmt, err := c.prepareNamed(query); err != nil {
-		return &customNamedStmt{}, err
-	} else {
-		return &customNamedStmt{namedStmt: namedStmt}, nil
-	}
-}
-
-// prepareNamed
-func (c *customTx) prepareNamed(query string) (*sqlx.NamedStmt, error) {
-	if c.testErr != nil {
-		return &sqlx.NamedStmt{}, c.popTestError()
-	}
-	return c.tx.PrepareNamed(query)
+	return c.PrepareNamedContext(context.Background(), query)
 }
 
 // PrepareNamedContext
 func (c *customTx) PrepareNamedContext(ctx context.Context, query string) (NamedStmt, error) {
-	if namedStmt, err := c.prepareNamedContext(ctx, query); err != nil {
-		return &customNamedStmt{}, err
-	} else {
-		return &customNamedStmt{namedStmt: namedStmt}, nil
-	}
+	return c.hooks.prepareNamed(ctx, query, c.prepareNamedContext)
 }
 
 // prepareNamedContext
@@ -244,28 +242,12 @@ func (c *customTx) prepareNamedContext(ctx context.Context, query string) (*sqlx
 
 // Prepare
 func (c *customTx) Prepare(query string) (Stmt, error) {
-	if stmt, err := c.prepare(query); err != nil {
-		return &customStmt{}, err
-	} else {
-		return &customStmt{stmt: stmt}, nil
-	}
-}
-
-// prepare
-func (c *customTx) prepare(query string) (*sqlx.Stmt, error) {
-	if c.testErr != nil {
-		return &sqlx.Stmt{}, c.popTestError()
-	}
-	return c.tx.Preparex(query)
+	return c.PrepareContext(context.Background(), query)
 }
 
 // PrepareContext
 func (c *customTx) PrepareContext(ctx context.Context, query string) (Stmt, error) {
-	if stmt, err := c.prepareContext(ctx, query); err != nil {
-		return &customStmt{}, err
-	} else {
-		return &customStmt{stmt: stmt}, nil
-	}
+	return c.hooks.prepare(ctx, query, c.prepareContext)
 }
 
 // prepareContext
@@ -278,38 +260,22 @@ func (c *customTx) prepareContext(ctx context.Context, query string) (*sqlx.Stmt
 
 // QueryRow
 func (c *customTx) QueryRow(query string, args ...interface{}) Row {
-	return &customRow{row: c.tx.QueryRowx(query, args...)}
+	return c.QueryRowContext(context.Background(), query, args...)
 }
 
 // QueryRowContext
 func (c *customTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
-	return &customRow{row: c.tx.QueryRowxContext(ctx, query, args...)}
+	return c.hooks.row(ctx, query, args, c.tx.QueryRowxContext)
 }
 
 // Query
 func (c *customTx) Query(query string, args ...interface{}) (Rows, error) {
-	if rows, err := c.query(query, args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
-}
-
-// query
-func (c *customTx) query(query string, args ...interface{}) (*sqlx.Rows, error) {
-	if c.testErr != nil {
-		return &sqlx.Rows{}, c.popTestError()
-	}
-	return c.tx.Queryx(query, args...)
+	return c.QueryContext(context.Background(), query, args...)
 }
 
 // QueryContext
 func (c *customTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	if rows, err := c.queryContext(ctx, query, args...); err != nil {
-		return &customRows{}, err
-	} else {
-		return &customRows{rows: rows}, nil
-	}
+	return c.hooks.rows(ctx, OperationQuery, query, args, c.queryContext)
 }
 
 // queryContext
@@ -327,22 +293,22 @@ func (c *customTx) Rebind(query string) string {
 
 // Select
 func (c *customTx) Select(dest interface{}, query string, args ...interface{}) error {
-	return c.tx.Select(dest, query, args...)
+	return c.SelectContext(context.Background(), dest, query, args...)
 }
 
 // SelectContext
 func (c *customTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return c.tx.SelectContext(ctx, dest, query, args...)
+	return c.hooks.scan(ctx, OperationSelect, dest, query, args, c.tx.SelectContext)
 }
 
 // Stmt
 func (c *customTx) Stmt(stmt interface{}) Stmt {
-	return &customStmt{stmt: c.tx.Stmtx(stmt)}
+	return &customStmt{stmt: c.tx.Stmtx(unwrapStmt(stmt)), hooks: c.hooks, query: stmtQuery(stmt)}
 }
 
 // StmtContext
 func (c *customTx) StmtContext(ctx context.Context, stmt interface{}) Stmt {
-	return &customStmt{stmt: c.tx.StmtxContext(ctx, stmt)}
+	return &customStmt{stmt: c.tx.StmtxContext(ctx, unwrapStmt(stmt)), hooks: c.hooks, query: stmtQuery(stmt)}
 }
 
 // Unsafe
@@ -354,3 +320,19 @@ func (c *customTx) Unsafe() *sqlx.Tx {
 func (c *customTx) Safe() *sqlx.Tx {
 	return c.tx
 }
+
+// unwrapStmt returns the underlying *sqlx.Stmt of a godb.Stmt
+func unwrapStmt(stmt interface{}) interface{} {
+	if godbStmt, ok := stmt.(Stmt); ok {
+		return godbStmt.Safe()
+	}
+	return stmt
+}
+
+// stmtQuery returns the query of a godb.Stmt, used by the query hooks
+func stmtQuery(stmt interface{}) string {
+	if godbStmt, ok := stmt.(*customStmt); ok {
+		return godbStmt.query
+	}
+	return ""
+}
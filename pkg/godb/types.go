@@ -0,0 +1,472 @@
+package godb
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ sql.Scanner      = (*Null[int])(nil)
+	_ driver.Valuer    = Null[int]{}
+	_ json.Marshaler   = Null[int]{}
+	_ json.Unmarshaler = (*Null[int])(nil)
+	_ sql.Scanner      = (*JSON[int])(nil)
+	_ driver.Valuer    = JSON[int]{}
+	_ json.Marshaler   = JSON[int]{}
+	_ json.Unmarshaler = (*JSON[int])(nil)
+	_ sql.Scanner      = (*Array[int])(nil)
+	_ driver.Valuer    = Array[int]{}
+	_ dialectValuer    = Array[int]{}
+)
+
+// dialectValuer is implemented by the types whose stored value depends on the database
+// dialect. godb calls it instead of driver.Valuer for the arguments of its statements.
+type dialectValuer interface {
+	dialectValue(dbType DBType) (driver.Value, error)
+}
+
+// encodeArgs replaces the dialect dependent arguments by their value for dbType
+func encodeArgs(dbType DBType, args []interface{}) ([]interface{}, error) {
+	var encoded []interface{}
+	for index, arg := range args {
+		valuer, ok := arg.(dialectValuer)
+		if !ok {
+			continue
+		}
+
+		// Nil pointers are stored as NULL by database/sql
+		if value := reflect.ValueOf(arg); value.Kind() == reflect.Pointer && value.IsNil() {
+			continue
+		}
+
+		if encoded == nil {
+			encoded = append([]interface{}{}, args...)
+		}
+
+		value, err := valuer.dialectValue(dbType)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", index+1, err)
+		}
+		encoded[index] = value
+	}
+
+	if encoded == nil {
+		return args, nil
+	}
+	return encoded, nil
+}
+
+// Null stores a value that may be NULL. It replaces sql.NullString, sql.NullInt64 and the
+// other sql.Null types, and is encoded as JSON null when it is not valid.
+type Null[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewNull Returns a valid Null holding v
+func NewNull[T any](v T) Null[T] {
+	return Null[T]{V: v, Valid: true}
+}
+
+// NullFromPtr Returns a Null holding the value of ptr. A nil ptr returns an invalid Null.
+func NullFromPtr[T any](ptr *T) Null[T] {
+	if ptr == nil {
+		return Null[T]{}
+	}
+	return NewNull(*ptr)
+}
+
+// Ptr Returns a pointer to the value or nil if it is not valid
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+// Scan implements the sql.Scanner interface
+func (n *Null[T]) Scan(value interface{}) error {
+	if value == nil {
+		var zero T
+		n.V, n.Valid = zero, false
+		return nil
+	}
+
+	if err := convertAssign(reflect.ValueOf(&n.V).Elem(), value); err != nil {
+		n.Valid = false
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.V)
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (n Null[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (n *Null[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero T
+		n.V, n.Valid = zero, false
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// JSON stores V as a JSON encoded column, such as JSONB on Postgres, JSON on MySQL or
+// TEXT on SQLite. NULL columns are scanned as the zero value; use Null[JSON[T]] to tell
+// them apart.
+type JSON[T any] struct {
+	V T
+}
+
+// NewJSON Returns a JSON holding v
+func NewJSON[T any](v T) JSON[T] {
+	return JSON[T]{V: v}
+}
+
+// Scan implements the sql.Scanner interface
+func (j *JSON[T]) Scan(value interface{}) error {
+	var zero T
+	j.V = zero
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, &j.V)
+	case string:
+		return json.Unmarshal([]byte(v), &j.V)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON. %w", value, ErrUnsupportedScanType)
+	}
+}
+
+// Value implements the driver.Valuer interface
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.V)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &j.V)
+}
+
+// Array stores a one-dimensional Postgres array such as text[] or bigint[]. On MySQL and
+// SQLite it degrades to a JSON encoded column. The format is chosen by the driver of the
+// godb DB, Tx, Conn or statement writing it, while Value, used outside of godb, writes
+// Postgres arrays. Both formats can be scanned regardless of the dialect. A nil Array is
+// stored as NULL.
+type Array[T any] []T
+
+// Scan implements the sql.Scanner interface
+func (a *Array[T]) Scan(value interface{}) error {
+	var data string
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = string(v)
+	case string:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into Array. %w", value, ErrUnsupportedScanType)
+	}
+
+	if trimmed := strings.TrimSpace(data); strings.HasPrefix(trimmed, "[") {
+		var values []T
+		if err := json.Unmarshal([]byte(trimmed), &values); err != nil {
+			return err
+		}
+		*a = values
+		return nil
+	}
+
+	elements, err := parsePostgresArray(data)
+	if err != nil {
+		return err
+	}
+
+	values := make([]T, len(elements))
+	for index, element := range elements {
+		var src interface{}
+		if element != nil {
+			src = *element
+		}
+
+		if err := convertAssign(reflect.ValueOf(&values[index]).Elem(), src); err != nil {
+			return fmt.Errorf("array element %d: %w", index, err)
+		}
+	}
+	*a = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (a Array[T]) Value() (driver.Value, error) {
+	return a.dialectValue(PostgresDB)
+}
+
+// dialectValue returns a Postgres array literal, or the JSON encoded array on other dialects
+func (a Array[T]) dialectValue(dbType DBType) (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	if dbType != PostgresDB {
+		data, err := json.Marshal([]T(a))
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	var builder strings.Builder
+	builder.WriteByte('{')
+	for index, element := range a {
+		if index > 0 {
+			builder.WriteByte(',')
+		}
+
+		value, err := driver.DefaultParameterConverter.ConvertValue(element)
+		if err != nil {
+			return nil, fmt.Errorf("array element %d: %w", index, err)
+		}
+
+		if err := writePostgresArrayElement(&builder, value); err != nil {
+			return nil, fmt.Errorf("array element %d: %w", index, err)
+		}
+	}
+	builder.WriteByte('}')
+	return builder.String(), nil
+}
+
+// writePostgresArrayElement writes an element of a Postgres array literal
+func writePostgresArrayElement(builder *strings.Builder, value driver.Value) error {
+	switch v := value.(type) {
+	case nil:
+		builder.WriteString("NULL")
+	case int64:
+		builder.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		builder.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case bool:
+		builder.WriteString(strconv.FormatBool(v))
+	case time.Time:
+		builder.WriteString(`"` + v.Format(time.RFC3339Nano) + `"`)
+	case string:
+		writeQuotedArrayElement(builder, v)
+	case []byte:
+		writeQuotedArrayElement(builder, string(v))
+	default:
+		return fmt.Errorf("cannot write %T into Array. %w", value, ErrUnsupportedScanType)
+	}
+	return nil
+}
+
+// writeQuotedArrayElement writes a quoted element of a Postgres array literal
+func writeQuotedArrayElement(builder *strings.Builder, value string) {
+	builder.WriteByte('"')
+	for _, char := range value {
+		if char == '"' || char == '\\' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteRune(char)
+	}
+	builder.WriteByte('"')
+}
+
+// parsePostgresArray parses a one-dimensional Postgres array literal.
+// NULL elements are returned as nil.
+func parsePostgresArray(data string) ([]*string, error) {
+	data = strings.TrimSpace(data)
+	if len(data) < 2 || data[0] != '{' || data[len(data)-1] != '}' {
+		return nil, fmt.Errorf("%q. %w", data, ErrInvalidArray)
+	}
+
+	data = data[1 : len(data)-1]
+	elements := []*string{}
+	if data == "" {
+		return elements, nil
+	}
+
+	for position := 0; ; {
+		var (
+			element strings.Builder
+			quoted  bool
+		)
+
+		switch {
+		case position < len(data) && data[position] == '{':
+			return nil, fmt.Errorf("multi-dimensional arrays are not supported. %w", ErrInvalidArray)
+		case position < len(data) && data[position] == '"':
+			quoted = true
+			position++
+			for ; position < len(data) && data[position] != '"'; position++ {
+				if data[position] == '\\' {
+					position++
+				}
+				if position < len(data) {
+					element.WriteByte(data[position])
+				}
+			}
+
+			if position >= len(data) {
+				return nil, fmt.Errorf("unterminated quoted element. %w", ErrInvalidArray)
+			}
+			position++
+		default:
+			for ; position < len(data) && data[position] != ','; position++ {
+				element.WriteByte(data[position])
+			}
+		}
+
+		value := element.String()
+		if !quoted && strings.EqualFold(value, "NULL") {
+			elements = append(elements, nil)
+		} else {
+			elements = append(elements, &value)
+		}
+
+		if position >= len(data) {
+			return elements, nil
+		}
+
+		if data[position] != ',' {
+			return nil, fmt.Errorf("unexpected %q at %d. %w", data[position], position, ErrInvalidArray)
+		}
+		position++
+	}
+}
+
+// convertAssign assigns a value read from the database to dest, converting between
+// strings, byte slices, numbers and booleans like database/sql does
+func convertAssign(dest reflect.Value, src interface{}) error {
+	if src == nil {
+		dest.SetZero()
+		return nil
+	}
+
+	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Type().AssignableTo(dest.Type()) {
+		if data, ok := src.([]byte); ok {
+			srcValue = reflect.ValueOf(bytes.Clone(data))
+		}
+		dest.Set(srcValue)
+		return nil
+	}
+
+	if dest.Type() == reflect.TypeOf(time.Time{}) {
+		if text, ok := asString(src); ok {
+			for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+				if parsed, err := time.Parse(layout, text); err == nil {
+					dest.Set(reflect.ValueOf(parsed))
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("cannot scan %T into %s. %w", src, dest.Type(), ErrUnsupportedScanType)
+	}
+
+	text, ok := asString(src)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into %s. %w", src, dest.Type(), ErrUnsupportedScanType)
+	}
+
+	switch dest.Kind() {
+	case reflect.String:
+		dest.SetString(text)
+	case reflect.Slice:
+		if dest.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("cannot scan %T into %s. %w", src, dest.Type(), ErrUnsupportedScanType)
+		}
+		dest.SetBytes([]byte(text))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := strconv.ParseInt(text, 10, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetInt(number)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := strconv.ParseUint(text, 10, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetUint(number)
+	case reflect.Float32, reflect.Float64:
+		number, err := strconv.ParseFloat(text, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetFloat(number)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		dest.SetBool(value)
+	default:
+		return fmt.Errorf("cannot scan %T into %s. %w", src, dest.Type(), ErrUnsupportedScanType)
+	}
+	return nil
+}
+
+// asString returns the text form of a value read from the database
+func asString(src interface{}) (string, bool) {
+	switch v := src.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	default:
+		return "", false
+	}
+}
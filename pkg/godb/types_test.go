@@ -0,0 +1,202 @@
+package godb
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Null(t *testing.T) {
+	type customData struct {
+		ID      int             `db:"id" json:"id"`
+		Name    Null[string]    `db:"name" json:"name"`
+		Age     Null[int]       `db:"age" json:"age"`
+		Active  Null[bool]      `db:"active" json:"active"`
+		Score   Null[float64]   `db:"score" json:"score"`
+		Created Null[time.Time] `db:"created" json:"created"`
+	}
+
+	db := newSQLiteTestDB(t, `
+		CREATE TABLE custom_table (id INTEGER PRIMARY KEY, name TEXT, age INTEGER, active BOOLEAN, score REAL, created DATETIME)
+	`)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := []customData{
+		{ID: 1, Name: NewNull("John Doe"), Age: NewNull(42), Active: NewNull(true), Score: NewNull(9.5), Created: NewNull(created)},
+		{ID: 2},
+	}
+	_, err := db.NamedExec("INSERT INTO custom_table (id, name, age, active, score, created) VALUES (:id, :name, :age, :active, :score, :created)", data)
+	assert.NoError(t, err)
+
+	var list []customData
+	assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+	assert.Equal(t, data, list)
+
+	var text Null[int]
+	assert.NoError(t, db.Get(&text, "SELECT '7'"))
+	assert.Equal(t, NewNull(7), text, "text columns should be converted")
+	assert.Error(t, db.Get(&text, "SELECT 'seven'"))
+	assert.False(t, text.Valid)
+
+	encoded, err := json.Marshal(list)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id": 1, "name": "John Doe", "age": 42, "active": true, "score": 9.5, "created": "2024-01-02T03:04:05Z"},
+		{"id": 2, "name": null, "age": null, "active": null, "score": null, "created": null}
+	]`, string(encoded))
+
+	var decoded []customData
+	assert.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, data, decoded)
+
+	assert.Nil(t, Null[int]{}.Ptr())
+	assert.Equal(t, 42, *NullFromPtr(data[0].Age.Ptr()).Ptr())
+	assert.False(t, NullFromPtr[int](nil).Valid)
+}
+
+func Test_JSON(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+
+	type customData struct {
+		ID       int                  `db:"id"`
+		Address  JSON[address]        `db:"address"`
+		Tags     JSON[[]string]       `db:"tags"`
+		Optional Null[JSON[[]string]] `db:"optional"`
+	}
+
+	db := newSQLiteTestDB(t, "CREATE TABLE custom_table (id INTEGER PRIMARY KEY, address TEXT, tags TEXT, optional TEXT)")
+
+	data := []customData{
+		{ID: 1, Address: NewJSON(address{City: "Lisbon"}), Tags: NewJSON([]string{"a", "b"}), Optional: NewNull(NewJSON([]string{}))},
+		{ID: 2},
+	}
+	_, err := db.NamedExec("INSERT INTO custom_table (id, address, tags, optional) VALUES (:id, :address, :tags, :optional)", data)
+	assert.NoError(t, err)
+
+	var stored string
+	assert.NoError(t, db.Get(&stored, "SELECT address FROM custom_table WHERE id = 1"))
+	assert.JSONEq(t, `{"city": "Lisbon"}`, stored)
+
+	var list []customData
+	assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+	assert.Equal(t, data[0], list[0])
+	assert.False(t, list[1].Optional.Valid, "NULL should not be valid")
+
+	encoded, err := json.Marshal(list[0])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ID": 1, "Address": {"city": "Lisbon"}, "Tags": ["a", "b"], "Optional": []}`, string(encoded))
+
+	var value JSON[address]
+	assert.NoError(t, json.Unmarshal([]byte(`{"city": "Porto"}`), &value))
+	assert.Equal(t, "Porto", value.V.City)
+	assert.ErrorIs(t, value.Scan(10), ErrUnsupportedScanType)
+}
+
+func Test_Array(t *testing.T) {
+	t.Run("should write Postgres array literals", func(t *testing.T) {
+		value, err := Array[string]{"a", `b "c"`, `d\e`, ""}.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a","b \"c\"","d\\e",""}`, value)
+
+		value, err = Array[int64]{1, 2, 3}.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "{1,2,3}", value)
+
+		value, err = Array[Null[bool]]{NewNull(true), {}}.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "{true,NULL}", value)
+
+		value, err = Array[int]{}.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", value)
+
+		value, err = Array[int](nil).Value()
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("should read Postgres array literals", func(t *testing.T) {
+		var texts Array[Null[string]]
+		assert.NoError(t, texts.Scan([]byte(`{a,"b \"c\"","d\\e","NULL",NULL}`)))
+		assert.Equal(t, Array[Null[string]]{NewNull("a"), NewNull(`b "c"`), NewNull(`d\e`), NewNull("NULL"), {}}, texts)
+
+		var flags Array[bool]
+		assert.NoError(t, flags.Scan("{t,f}"))
+		assert.Equal(t, Array[bool]{true, false}, flags)
+
+		var numbers Array[int]
+		assert.NoError(t, numbers.Scan("{}"))
+		assert.Equal(t, Array[int]{}, numbers)
+		assert.NoError(t, numbers.Scan(nil))
+		assert.Nil(t, numbers)
+
+		assert.ErrorIs(t, numbers.Scan("{{1,2},{3,4}}"), ErrInvalidArray)
+		assert.ErrorIs(t, numbers.Scan(`{"1`), ErrInvalidArray)
+		assert.ErrorIs(t, numbers.Scan("1,2"), ErrInvalidArray)
+		assert.ErrorIs(t, numbers.Scan(10), ErrUnsupportedScanType)
+		assert.Error(t, numbers.Scan("{a}"))
+	})
+
+	t.Run("should encode arguments for the database", func(t *testing.T) {
+		args := []interface{}{1, Array[int]{1, 2}, (*Array[int])(nil)}
+		encoded, err := encodeArgs(MySQLDB, args)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{1, "[1,2]", (*Array[int])(nil)}, encoded)
+		assert.Equal(t, Array[int]{1, 2}, args[1], "the arguments should not be modified")
+
+		encoded, err = encodeArgs(PostgresDB, args)
+		assert.NoError(t, err)
+		assert.Equal(t, "{1,2}", encoded[1])
+	})
+
+	t.Run("should degrade to JSON on SQLite", func(t *testing.T) {
+		type customData struct {
+			ID   int           `db:"id"`
+			Tags Array[string] `db:"tags"`
+		}
+
+		db := newSQLiteTestDB(t, "CREATE TABLE custom_table (id INTEGER PRIMARY KEY, tags TEXT)")
+		_, err := db.NamedExec("INSERT INTO custom_table (id, tags) VALUES (:id, :tags)", []customData{
+			{ID: 1, Tags: Array[string]{"a", "b"}},
+			{ID: 2},
+		})
+		assert.NoError(t, err)
+		_, err = db.Exec("INSERT INTO custom_table VALUES (?, ?)", 3, `{c,d}`)
+		assert.NoError(t, err)
+		_, err = db.Exec("INSERT INTO custom_table VALUES (?, ?)", 4, Array[string]{"e"})
+		assert.NoError(t, err)
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		_, err = tx.NamedExec("INSERT INTO custom_table (id, tags) VALUES (:id, :tags)", customData{ID: 5, Tags: Array[string]{"f"}})
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+
+		stmt, err := db.PrepareNamed("INSERT INTO custom_table (id, tags) VALUES (:id, :tags)")
+		assert.NoError(t, err)
+		_, err = stmt.Exec(map[string]interface{}{"id": 6, "tags": Array[string]{"g"}})
+		assert.NoError(t, err)
+		_, err = stmt.Exec(map[string]interface{}{"id": 7})
+		assert.Error(t, err, "missing named arguments should be rejected")
+		assert.NoError(t, stmt.Close())
+
+		var stored []string
+		assert.NoError(t, db.Select(&stored, "SELECT tags FROM custom_table WHERE id IN (1, 4, 5, 6) ORDER BY id"))
+		assert.Equal(t, []string{`["a","b"]`, `["e"]`, `["f"]`, `["g"]`}, stored)
+
+		var list []customData
+		assert.NoError(t, db.Select(&list, "SELECT * FROM custom_table ORDER BY id"))
+		assert.Equal(t, []customData{
+			{ID: 1, Tags: Array[string]{"a", "b"}},
+			{ID: 2},
+			{ID: 3, Tags: Array[string]{"c", "d"}},
+			{ID: 4, Tags: Array[string]{"e"}},
+			{ID: 5, Tags: Array[string]{"f"}},
+			{ID: 6, Tags: Array[string]{"g"}},
+		}, list)
+	})
+}
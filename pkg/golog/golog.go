@@ -5,11 +5,13 @@ package golog
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/goenv"
@@ -111,6 +113,19 @@ func Log() *Logger {
 	return logger
 }
 
+// Sync Flushes the buffered logs. Use it before the application exits.
+func Sync() error {
+	if logger == nil || logger.zapLogger == nil {
+		return nil
+	}
+
+	// Syncing a terminal or pipe, such as the default stderr, is not supported by every OS
+	if err := logger.zapLogger.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTTY) {
+		return err
+	}
+	return nil
+}
+
 // WithTags Add a group of tags into current log. Tags are used to provide more context when writing logs.
 func WithTags(tags map[string]interface{}) Options {
 	return func(ctx context.Context, message string, logger *Logger, logType logType) {
@@ -360,3 +360,17 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestSync(t *testing.T) {
+	SetEnv(goenv.Test)
+	logger = nil
+	initLogOnce = sync.Once{}
+	Log()
+	assert.NoError(t, Sync(), "local loggers have nothing to flush")
+
+	SetEnv(goenv.Production)
+	logger = nil
+	initLogOnce = sync.Once{}
+	Log()
+	assert.NoError(t, Sync())
+}
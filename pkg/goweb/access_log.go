@@ -0,0 +1,229 @@
+package goweb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const (
+	defaultRequestIDHeader = "X-Request-ID"
+	defaultMaxBodySize     = 4096
+	// requestIDKey is the gocontext key of the request ID
+	requestIDKey  = "request-id"
+	redactedValue = "[REDACTED]"
+)
+
+var (
+	defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+	defaultRedactFields  = []string{"password", "token", "access_token", "refresh_token", "secret", "api_key"}
+)
+
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota + 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+type AccessLogConfig struct {
+	Enabled bool
+	// Levels are the log levels of each status class, such as 4 for 4xx. Defaults to info
+	// for 1xx, 2xx and 3xx, warn for 4xx and error for 5xx.
+	Levels map[int]LogLevel
+	// SkipPaths are the paths that are not logged. Paths ending with * are prefixes.
+	// DefaultConfig also skips the swagger and readiness routes.
+	SkipPaths []string
+	// RequestIDHeader is read for the request ID, which is generated if missing and sent in
+	// the response. Defaults to X-Request-ID.
+	RequestIDHeader string
+	// CaptureHeaders logs the request and response headers.
+	CaptureHeaders bool
+	// CaptureBody logs the request and response bodies, truncated to MaxBodySize.
+	CaptureBody bool
+	// MaxBodySize is the maximum size of the logged bodies. Defaults to 4096 bytes.
+	MaxBodySize int
+	// RedactHeaders are logged as [REDACTED], in addition to Authorization, Cookie,
+	// Set-Cookie, Proxy-Authorization and X-Api-Key.
+	RedactHeaders []string
+	// RedactFields are the JSON body fields logged as [REDACTED], at any depth, in addition to
+	// password, token, access_token, refresh_token, secret and api_key.
+	RedactFields []string
+}
+
+// RequestID Get the request ID set by the access log middleware
+func RequestID(ctx context.Context) string {
+	requestID, _ := gocontext.Get[string](ctx, requestIDKey)
+	return requestID
+}
+
+// AccessLogMiddleware Creates a middleware logging every request through logger.
+// Errors returned by the next handlers are sent with the app ErrorHandler, so their status is logged,
+// and are kept for the outer middlewares, such as the tracing one.
+func AccessLogMiddleware(logger WebServerLogger, config AccessLogConfig) fiber.Handler {
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = defaultRequestIDHeader
+	}
+
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+
+	redactHeaders := map[string]bool{}
+	for _, header := range append(defaultRedactHeaders, config.RedactHeaders...) {
+		redactHeaders[strings.ToLower(header)] = true
+	}
+
+	redactFields := map[string]bool{}
+	for _, field := range append(defaultRedactFields, config.RedactFields...) {
+		redactFields[strings.ToLower(field)] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if skipPath(c.Path(), config.SkipPaths) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		requestID := c.Get(config.RequestIDHeader)
+		if requestID == "" {
+			requestID = utils.UUIDv4()
+		}
+		c.Set(config.RequestIDHeader, requestID)
+		c.SetUserContext(gocontext.Add(c.UserContext(), requestIDKey, requestID))
+
+		if err := c.Next(); err != nil {
+			handleError(c, err)
+		}
+
+		tags := map[string]interface{}{
+			"method":     c.Method(),
+			"route":      c.Route().Path,
+			"path":       c.Path(),
+			"status":     c.Response().StatusCode(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":      len(c.Response().Body()),
+			"ip":         c.IP(),
+			"user_agent": c.Get(fiber.HeaderUserAgent),
+			"request_id": requestID,
+		}
+
+		if config.CaptureHeaders {
+			tags["request_headers"] = redactHeaderValues(c.GetReqHeaders(), redactHeaders)
+			tags["response_headers"] = redactHeaderValues(c.GetRespHeaders(), redactHeaders)
+		}
+
+		if config.CaptureBody {
+			tags["request_body"] = redactBody(c.Body(), string(c.Request().Header.ContentType()), redactFields, config.MaxBodySize)
+			tags["response_body"] = redactBody(c.Response().Body(), string(c.Response().Header.ContentType()), redactFields, config.MaxBodySize)
+		}
+
+		status := c.Response().StatusCode()
+		message := fmt.Sprintf("%s %s %d", c.Method(), c.Path(), status)
+		switch statusLevel(status, config.Levels) {
+		case LevelDebug:
+			logger.Debug(c.UserContext(), message, golog.WithTags(tags))
+		case LevelWarn:
+			logger.Warn(c.UserContext(), message, golog.WithTags(tags))
+		case LevelError:
+			logger.Error(c.UserContext(), message, golog.WithTags(tags))
+		default:
+			logger.Info(c.UserContext(), message, golog.WithTags(tags))
+		}
+		return nil
+	}
+}
+
+// statusLevel Get the log level of a status
+func statusLevel(status int, levels map[int]LogLevel) LogLevel {
+	if level, ok := levels[status/100]; ok {
+		return level
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		return LevelError
+	case status >= http.StatusBadRequest:
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
+
+// skipPath Check if a path is in the skip list
+func skipPath(path string, skipPaths []string) bool {
+	for _, skip := range skipPaths {
+		if prefix, ok := strings.CutSuffix(skip, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == skip {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaderValues Convert the headers into log tags, redacting the sensitive ones
+func redactHeaderValues(headers map[string][]string, redact map[string]bool) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name, value := range headers {
+		if redact[strings.ToLower(name)] {
+			values[name] = redactedValue
+		} else {
+			values[name] = strings.Join(value, ", ")
+		}
+	}
+	return values
+}
+
+// redactBody Convert a body into a log tag, redacting the sensitive JSON fields
+func redactBody(body []byte, contentType string, redact map[string]bool, maxSize int) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) || strings.HasSuffix(strings.Split(contentType, ";")[0], "+json") {
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err == nil {
+			if redacted, err := json.Marshal(redactValue(value, redact)); err == nil {
+				body = redacted
+			}
+		}
+	}
+
+	if len(body) > maxSize {
+		return string(body[:maxSize]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// redactValue Redact the sensitive fields of a JSON value
+func redactValue(value interface{}, redact map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(field, redact)
+			}
+		}
+	case []interface{}:
+		for index, item := range v {
+			v[index] = redactValue(item, redact)
+		}
+	}
+	return value
+}
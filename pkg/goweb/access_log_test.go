@@ -0,0 +1,174 @@
+package goweb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// loggerRecorder records the log levels and messages
+type loggerRecorder struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+// record
+func (lr *loggerRecorder) record(level string, message string) {
+	lr.mutex.Lock()
+	defer lr.mutex.Unlock()
+	lr.messages = append(lr.messages, level+" "+message)
+}
+
+// Info
+func (lr *loggerRecorder) Info(_ context.Context, message string, _ ...golog.Options) {
+	lr.record("info", message)
+}
+
+// Warn
+func (lr *loggerRecorder) Warn(_ context.Context, message string, _ ...golog.Options) {
+	lr.record("warn", message)
+}
+
+// Debug
+func (lr *loggerRecorder) Debug(_ context.Context, message string, _ ...golog.Options) {
+	lr.record("debug", message)
+}
+
+// Error
+func (lr *loggerRecorder) Error(_ context.Context, message string, _ ...golog.Options) {
+	lr.record("error", message)
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	logger := &loggerRecorder{}
+	ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{
+		Logger: logger,
+		AccessLog: AccessLogConfig{
+			Enabled:     true,
+			Levels:      map[int]LogLevel{2: LevelDebug},
+			SkipPaths:   []string{"/health"},
+			CaptureBody: true,
+		},
+	}))
+
+	requestIDs := []string{}
+	app := ws.GetApp()
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		requestIDs = append(requestIDs, RequestID(c.UserContext()))
+		return c.SendString("user")
+	})
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("database is down")
+	})
+	app.Get("/swagger/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/users/10", nil)
+	request.Header.Set("X-Request-ID", "request-1")
+	resp, err := app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "request-1", resp.Header.Get("X-Request-ID"))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/users/11", nil))
+	assert.NoError(t, err)
+	assert.Len(t, resp.Header.Get("X-Request-ID"), 36, "missing request IDs should be generated")
+
+	for _, path := range []string{"/health", "/swagger/index.html", "/missing", "/fail"} {
+		_, err = app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, "request-1", requestIDs[0])
+	assert.Equal(t, []string{
+		"debug GET /users/10 200",
+		"debug GET /users/11 200",
+		"warn GET /missing 404",
+		"error Unexpected error. Cause: database is down",
+		"error GET /fail 500",
+	}, logger.messages)
+
+	logger.messages = nil
+	app = fiber.New()
+	app.Use(AccessLogMiddleware(logger, AccessLogConfig{}))
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"warn GET /missing 404"}, logger.messages, "4xx should be warnings")
+
+	var handled error
+	app = fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		err := c.Next()
+		handled = handledError(c)
+		return err
+	})
+	app.Use(AccessLogMiddleware(logger, AccessLogConfig{}))
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("database is down")
+	})
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.NoError(t, err)
+	assert.EqualError(t, handled, "database is down", "the error should be kept for the outer middlewares")
+}
+
+func TestAccessLogRedaction(t *testing.T) {
+	redactHeaders := map[string]bool{"authorization": true}
+	assert.Equal(t,
+		map[string]interface{}{"Authorization": redactedValue, "Accept": "text/html, application/json"},
+		redactHeaderValues(map[string][]string{"Authorization": {"Bearer token"}, "Accept": {"text/html", "application/json"}}, redactHeaders),
+	)
+
+	redactFields := map[string]bool{"password": true, "token": true}
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		maxSize     int
+		expected    string
+	}{
+		{
+			name:        "should redact nested JSON fields",
+			body:        `{"user":"john","Password":"secret","sessions":[{"token":"abc","id":1}]}`,
+			contentType: "application/json; charset=utf-8",
+			maxSize:     defaultMaxBodySize,
+			expected:    `{"Password":"[REDACTED]","sessions":[{"id":1,"token":"[REDACTED]"}],"user":"john"}`,
+		},
+		{
+			name:        "should redact JSON suffixed types",
+			body:        `{"password":"secret"}`,
+			contentType: "application/problem+json",
+			maxSize:     defaultMaxBodySize,
+			expected:    `{"password":"[REDACTED]"}`,
+		},
+		{
+			name:        "should keep other bodies",
+			body:        "password=secret",
+			contentType: "text/plain",
+			maxSize:     defaultMaxBodySize,
+			expected:    "password=secret",
+		},
+		{
+			name:        "should truncate bodies",
+			body:        strings.Repeat("a", 20),
+			contentType: "text/plain",
+			maxSize:     16,
+			expected:    strings.Repeat("a", 16) + "...(truncated)",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, redactBody([]byte(test.body), test.contentType, redactFields, test.maxSize), test.name)
+	}
+}
@@ -0,0 +1,445 @@
+package goweb
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/gocrypto"
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultAPIKeyHeader is the request header with the API key
+	DefaultAPIKeyHeader = "X-API-Key"
+	// AllScopes grants every scope
+	AllScopes = "*"
+
+	apiKeySeparator   = "."
+	apiKeyIDSize      = 8
+	apiKeySecretSize  = 32
+	apiKeyPrefixKey   = "api-key-prefix"
+	apiKeyScopesKey   = "api-key-scopes"
+	defaultAPIKeyName = "key"
+	// apiKeyVerifiedTTL is how long verified secrets skip the argon2id hash
+	apiKeyVerifiedTTL = time.Minute
+	// apiKeyVerifiedSweepInterval is how often APIKeys deletes the expired verified secrets
+	apiKeyVerifiedSweepInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrAPIKeyExpired  = errors.New("API key expired")
+
+	// defaultAPIKeyHashParams are cheaper than the password hash params, since the secrets are
+	// random and every request verifies its key
+	defaultAPIKeyHashParams = gocrypto.HashParsms{
+		Memory:      16 * 1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLentgh:   32,
+	}
+)
+
+// APIKey is a stored API key. Its secret is never stored, only its hash.
+type APIKey struct {
+	// Prefix is the visible part of the key, identifying it, such as svc_1a2b3c4d5e6f7a8b
+	Prefix    string
+	Hash      string
+	Name      string
+	Scopes    []string
+	CreatedAt time.Time
+	// ExpiresAt is when the key stops working. Zero means it never expires.
+	ExpiresAt time.Time
+}
+
+// NewAPIKey defines the API key created by APIKeys.Create
+type NewAPIKey struct {
+	// Label is the start of the key prefix, such as svc or the name of the environment.
+	// Defaults to key.
+	Label  string
+	Name   string
+	Scopes []string
+	// TTL is how long the key works. Zero means it never expires.
+	TTL time.Duration
+}
+
+// APIKeyStore stores the API keys by prefix
+type APIKeyStore interface {
+	Save(ctx context.Context, key APIKey) error
+	// Find returns ErrAPIKeyNotFound for unknown prefixes
+	Find(ctx context.Context, prefix string) (APIKey, error)
+	// Delete returns ErrAPIKeyNotFound for unknown prefixes
+	Delete(ctx context.Context, prefix string) error
+}
+
+// APIKeys creates and verifies API keys with the format <prefix>.<secret>
+type APIKeys struct {
+	store      APIKeyStore
+	hashParams gocrypto.HashParsms
+	now        func() time.Time
+	mutex      sync.Mutex
+	verified   map[string]verifiedAPIKey
+	lastSweep  time.Time
+}
+
+// verifiedAPIKey is a recently verified secret of a key
+type verifiedAPIKey struct {
+	secret    [sha256.Size]byte
+	hash      string
+	expiresAt time.Time
+}
+
+// NewAPIKeys Creates a new API keys manager. The secrets are hashed with argon2id using
+// hashParams, or cheap defaults when they are empty.
+func NewAPIKeys(store APIKeyStore, hashParams gocrypto.HashParsms) *APIKeys {
+	if hashParams == (gocrypto.HashParsms{}) {
+		hashParams = defaultAPIKeyHashParams
+	}
+	return &APIKeys{store: store, hashParams: hashParams, now: time.Now, verified: map[string]verifiedAPIKey{}}
+}
+
+// Create Create and store a new API key, returning the key sent by the clients. It can not be
+// read again, since only the hash of its secret is stored.
+func (ak *APIKeys) Create(ctx context.Context, newKey NewAPIKey) (string, APIKey, error) {
+	label := defaultIfEmpty(newKey.Label, defaultAPIKeyName)
+	if strings.Contains(label, apiKeySeparator) {
+		return "", APIKey{}, fmt.Errorf("label %q can not contain %q. %w", label, apiKeySeparator, ErrInvalidAPIKey)
+	}
+
+	id := make([]byte, apiKeyIDSize)
+	secret := make([]byte, apiKeySecretSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", APIKey{}, fmt.Errorf("%s. %w", err, gocrypto.ErrRandomBytes)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", APIKey{}, fmt.Errorf("%s. %w", err, gocrypto.ErrRandomBytes)
+	}
+
+	prefix := label + "_" + hex.EncodeToString(id)
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	hash, err := gocrypto.Hash(encodedSecret, ak.hashParams)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	now := ak.now().UTC()
+	key := APIKey{
+		Prefix:    prefix,
+		Hash:      hash,
+		Name:      newKey.Name,
+		Scopes:    newKey.Scopes,
+		CreatedAt: now,
+	}
+	if newKey.TTL > 0 {
+		key.ExpiresAt = now.Add(newKey.TTL)
+	}
+
+	if err := ak.store.Save(ctx, key); err != nil {
+		return "", APIKey{}, err
+	}
+	return prefix + apiKeySeparator + encodedSecret, key, nil
+}
+
+// Verify Find the key by its prefix and verify its secret and expiration.
+//
+// Verified secrets are remembered for a minute, so only the first request of a client runs
+// argon2id. The key is still loaded from the store, so revoked keys are rejected at once. Wrong
+// secrets are always hashed, so APIKeyMiddleware should run behind a RateLimitMiddleware.
+func (ak *APIKeys) Verify(ctx context.Context, rawKey string) (APIKey, error) {
+	prefix, secret, ok := strings.Cut(rawKey, apiKeySeparator)
+	if !ok || prefix == "" || secret == "" {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	key, err := ak.store.Find(ctx, prefix)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	now := ak.now()
+	if !ak.isVerified(key, secret, now) {
+		// Compare hashes the secret again, comparing the hashes in constant time
+		if err := gocrypto.Compare(secret, key.Hash); err != nil {
+			return APIKey{}, fmt.Errorf("%s. %w", err, ErrInvalidAPIKey)
+		}
+		ak.setVerified(key, secret, now)
+	}
+
+	if !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt) {
+		return APIKey{}, ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+// isVerified checks if the secret of the key was recently verified
+func (ak *APIKeys) isVerified(key APIKey, secret string, now time.Time) bool {
+	ak.mutex.Lock()
+	defer ak.mutex.Unlock()
+
+	verified, ok := ak.verified[key.Prefix]
+	if !ok || verified.hash != key.Hash || !now.Before(verified.expiresAt) {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(digest[:], verified.secret[:]) == 1
+}
+
+// setVerified remembers the verified secret of the key
+func (ak *APIKeys) setVerified(key APIKey, secret string, now time.Time) {
+	ak.mutex.Lock()
+	defer ak.mutex.Unlock()
+
+	// Sweep the expired secrets, which are only replaced when their key is verified again
+	if now.Sub(ak.lastSweep) >= apiKeyVerifiedSweepInterval {
+		for prefix, verified := range ak.verified {
+			if !now.Before(verified.expiresAt) {
+				delete(ak.verified, prefix)
+			}
+		}
+		ak.lastSweep = now
+	}
+
+	ak.verified[key.Prefix] = verifiedAPIKey{
+		secret:    sha256.Sum256([]byte(secret)),
+		hash:      key.Hash,
+		expiresAt: now.Add(apiKeyVerifiedTTL),
+	}
+}
+
+// Revoke Delete the key with the prefix
+func (ak *APIKeys) Revoke(ctx context.Context, prefix string) error {
+	ak.mutex.Lock()
+	delete(ak.verified, prefix)
+	ak.mutex.Unlock()
+	return ak.store.Delete(ctx, prefix)
+}
+
+// HasScopes Check if the key has all the scopes
+func (k APIKey) HasScopes(scopes ...string) bool {
+	return hasScopes(k.Scopes, scopes)
+}
+
+// MemoryAPIKeyStore stores the API keys in memory, such as for tests and static keys
+type MemoryAPIKeyStore struct {
+	mutex sync.RWMutex
+	keys  map[string]APIKey
+}
+
+// NewMemoryAPIKeyStore Creates a new in-memory API key store
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: map[string]APIKey{}}
+}
+
+// Save
+func (ms *MemoryAPIKeyStore) Save(_ context.Context, key APIKey) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.keys[key.Prefix] = key
+	return nil
+}
+
+// Find
+func (ms *MemoryAPIKeyStore) Find(_ context.Context, prefix string) (APIKey, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+	if key, ok := ms.keys[prefix]; ok {
+		return key, nil
+	}
+	return APIKey{}, ErrAPIKeyNotFound
+}
+
+// Delete
+func (ms *MemoryAPIKeyStore) Delete(_ context.Context, prefix string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if _, ok := ms.keys[prefix]; !ok {
+		return ErrAPIKeyNotFound
+	}
+	delete(ms.keys, prefix)
+	return nil
+}
+
+// apiKeyRow is an API key stored by DBAPIKeyStore
+type apiKeyRow struct {
+	_         struct{}             `godb:"table:api_keys"`
+	Prefix    string               `db:"prefix" godb:"pk"`
+	Hash      string               `db:"hash"`
+	Name      string               `db:"name"`
+	Scopes    string               `db:"scopes"`
+	CreatedAt time.Time            `db:"created_at"`
+	ExpiresAt godb.Null[time.Time] `db:"expires_at"`
+}
+
+// DBAPIKeyStore stores the API keys in the api_keys table:
+//
+//	CREATE TABLE api_keys (
+//		prefix     VARCHAR(64) PRIMARY KEY,
+//		hash       VARCHAR(255) NOT NULL,
+//		name       VARCHAR(255) NOT NULL,
+//		scopes     TEXT NOT NULL,
+//		created_at TIMESTAMP NOT NULL,
+//		expires_at TIMESTAMP NULL
+//	);
+type DBAPIKeyStore struct {
+	repository *godb.Repository[apiKeyRow]
+}
+
+// NewDBAPIKeyStore Creates a new API key store using db
+func NewDBAPIKeyStore(db godb.Queryer) (*DBAPIKeyStore, error) {
+	repository, err := godb.NewRepository[apiKeyRow](db)
+	if err != nil {
+		return nil, err
+	}
+	return &DBAPIKeyStore{repository: repository}, nil
+}
+
+// Save
+func (ds *DBAPIKeyStore) Save(ctx context.Context, key APIKey) error {
+	row := apiKeyRow{
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Name:      key.Name,
+		Scopes:    strings.Join(key.Scopes, " "),
+		CreatedAt: key.CreatedAt,
+	}
+	if !key.ExpiresAt.IsZero() {
+		row.ExpiresAt = godb.NewNull(key.ExpiresAt)
+	}
+	return ds.repository.Insert(ctx, &row)
+}
+
+// Find
+func (ds *DBAPIKeyStore) Find(ctx context.Context, prefix string) (APIKey, error) {
+	row, err := ds.repository.FindByID(ctx, prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	return APIKey{
+		Prefix:    row.Prefix,
+		Hash:      row.Hash,
+		Name:      row.Name,
+		Scopes:    strings.Fields(row.Scopes),
+		CreatedAt: row.CreatedAt,
+		ExpiresAt: row.ExpiresAt.V,
+	}, nil
+}
+
+// Delete
+func (ds *DBAPIKeyStore) Delete(ctx context.Context, prefix string) error {
+	err := ds.repository.Delete(ctx, prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+type APIKeyAuthConfig struct {
+	Keys *APIKeys
+	// Header is the request header with the API key. Defaults to X-API-Key.
+	Header string
+	// SkipPaths are the paths that are not authenticated. Paths ending with * are prefixes.
+	SkipPaths []string
+}
+
+// APIKeyMiddleware Creates a middleware authenticating the requests with their API key.
+//
+// The prefix and scopes of valid keys are added to the fiber UserContext with gocontext, see
+// APIKeyPrefix and RequireScopes. Requests without a valid key are rejected with a 401 HTTPError.
+// Wrong secrets are hashed on every request, so run it behind a RateLimitMiddleware.
+func APIKeyMiddleware(config APIKeyAuthConfig) fiber.Handler {
+	header := defaultIfEmpty(config.Header, DefaultAPIKeyHeader)
+	return func(c *fiber.Ctx) error {
+		if skipPath(c.Path(), config.SkipPaths) {
+			return c.Next()
+		}
+
+		rawKey := c.Get(header)
+		if rawKey == "" {
+			return Unauthorized("missing API key").WithCode("missing_api_key")
+		}
+
+		key, err := config.Keys.Verify(c.UserContext(), rawKey)
+		switch {
+		case errors.Is(err, ErrInvalidAPIKey), errors.Is(err, ErrAPIKeyNotFound):
+			return Unauthorized("invalid API key").WithCode("invalid_api_key").WithCause(err)
+		case errors.Is(err, ErrAPIKeyExpired):
+			return Unauthorized("expired API key").WithCode("invalid_api_key").WithCause(err)
+		case err != nil:
+			return err
+		}
+
+		ctx := gocontext.Add(c.UserContext(), apiKeyPrefixKey, key.Prefix)
+		c.SetUserContext(gocontext.Add(ctx, apiKeyScopesKey, strings.Join(key.Scopes, " ")))
+		return c.Next()
+	}
+}
+
+// RequireScopes Creates a middleware rejecting the requests without all the scopes with a 403
+// HTTPError. The scopes are the ones of the API key added by APIKeyMiddleware, or of the scope
+// claim of the token added by JWTMiddleware.
+func RequireScopes(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !hasScopes(Scopes(c.UserContext()), scopes) {
+			return Forbidden(fmt.Sprintf("requires the scopes: %s", strings.Join(scopes, ", "))).WithCode("insufficient_scope")
+		}
+		return c.Next()
+	}
+}
+
+// APIKeyPrefix Get the prefix of the request API key added by APIKeyMiddleware
+func APIKeyPrefix(ctx context.Context) string {
+	prefix, _ := gocontext.Get[string](ctx, apiKeyPrefixKey)
+	return prefix
+}
+
+// Scopes Get the scopes of the request API key or token
+func Scopes(ctx context.Context) []string {
+	if scopes, ok := gocontext.Get[string](ctx, apiKeyScopesKey); ok {
+		return strings.Fields(scopes)
+	}
+
+	if scope, ok := gocontext.Get[string](ctx, jwtScopeKey); ok {
+		return strings.Fields(scope)
+	}
+	return nil
+}
+
+// hasScopes Check if the granted scopes include all the required ones
+func hasScopes(granted []string, required []string) bool {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		grantedSet[scope] = true
+	}
+
+	if grantedSet[AllScopes] {
+		return true
+	}
+
+	for _, scope := range required {
+		if !grantedSet[scope] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,200 @@
+package goweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocrypto"
+	"github.com/JhonatanRSantos/gocore/pkg/godb/godbtest"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	dbStore, err := NewDBAPIKeyStore(godbtest.NewSQLiteDB(t, godbtest.Config{Schema: []string{"testdata/api_keys.sql"}}))
+	assert.NoError(t, err)
+
+	for name, store := range map[string]APIKeyStore{"memory": NewMemoryAPIKeyStore(), "db": dbStore} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			keys := NewAPIKeys(store, gocrypto.HashParsms{})
+
+			rawKey, key, err := keys.Create(ctx, NewAPIKey{Label: "svc", Name: "billing", Scopes: []string{"users:read"}, TTL: time.Hour})
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(rawKey, key.Prefix+"."), "the key should start with its prefix")
+			assert.True(t, strings.HasPrefix(key.Prefix, "svc_"))
+			assert.True(t, strings.HasPrefix(key.Hash, "argon2id$"))
+
+			stored, err := store.Find(ctx, key.Prefix)
+			assert.NoError(t, err)
+			assert.NotContains(t, stored.Hash, strings.TrimPrefix(rawKey, key.Prefix+"."), "the secret should not be stored")
+
+			verified, err := keys.Verify(ctx, rawKey)
+			assert.NoError(t, err)
+			assert.Equal(t, "billing", verified.Name)
+			assert.Equal(t, []string{"users:read"}, verified.Scopes)
+			assert.WithinDuration(t, key.ExpiresAt, verified.ExpiresAt, time.Second)
+
+			_, err = keys.Verify(ctx, key.Prefix+".wrong-secret")
+			assert.ErrorIs(t, err, ErrInvalidAPIKey)
+			_, err = keys.Verify(ctx, "svc_unknown.secret")
+			assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+			_, err = keys.Verify(ctx, "no-separator")
+			assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+			keys.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+			_, err = keys.Verify(ctx, rawKey)
+			assert.ErrorIs(t, err, ErrAPIKeyExpired)
+
+			assert.NoError(t, keys.Revoke(ctx, key.Prefix))
+			assert.ErrorIs(t, keys.Revoke(ctx, key.Prefix), ErrAPIKeyNotFound)
+			_, err = keys.Verify(ctx, rawKey)
+			assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+		})
+	}
+
+	t.Run("verified secrets", func(t *testing.T) {
+		ctx := context.Background()
+		store := NewMemoryAPIKeyStore()
+		keys := NewAPIKeys(store, gocrypto.HashParsms{})
+
+		rawKey, key, err := keys.Create(ctx, NewAPIKey{})
+		assert.NoError(t, err)
+		_, err = keys.Verify(ctx, rawKey)
+		assert.NoError(t, err)
+		assert.Len(t, keys.verified, 1)
+
+		// A hash that can not be compared shows the verified secret is used instead
+		verified := keys.verified[key.Prefix]
+		verified.hash, key.Hash = "cached", "cached"
+		keys.verified[key.Prefix] = verified
+		assert.NoError(t, store.Save(ctx, key))
+		_, err = keys.Verify(ctx, rawKey)
+		assert.NoError(t, err, "verified secrets should not be hashed again")
+		_, err = keys.Verify(ctx, key.Prefix+".wrong-secret")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+		key.Hash = "rotated"
+		assert.NoError(t, store.Save(ctx, key))
+		_, err = keys.Verify(ctx, rawKey)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, "changed hashes should be verified again")
+
+		key.Hash = "cached"
+		assert.NoError(t, store.Save(ctx, key))
+		keys.now = func() time.Time { return time.Now().Add(apiKeyVerifiedTTL) }
+		_, err = keys.Verify(ctx, rawKey)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, "expired secrets should be verified again")
+
+		assert.NoError(t, keys.Revoke(ctx, key.Prefix))
+		assert.Empty(t, keys.verified)
+	})
+
+	t.Run("expired verified secrets", func(t *testing.T) {
+		ctx := context.Background()
+		keys := NewAPIKeys(NewMemoryAPIKeyStore(), gocrypto.HashParsms{})
+		now := time.Now()
+		keys.now = func() time.Time { return now }
+
+		rawKeys := make([]string, 3)
+		prefixes := make([]string, 3)
+		for index := range rawKeys {
+			rawKey, key, err := keys.Create(ctx, NewAPIKey{})
+			assert.NoError(t, err)
+			rawKeys[index], prefixes[index] = rawKey, key.Prefix
+		}
+
+		_, err := keys.Verify(ctx, rawKeys[0])
+		assert.NoError(t, err)
+
+		now = now.Add(apiKeyVerifiedTTL)
+		keys.lastSweep = now.Add(-apiKeyVerifiedSweepInterval / 2)
+		_, err = keys.Verify(ctx, rawKeys[1])
+		assert.NoError(t, err)
+		assert.Len(t, keys.verified, 2, "expired secrets should only be swept once per interval")
+
+		now = now.Add(apiKeyVerifiedSweepInterval / 2)
+		_, err = keys.Verify(ctx, rawKeys[2])
+		assert.NoError(t, err)
+		assert.Len(t, keys.verified, 2)
+		assert.NotContains(t, keys.verified, prefixes[0])
+	})
+
+	_, _, err = NewAPIKeys(NewMemoryAPIKeyStore(), gocrypto.HashParsms{}).Create(context.Background(), NewAPIKey{Label: "svc.live"})
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	ctx := context.Background()
+	keys := NewAPIKeys(NewMemoryAPIKeyStore(), gocrypto.HashParsms{})
+	reader, _, err := keys.Create(ctx, NewAPIKey{Scopes: []string{"users:read"}})
+	assert.NoError(t, err)
+	admin, adminKey, err := keys.Create(ctx, NewAPIKey{Scopes: []string{AllScopes}})
+	assert.NoError(t, err)
+
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Use(APIKeyMiddleware(APIKeyAuthConfig{Keys: keys}))
+	app.Get("/users", RequireScopes("users:read"), func(c *fiber.Ctx) error {
+		return c.SendString(APIKeyPrefix(c.UserContext()))
+	})
+	app.Delete("/users", RequireScopes("users:read", "users:write"), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		status int
+	}{
+		{name: "should accept keys with the scopes", method: http.MethodGet, key: reader, status: http.StatusOK},
+		{name: "should reject keys without the scopes", method: http.MethodDelete, key: reader, status: http.StatusForbidden},
+		{name: "should accept keys with all the scopes", method: http.MethodDelete, key: admin, status: http.StatusNoContent},
+		{name: "should reject missing keys", method: http.MethodGet, status: http.StatusUnauthorized},
+		{name: "should reject invalid keys", method: http.MethodGet, key: reader + "x", status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/users", nil)
+			if test.key != "" {
+				request.Header.Set(DefaultAPIKeyHeader, test.key)
+			}
+
+			resp, err := app.Test(request)
+			assert.NoError(t, err)
+			assert.Equal(t, test.status, resp.StatusCode)
+		})
+	}
+
+	jwtKey, err := gocrypto.GenerateJWTKey("key-1", gocrypto.AlgHS256)
+	assert.NoError(t, err)
+	jwt, err := gocrypto.NewJWT(gocrypto.JWTConfig{Keys: []gocrypto.JWTKey{jwtKey}})
+	assert.NoError(t, err)
+
+	app = fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Use(JWTMiddleware(JWTAuthConfig{Verifier: jwt}))
+	app.Get("/users", RequireScopes("users:read"), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	for scope, status := range map[string]int{"users:read users:write": http.StatusOK, "orders:read": http.StatusForbidden} {
+		token, err := jwt.Sign(gocrypto.Claims{"sub": "user-1", "scope": scope})
+		assert.NoError(t, err)
+
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		resp, err := app.Test(request)
+		assert.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, "the scope claim should be used for %q", scope)
+	}
+
+	assert.True(t, adminKey.HasScopes("anything"))
+	assert.True(t, hasScopes([]string{"a", "b"}, []string{"b"}))
+	assert.False(t, hasScopes(nil, []string{"a"}))
+	assert.True(t, hasScopes(nil, nil))
+}
@@ -0,0 +1,461 @@
+package goweb
+
+import (
+	"encoding"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// Struct tags read by Bind
+const (
+	TagPath     = "path"
+	TagQuery    = "query"
+	TagHeader   = "header"
+	TagJSON     = "json"
+	TagValidate = "validate"
+)
+
+var (
+	// regexCache caches the compiled regex rules
+	regexCache sync.Map
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Bind Bind a request into a new T and validate it. See BindInto.
+func Bind[T any](c *fiber.Ctx) (T, error) {
+	var dest T
+	err := BindInto(c, &dest)
+	return dest, err
+}
+
+// BindInto Bind a request into dest, which must be a pointer to a struct, and validate it.
+//
+// The JSON body is decoded with the app JSONDecoder, so the JSONConfig of the web server is
+// used. Then the fields tagged with path, query or header are set from the path params, query
+// args and headers, converting them to the field type. Slices are set from repeated or comma
+// separated values.
+//
+// The struct is validated with the validate tag. See Validate. Invalid JSON bodies return a 400
+// HTTPError and invalid params or fields a 422 HTTPError listing every invalid field, which
+// the error handler sends as a problem details response.
+func BindInto(c *fiber.Ctx, dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return InternalServerError(fmt.Errorf("bind destination must be a pointer to a struct, got %T", dest))
+	}
+
+	contentType := strings.ToLower(string(c.Request().Header.ContentType()))
+	if len(c.Body()) > 0 && (contentType == "" || strings.Contains(contentType, "json")) {
+		if err := c.App().Config().JSONDecoder(c.Body(), dest); err != nil {
+			return BadRequest("invalid JSON body").WithCause(err)
+		}
+	}
+
+	if details := bindParams(c, value.Elem()); len(details) > 0 {
+		return UnprocessableEntity("invalid request", details...)
+	}
+	return Validate(dest)
+}
+
+// bindParams Set the path, query and header fields of a struct
+func bindParams(c *fiber.Ctx, value reflect.Value) []ErrorDetail {
+	details := []ErrorDetail{}
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			details = append(details, bindParams(c, value.Field(index))...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, source := range []string{TagPath, TagQuery, TagHeader} {
+			name := tagName(field, source)
+			if name == "" {
+				continue
+			}
+
+			values := requestValues(c, source, name)
+			if len(values) == 0 {
+				continue
+			}
+
+			if err := setValues(value.Field(index), values); err != nil {
+				details = append(details, ErrorDetail{
+					Field:   name,
+					Code:    "type",
+					Message: fmt.Sprintf("must be a valid %s", typeName(field.Type)),
+				})
+			}
+		}
+	}
+	return details
+}
+
+// requestValues Get the values of a path param, query arg or header
+func requestValues(c *fiber.Ctx, source string, name string) []string {
+	values := []string{}
+	switch source {
+	case TagPath:
+		if value := c.Params(name); value != "" {
+			values = append(values, value)
+		}
+	case TagQuery:
+		for _, value := range c.Context().QueryArgs().PeekMulti(name) {
+			values = append(values, string(value))
+		}
+	case TagHeader:
+		if value := c.Get(name); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// setValues Set a field from its request values
+func setValues(field reflect.Value, values []string) error {
+	if field.Kind() != reflect.Slice || field.Type().Implements(textUnmarshalerType) || reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		return setValue(field, values[len(values)-1])
+	}
+
+	items := reflect.MakeSlice(field.Type(), 0, len(values))
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			element := reflect.New(field.Type().Elem()).Elem()
+			if err := setValue(element, strings.TrimSpace(item)); err != nil {
+				return err
+			}
+			items = reflect.Append(items, element)
+		}
+	}
+	field.Set(items)
+	return nil
+}
+
+// setValue Convert a request value into the field type
+func setValue(field reflect.Value, value string) error {
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.Pointer:
+		element := reflect.New(field.Type().Elem())
+		if err := setValue(element.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(element)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == durationType {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(parsed))
+			return nil
+		}
+
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate Validate a struct with the rules of its validate tags, returning a 422 HTTPError
+// listing every invalid field, or nil.
+//
+// Rules are separated by commas:
+//   - required: the field must not be zero, nil or without items. Other rules are skipped
+//     for nil pointers and empty strings, slices and maps, so they are optional.
+//   - min=N, max=N: the minimum and maximum value of numbers, or length of strings, slices and maps.
+//   - len=N: the exact length of strings, slices and maps.
+//   - enum=a b c: the field must be one of the values, separated by spaces.
+//   - email: the field must be an email address.
+//   - regex=PATTERN: the field must match the pattern. It must be the last rule, so the
+//     pattern can have commas.
+//
+// Nested structs, pointers to structs and slices of structs are validated too. Fields are
+// reported by their json, path, query or header name, such as items[0].sku.
+func Validate(value interface{}) error {
+	details := validateStruct(reflect.Indirect(reflect.ValueOf(value)), "")
+	if len(details) > 0 {
+		return UnprocessableEntity("invalid request", details...)
+	}
+	return nil
+}
+
+// validateStruct Validate the fields of a struct
+func validateStruct(value reflect.Value, prefix string) []ErrorDetail {
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	details := []ErrorDetail{}
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		rules := field.Tag.Get(TagValidate)
+		if rules == "-" {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && fieldName(field) == field.Name {
+			details = append(details, validateStruct(value.Field(index), prefix)...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		path := fieldName(field)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		fieldDetails := validateField(value.Field(index), rules, path)
+		details = append(details, fieldDetails...)
+		if len(fieldDetails) == 0 {
+			details = append(details, validateNested(value.Field(index), path)...)
+		}
+	}
+	return details
+}
+
+// validateNested Validate the nested structs of a field
+func validateNested(value reflect.Value, path string) []ErrorDetail {
+	value = reflect.Indirect(value)
+	switch {
+	case value.Kind() == reflect.Struct && value.Type() != timeType:
+		return validateStruct(value, path)
+	case value.Kind() == reflect.Slice || value.Kind() == reflect.Array:
+		details := []ErrorDetail{}
+		for index := 0; index < value.Len(); index++ {
+			details = append(details, validateNested(value.Index(index), fmt.Sprintf("%s[%d]", path, index))...)
+		}
+		return details
+	}
+	return nil
+}
+
+// validateField Validate a field with its rules
+func validateField(value reflect.Value, rules string, path string) []ErrorDetail {
+	if rules == "" {
+		return nil
+	}
+
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	details := []ErrorDetail{}
+	for _, rule := range parseRules(rules) {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "required" {
+			if isEmpty(value) {
+				return []ErrorDetail{{Field: path, Code: name, Message: "is required"}}
+			}
+			continue
+		}
+
+		if isOmitted(value) {
+			return nil
+		}
+
+		if message, ok := checkRule(value, name, param); !ok {
+			details = append(details, ErrorDetail{Field: path, Code: name, Message: message})
+		}
+	}
+	return details
+}
+
+// parseRules Split the rules of a validate tag. The regex rule takes the rest of the tag.
+func parseRules(rules string) []string {
+	parsed := []string{}
+	for rules != "" {
+		if strings.HasPrefix(rules, "regex=") {
+			return append(parsed, rules)
+		}
+
+		rule, rest, _ := strings.Cut(rules, ",")
+		if rule = strings.TrimSpace(rule); rule != "" {
+			parsed = append(parsed, rule)
+		}
+		rules = strings.TrimSpace(rest)
+	}
+	return parsed
+}
+
+// checkRule Check a rule, returning the error message if it fails
+func checkRule(value reflect.Value, name string, param string) (string, bool) {
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("goweb: invalid %s rule parameter %q", name, param))
+		}
+
+		size, unit, ok := measure(value)
+		if !ok {
+			panic(fmt.Sprintf("goweb: %s rule is not supported by %s", name, value.Type()))
+		}
+
+		switch {
+		case name == "min" && size < limit:
+			return strings.TrimSpace(fmt.Sprintf("must be at least %s %s", param, unit)), false
+		case name == "max" && size > limit:
+			return strings.TrimSpace(fmt.Sprintf("must be at most %s %s", param, unit)), false
+		case name == "len" && size != limit:
+			return strings.TrimSpace(fmt.Sprintf("must have exactly %s %s", param, unit)), false
+		}
+	case "enum":
+		options := strings.Fields(param)
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if option == actual {
+				return "", true
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(options, ", ")), false
+	case "email":
+		text := fmt.Sprint(value.Interface())
+		if address, err := mail.ParseAddress(text); err != nil || address.Address != text {
+			return "must be a valid email", false
+		}
+	case "regex":
+		if !compileRegex(param).MatchString(fmt.Sprint(value.Interface())) {
+			return fmt.Sprintf("must match %s", param), false
+		}
+	default:
+		panic(fmt.Sprintf("goweb: unknown validation rule %q", name))
+	}
+	return "", true
+}
+
+// measure Get the value of numbers or the length of strings, slices and maps, with its unit
+func measure(value reflect.Value) (float64, string, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), "characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "", true
+	}
+	return 0, "", false
+}
+
+// isOmitted Check if an optional value was not sent: nil or without items
+func isOmitted(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Pointer, reflect.Interface, reflect.Invalid:
+		return !value.IsValid() || value.IsNil()
+	}
+	return false
+}
+
+// compileRegex Compile a regex rule, caching it
+func compileRegex(pattern string) *regexp.Regexp {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	compiled := regexp.MustCompile(pattern)
+	regexCache.Store(pattern, compiled)
+	return compiled
+}
+
+// isEmpty Check if a value is empty: nil, zero or without items
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Invalid:
+		return true
+	}
+	return value.IsZero()
+}
+
+// tagName Get the name of a field in a tag, without its options
+func tagName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// fieldName Get the name of a field reported to the clients
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{TagJSON, TagPath, TagQuery, TagHeader} {
+		if name := tagName(field, tag); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// typeName Get a readable name of a field type
+func typeName(fieldType reflect.Type) string {
+	for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+		fieldType = fieldType.Elem()
+	}
+
+	switch {
+	case fieldType == durationType:
+		return "duration"
+	case fieldType == timeType:
+		return "time"
+	case fieldType.Kind() == reflect.Bool:
+		return "boolean"
+	case fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Uint64:
+		return "integer"
+	case fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64:
+		return "number"
+	}
+	return fieldType.Kind().String()
+}
@@ -0,0 +1,145 @@
+package goweb
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderItem struct {
+	SKU      string `json:"sku" validate:"required,regex=^[A-Z]{3}-[0-9]+$"`
+	Quantity int    `json:"quantity" validate:"min=1,max=10"`
+}
+
+type orderAddress struct {
+	Zip string `json:"zip" validate:"required,len=5"`
+}
+
+type createOrderRequest struct {
+	TenantID string        `header:"X-Tenant-ID" validate:"required"`
+	UserID   int64         `path:"id" validate:"min=1"`
+	DryRun   bool          `query:"dry_run"`
+	Tags     []string      `query:"tag" validate:"max=3"`
+	Timeout  time.Duration `query:"timeout"`
+	Email    string        `json:"email" validate:"required,email"`
+	Status   string        `json:"status" validate:"enum=draft placed"`
+	Note     *string       `json:"note" validate:"max=5"`
+	Address  *orderAddress `json:"address" validate:"required"`
+	Items    []orderItem   `json:"items" validate:"required,min=1"`
+}
+
+func TestBind(t *testing.T) {
+	decoded := 0
+	ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{
+		JSONConfig: JSONConfig{
+			Decoder: func(data []byte, v interface{}) error {
+				decoded++
+				return json.Unmarshal(data, v)
+			},
+		},
+	}))
+
+	app := ws.GetApp()
+	app.Post("/users/:id/orders", func(c *fiber.Ctx) error {
+		request, err := Bind[createOrderRequest](c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(request)
+	})
+
+	body := `{"email":"john@doe.com","status":"placed","address":{"zip":"12345"},"items":[{"sku":"ABC-1","quantity":2}]}`
+	request := httptest.NewRequest(http.MethodPost, "/users/10/orders?dry_run=true&tag=a&tag=b,c&timeout=2s", strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.Header.Set("X-Tenant-ID", "acme")
+	resp, err := app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, decoded, "the configured JSON decoder should be used")
+
+	var bound createOrderRequest
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&bound))
+	assert.Equal(t, "acme", bound.TenantID)
+	assert.Equal(t, int64(10), bound.UserID)
+	assert.True(t, bound.DryRun)
+	assert.Equal(t, []string{"a", "b", "c"}, bound.Tags)
+	assert.Equal(t, 2*time.Second, bound.Timeout)
+	assert.Equal(t, "12345", bound.Address.Zip)
+
+	body = `{"email":"john","status":"paid","note":"too long","address":{"zip":"123"},"items":[{"sku":"abc","quantity":20},{"quantity":1}]}`
+	request = httptest.NewRequest(http.MethodPost, "/users/0/orders?tag=a,b,c,d", strings.NewReader(body))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err = app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+
+	var problem Problem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, []ErrorDetail{
+		{Field: "X-Tenant-ID", Code: "required", Message: "is required"},
+		{Field: "id", Code: "min", Message: "must be at least 1"},
+		{Field: "tag", Code: "max", Message: "must be at most 3 items"},
+		{Field: "email", Code: "email", Message: "must be a valid email"},
+		{Field: "status", Code: "enum", Message: "must be one of draft, placed"},
+		{Field: "note", Code: "max", Message: "must be at most 5 characters"},
+		{Field: "address.zip", Code: "len", Message: "must have exactly 5 characters"},
+		{Field: "items[0].sku", Code: "regex", Message: "must match ^[A-Z]{3}-[0-9]+$"},
+		{Field: "items[0].quantity", Code: "max", Message: "must be at most 10"},
+		{Field: "items[1].sku", Code: "required", Message: "is required"},
+	}, problem.Errors)
+
+	request = httptest.NewRequest(http.MethodPost, "/users/abc/orders", strings.NewReader(`{"email":`))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err = app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "malformed bodies should be bad requests")
+
+	request = httptest.NewRequest(http.MethodPost, "/users/abc/orders", nil)
+	resp, err = app.Test(request)
+	assert.NoError(t, err)
+	raw, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Contains(t, string(raw), `{"field":"id","code":"type","message":"must be a valid integer"}`)
+}
+
+func TestValidate(t *testing.T) {
+	type embedded struct {
+		Name string `json:"name" validate:"required,min=2"`
+	}
+
+	type request struct {
+		embedded
+		Pattern string  `json:"pattern" validate:"regex=^a,b$"`
+		Score   float64 `json:"score" validate:"max=1.5"`
+		Hidden  string  `validate:"-"`
+	}
+
+	err := Validate(&request{embedded: embedded{Name: "jo"}, Pattern: "a,b", Score: 1})
+	assert.NoError(t, err)
+
+	err = Validate(request{Pattern: "ab", Score: 2})
+	var httpErr *HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, httpErr.Status)
+	assert.Equal(t, []ErrorDetail{
+		{Field: "name", Code: "required", Message: "is required"},
+		{Field: "pattern", Code: "regex", Message: "must match ^a,b$"},
+		{Field: "score", Code: "max", Message: "must be at most 1.5"},
+	}, httpErr.Details)
+
+	assert.Equal(t, []string{"required", "min=1", "regex=^a{1,2}$"}, parseRules("required, min=1,regex=^a{1,2}$"))
+	assert.Panics(t, func() {
+		_ = Validate(struct {
+			Name string `validate:"unknown"`
+		}{Name: "john"})
+	})
+}
@@ -0,0 +1,172 @@
+package goweb
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// APIVersionHeader is the response header with the API version of the routes in a version group
+const APIVersionHeader = "API-Version"
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+|-fm)+$`)
+
+// RouteGroup is a group of routes sharing a path prefix and middlewares. Its middlewares run
+// before the handlers of its routes and of its nested groups, in the order they were added.
+type RouteGroup struct {
+	prefix      string
+	middlewares []func(c *fiber.Ctx) error
+	routes      []WebRoute
+	groups      []*RouteGroup
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method string
+	Path   string
+	// Middlewares are the names of the handlers running before the route handler
+	Middlewares []string
+	Handler     string
+}
+
+// Group Create a new route group with the path prefix and middlewares
+func (ws *WebServer) Group(prefix string, middlewares ...func(c *fiber.Ctx) error) *RouteGroup {
+	group := &RouteGroup{prefix: prefix, middlewares: middlewares}
+	ws.groups = append(ws.groups, group)
+	return group
+}
+
+// Version Create a new route group for a version of the API, such as /v1. Its responses have
+// the API-Version header.
+func (ws *WebServer) Version(version string, middlewares ...func(c *fiber.Ctx) error) *RouteGroup {
+	return ws.Group("/"+version, append([]func(c *fiber.Ctx) error{versionHeader(version)}, middlewares...)...)
+}
+
+// Group Create a nested route group with the path prefix and middlewares
+func (rg *RouteGroup) Group(prefix string, middlewares ...func(c *fiber.Ctx) error) *RouteGroup {
+	group := &RouteGroup{prefix: prefix, middlewares: middlewares}
+	rg.groups = append(rg.groups, group)
+	return group
+}
+
+// Version Create a nested route group for a version of the API, such as /api/v1
+func (rg *RouteGroup) Version(version string, middlewares ...func(c *fiber.Ctx) error) *RouteGroup {
+	return rg.Group("/"+version, append([]func(c *fiber.Ctx) error{versionHeader(version)}, middlewares...)...)
+}
+
+// Use Add middlewares to the group
+func (rg *RouteGroup) Use(middlewares ...func(c *fiber.Ctx) error) *RouteGroup {
+	rg.middlewares = append(rg.middlewares, middlewares...)
+	return rg
+}
+
+// AddRoutes Add routes to the group. Their paths are relative to the group prefix.
+func (rg *RouteGroup) AddRoutes(routes ...WebRoute) *RouteGroup {
+	rg.routes = append(rg.routes, routes...)
+	return rg
+}
+
+// flatten Get the routes of the group and its nested groups, with the full paths and the
+// middlewares before their handlers
+func (rg *RouteGroup) flatten(prefix string, middlewares []func(c *fiber.Ctx) error) []WebRoute {
+	prefix = joinPath(prefix, rg.prefix)
+	middlewares = append(append([]func(c *fiber.Ctx) error{}, middlewares...), rg.middlewares...)
+
+	routes := []WebRoute{}
+	for _, route := range rg.routes {
+		route.Path = joinPath(prefix, route.Path)
+		route.Handlers = append(append([]func(c *fiber.Ctx) error{}, middlewares...), route.Handlers...)
+		routes = append(routes, route)
+	}
+
+	for _, group := range rg.groups {
+		routes = append(routes, group.flatten(prefix, middlewares)...)
+	}
+	return routes
+}
+
+// routes Get all the routes of the web server, followed by the routes of its groups
+func (ws *WebServer) routes() []WebRoute {
+	routes := append([]WebRoute{}, ws.routers...)
+	for _, group := range ws.groups {
+		routes = append(routes, group.flatten("", nil)...)
+	}
+	return routes
+}
+
+// Routes Get the routes of the web server and its groups, in the order they are registered
+func (ws *WebServer) Routes() []RouteInfo {
+	infos := []RouteInfo{}
+	for _, route := range ws.routes() {
+		info := RouteInfo{Method: route.Method, Path: route.Path, Middlewares: []string{}}
+		for index, handler := range route.Handlers {
+			if index == len(route.Handlers)-1 {
+				info.Handler = handlerName(handler)
+			} else {
+				info.Middlewares = append(info.Middlewares, handlerName(handler))
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// RouteTable Format the routes of the web server as a table, for debugging
+func (ws *WebServer) RouteTable() string {
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "METHOD\tPATH\tMIDDLEWARES\tHANDLER")
+	for _, route := range ws.Routes() {
+		middlewares := strings.Join(route.Middlewares, ", ")
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", route.Method, route.Path, defaultIfEmpty(middlewares, "-"), route.Handler)
+	}
+	_ = writer.Flush()
+	return builder.String()
+}
+
+// versionHeader Creates a middleware setting the API-Version header
+func versionHeader(version string) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		c.Set(APIVersionHeader, version)
+		return c.Next()
+	}
+}
+
+// handlerName Get the name of a handler function, such as goweb.TimeoutMiddleware
+func handlerName(handler func(c *fiber.Ctx) error) string {
+	runtimeFunc := runtime.FuncForPC(reflect.ValueOf(handler).Pointer())
+	if runtimeFunc == nil {
+		return "unknown"
+	}
+
+	name := runtimeFunc.Name()
+	if index := strings.LastIndex(name, "/"); index >= 0 {
+		name = name[index+1:]
+	}
+	// Generic functions are named with [...]
+	name = closureSuffix.ReplaceAllString(strings.ReplaceAll(name, "[...]", ""), "")
+
+	// Closures of inlined functions are named with their callers, such as
+	// goweb.TestRouteGroups.TimeoutMiddleware, so only the last function is kept
+	pkg, function, _ := strings.Cut(name, ".")
+	segments := strings.Split(function, ".")
+	function = segments[len(segments)-1]
+	if len(segments) > 1 && strings.HasPrefix(segments[len(segments)-2], "(") {
+		function = segments[len(segments)-2] + "." + function
+	}
+	return pkg + "." + function
+}
+
+// joinPath Join a prefix and a route path
+func joinPath(prefix string, routePath string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if routePath == "" || routePath == "/" {
+		return defaultIfEmpty(prefix, "/")
+	}
+	return prefix + "/" + strings.TrimPrefix(routePath, "/")
+}
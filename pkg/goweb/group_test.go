@@ -0,0 +1,92 @@
+package goweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// requireTenant rejects the requests without a tenant
+func requireTenant(c *fiber.Ctx) error {
+	if c.Get("X-Tenant-ID") == "" {
+		return Unauthorized("missing tenant")
+	}
+	return c.Next()
+}
+
+// listUsers
+func listUsers(c *fiber.Ctx) error {
+	return c.SendString("users")
+}
+
+func TestRouteGroups(t *testing.T) {
+	ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{}))
+	ws.AddRoutes(WebRoute{Method: http.MethodGet, Path: "/health", Handlers: []func(c *fiber.Ctx) error{listUsers}})
+
+	api := ws.Group("/api/", requireTenant)
+	v1 := api.Version("v1")
+	v1.AddRoutes(WebRoute{Method: http.MethodGet, Path: "/users", Handlers: []func(c *fiber.Ctx) error{listUsers}})
+	v1.Group("/admin", TimeoutMiddleware(time.Second)).AddRoutes(
+		WebRoute{Method: http.MethodGet, Path: "/", Handlers: []func(c *fiber.Ctx) error{listUsers}},
+		Handle(http.MethodGet, "/users/:id", func(c *fiber.Ctx, req getUserRequest) (user, error) {
+			return user{ID: req.ID}, nil
+		}),
+	)
+	api.Use(func(c *fiber.Ctx) error {
+		c.Set("X-Group", "api")
+		return c.Next()
+	})
+
+	assert.Equal(t, []RouteInfo{
+		{Method: http.MethodGet, Path: "/health", Middlewares: []string{}, Handler: "goweb.listUsers"},
+		{Method: http.MethodGet, Path: "/api/v1/users", Middlewares: []string{"goweb.requireTenant", "goweb.TestRouteGroups", "goweb.versionHeader"}, Handler: "goweb.listUsers"},
+		{Method: http.MethodGet, Path: "/api/v1/admin", Middlewares: []string{"goweb.requireTenant", "goweb.TestRouteGroups", "goweb.versionHeader", "goweb.TimeoutMiddleware"}, Handler: "goweb.listUsers"},
+		{Method: http.MethodGet, Path: "/api/v1/admin/users/:id", Middlewares: []string{"goweb.requireTenant", "goweb.TestRouteGroups", "goweb.versionHeader", "goweb.TimeoutMiddleware"}, Handler: "goweb.Handle"},
+	}, ws.Routes())
+
+	table := ws.RouteTable()
+	assert.True(t, strings.HasPrefix(table, "METHOD  PATH"), table)
+	assert.Contains(t, table, "GET     /health                  -")
+
+	_, ok := ws.OpenAPI().Paths["/api/v1/admin/users/{id}"]
+	assert.True(t, ok, "grouped routes should be documented with their full path")
+
+	app := ws.GetApp()
+	for _, route := range ws.routes() {
+		app.Add(route.Method, route.Path, route.Handlers...)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	request.Header.Set("X-Tenant-ID", "acme")
+	resp, err = app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "v1", resp.Header.Get(APIVersionHeader))
+	assert.Equal(t, "api", resp.Header.Get("X-Group"), "middlewares added later should apply to the group")
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "group middlewares should not run outside the group")
+	assert.Empty(t, resp.Header.Get(APIVersionHeader))
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Get("/slow", TimeoutMiddleware(10*time.Millisecond), func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return c.UserContext().Err()
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+}
@@ -0,0 +1,128 @@
+package goweb
+
+import (
+	"net/http"
+	"reflect"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// TypedHandler handles a request bound into Req, returning the Resp sent as JSON
+type TypedHandler[Req any, Resp any] func(c *fiber.Ctx, req Req) (Resp, error)
+
+// RouteDoc describes a route in the OpenAPI document
+type RouteDoc struct {
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Status is the status of the successful responses. Defaults to 200.
+	Status int
+	// Request is the type requests are bound into. See Bind.
+	Request reflect.Type
+	// Response is the type sent as JSON by successful responses. It is ignored for 204 responses.
+	Response reflect.Type
+}
+
+// RouteOption configures the documentation of a route
+type RouteOption func(doc *RouteDoc)
+
+// WithOperationID Set the OpenAPI operation id. Defaults to the method and path.
+func WithOperationID(operationID string) RouteOption {
+	return func(doc *RouteDoc) {
+		doc.OperationID = operationID
+	}
+}
+
+// WithSummary Set the route summary
+func WithSummary(summary string) RouteOption {
+	return func(doc *RouteDoc) {
+		doc.Summary = summary
+	}
+}
+
+// WithDescription Set the route description
+func WithDescription(description string) RouteOption {
+	return func(doc *RouteDoc) {
+		doc.Description = description
+	}
+}
+
+// WithTags Set the tags grouping the route in the Swagger UI
+func WithTags(tags ...string) RouteOption {
+	return func(doc *RouteDoc) {
+		doc.Tags = append(doc.Tags, tags...)
+	}
+}
+
+// WithStatus Set the status of the successful responses
+func WithStatus(status int) RouteOption {
+	return func(doc *RouteDoc) {
+		doc.Status = status
+	}
+}
+
+// WithDeprecated Mark the route as deprecated
+func WithDeprecated() RouteOption {
+	return func(doc *RouteDoc) {
+		doc.Deprecated = true
+	}
+}
+
+// Handle Creates a documented web route calling handler with the request bound into Req and
+// sending the Resp it returns as JSON. Req must be a struct or a pointer to a struct, use
+// struct{} for routes without input. See Bind for the binding and validation of the requests.
+//
+// The route is added to the OpenAPI document generated by the web server, which is served
+// with the Swagger UI. Errors are sent by the app ErrorHandler.
+func Handle[Req any, Resp any](method string, path string, handler TypedHandler[Req, Resp], opts ...RouteOption) WebRoute {
+	doc := &RouteDoc{
+		Status:   http.StatusOK,
+		Request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		Response: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+
+	for _, opt := range opts {
+		opt(doc)
+	}
+
+	return WebRoute{
+		Method: method,
+		Path:   path,
+		Handlers: []func(c *fiber.Ctx) error{
+			func(c *fiber.Ctx) error {
+				req, err := bindRequest[Req](c)
+				if err != nil {
+					return err
+				}
+
+				resp, err := handler(c, req)
+				if err != nil {
+					return err
+				}
+
+				if doc.Status == http.StatusNoContent {
+					return c.SendStatus(doc.Status)
+				}
+				return c.Status(doc.Status).JSON(resp)
+			},
+		},
+		Doc: doc,
+	}
+}
+
+// bindRequest binds the request of a typed handler. Pointer requests are bound into a new element.
+func bindRequest[Req any](c *fiber.Ctx) (Req, error) {
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	if reqType.Kind() != reflect.Pointer {
+		return Bind[Req](c)
+	}
+
+	var req Req
+	dest := reflect.New(reqType.Elem())
+	if err := BindInto(c, dest.Interface()); err != nil {
+		return req, err
+	}
+	return dest.Interface().(Req), nil
+}
@@ -0,0 +1,64 @@
+package goweb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type getUserRequest struct {
+	ID int64 `path:"id" validate:"min=1"`
+}
+
+type user struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+func TestHandle(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	for _, route := range []WebRoute{
+		Handle(http.MethodGet, "/users/:id", func(c *fiber.Ctx, req getUserRequest) (user, error) {
+			if req.ID == 404 {
+				return user{}, NotFound("user not found")
+			}
+			return user{ID: req.ID, Email: "john@doe.com"}, nil
+		}),
+		Handle(http.MethodDelete, "/users/:id", func(c *fiber.Ctx, req getUserRequest) (struct{}, error) {
+			return struct{}{}, nil
+		}, WithStatus(http.StatusNoContent)),
+		Handle(http.MethodPut, "/users/:id", func(c *fiber.Ctx, req *getUserRequest) (user, error) {
+			return user{ID: req.ID}, nil
+		}),
+	} {
+		app.Add(route.Method, route.Path, route.Handlers...)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/users/10", status: http.StatusOK, body: `{"id":10,"email":"john@doe.com"}`},
+		{method: http.MethodGet, path: "/users/404", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/users/0", status: http.StatusUnprocessableEntity},
+		{method: http.MethodDelete, path: "/users/10", status: http.StatusNoContent},
+		{method: http.MethodPut, path: "/users/10", status: http.StatusOK, body: `{"id":10,"email":""}`},
+		{method: http.MethodPut, path: "/users/0", status: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		resp, err := app.Test(httptest.NewRequest(test.method, test.path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, test.status, resp.StatusCode, test.path)
+		if test.body != "" {
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, test.body, string(body))
+		}
+	}
+}
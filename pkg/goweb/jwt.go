@@ -0,0 +1,94 @@
+package goweb
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/gocrypto"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const (
+	// jwtSubjectKey is the gocontext key of the sub claim of the request token
+	jwtSubjectKey = "jwt-subject"
+	// jwtScopeKey is the gocontext key of the scope claim of the request token
+	jwtScopeKey  = "jwt-scope"
+	bearerScheme = "Bearer"
+)
+
+// jwtClaimsKey defines the context key of the claims of the request token
+type jwtClaimsKey struct{}
+
+type JWTAuthConfig struct {
+	// Verifier verifies the bearer tokens. See gocrypto.NewJWT.
+	Verifier *gocrypto.JWT
+	// Optional lets the requests without a token through. Invalid tokens are still rejected.
+	Optional bool
+	// SkipPaths are the paths that are not authenticated. Paths ending with * are prefixes.
+	SkipPaths []string
+}
+
+// JWTMiddleware Creates a middleware authenticating the requests with the JWT bearer token of
+// the Authorization header.
+//
+// The claims of valid tokens are added to the fiber UserContext, see JWTClaims, JWTSubject
+// and Scopes. Requests without a valid token are rejected with a 401 HTTPError.
+func JWTMiddleware(config JWTAuthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skipPath(c.Path(), config.SkipPaths) {
+			return c.Next()
+		}
+
+		token, ok := bearerToken(c.Get(fiber.HeaderAuthorization))
+		if !ok {
+			if config.Optional {
+				return c.Next()
+			}
+
+			c.Set(fiber.HeaderWWWAuthenticate, bearerScheme)
+			return Unauthorized("missing bearer token").WithCode("missing_token")
+		}
+
+		claims, err := config.Verifier.Verify(c.UserContext(), token)
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, bearerScheme+` error="invalid_token"`)
+			message := "invalid bearer token"
+			if errors.Is(err, gocrypto.ErrTokenExpired) {
+				message = "expired bearer token"
+			}
+			return Unauthorized(message).WithCode("invalid_token").WithCause(err)
+		}
+
+		ctx := gocontext.Add(c.UserContext(), jwtSubjectKey, claims.Subject())
+		ctx = gocontext.Add(ctx, jwtScopeKey, claims.String("scope"))
+		c.SetUserContext(context.WithValue(ctx, jwtClaimsKey{}, claims))
+		return c.Next()
+	}
+}
+
+// JWTClaims Get the claims of the request token added by JWTMiddleware. They are shared by
+// the request handlers, so they must not be changed.
+func JWTClaims(ctx context.Context) (gocrypto.Claims, bool) {
+	claims, ok := ctx.Value(jwtClaimsKey{}).(gocrypto.Claims)
+	return claims, ok
+}
+
+// JWTSubject Get the sub claim of the request token added by JWTMiddleware
+func JWTSubject(ctx context.Context) string {
+	subject, _ := gocontext.Get[string](ctx, jwtSubjectKey)
+	return subject
+}
+
+// bearerToken Get the token of a bearer Authorization header
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, bearerScheme) {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,93 @@
+package goweb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocrypto"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTMiddleware(t *testing.T) {
+	key, err := gocrypto.GenerateJWTKey("key-1", gocrypto.AlgEdDSA)
+	assert.NoError(t, err)
+
+	jwt, err := gocrypto.NewJWT(gocrypto.JWTConfig{Keys: []gocrypto.JWTKey{key}, Issuer: "auth", Audience: []string{"users-api"}})
+	assert.NoError(t, err)
+
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Use(JWTMiddleware(JWTAuthConfig{Verifier: jwt, SkipPaths: []string{"/public/*"}}))
+	app.Get("/me", func(c *fiber.Ctx) error {
+		claims, ok := JWTClaims(c.UserContext())
+		assert.True(t, ok)
+		return c.JSON(map[string]interface{}{"sub": JWTSubject(c.UserContext()), "tenant": claims.String("tenant")})
+	})
+	app.Get("/public/status", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	token, err := jwt.Sign(gocrypto.Claims{"sub": "user-1", "tenant": "acme"})
+	assert.NoError(t, err)
+	expired, err := jwt.Sign(gocrypto.Claims{"sub": "user-1", "exp": time.Now().Add(-time.Hour).Unix()})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+		code          string
+	}{
+		{name: "should accept valid tokens", path: "/me", authorization: "Bearer " + token, status: http.StatusOK},
+		{name: "should accept lower case schemes", path: "/me", authorization: "bearer " + token, status: http.StatusOK},
+		{name: "should reject missing tokens", path: "/me", status: http.StatusUnauthorized, code: "missing_token"},
+		{name: "should reject other schemes", path: "/me", authorization: "Basic dXNlcjpwYXNz", status: http.StatusUnauthorized, code: "missing_token"},
+		{name: "should reject expired tokens", path: "/me", authorization: "Bearer " + expired, status: http.StatusUnauthorized, code: "invalid_token"},
+		{name: "should reject invalid tokens", path: "/me", authorization: "Bearer " + token + "x", status: http.StatusUnauthorized, code: "invalid_token"},
+		{name: "should skip paths", path: "/public/status", status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.authorization != "" {
+				request.Header.Set(fiber.HeaderAuthorization, test.authorization)
+			}
+
+			resp, err := app.Test(request)
+			assert.NoError(t, err)
+			assert.Equal(t, test.status, resp.StatusCode)
+
+			if test.path != "/me" {
+				return
+			}
+
+			body := map[string]interface{}{}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			if test.code != "" {
+				assert.Equal(t, test.code, body["code"])
+				assert.Contains(t, resp.Header.Get(fiber.HeaderWWWAuthenticate), "Bearer")
+			} else {
+				assert.Equal(t, map[string]interface{}{"sub": "user-1", "tenant": "acme"}, body)
+			}
+		})
+	}
+
+	app = fiber.New()
+	app.Use(JWTMiddleware(JWTAuthConfig{Verifier: jwt, Optional: true}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(JWTSubject(c.UserContext()))
+	})
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "optional authentication should accept missing tokens")
+
+	_, ok := JWTClaims(context.Background())
+	assert.False(t, ok)
+}
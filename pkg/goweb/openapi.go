@@ -0,0 +1,477 @@
+package goweb
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const (
+	openAPIVersion        = "3.0.3"
+	defaultOpenAPIVersion = "1.0.0"
+	defaultOpenAPIFile    = "openapi.json"
+	schemaRefPrefix       = "#/components/schemas/"
+)
+
+var (
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	invalidSchemaChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// OpenAPIDocument is an OpenAPI 3 document
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is an OpenAPI 3 schema
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// OpenAPI Generate the OpenAPI 3 document of the routes created with Handle.
+//
+// Schemas are generated from the request and response types: path, query and header fields
+// are parameters, the other fields are the JSON body, and the validate rules are converted
+// into constraints. Named structs are shared in the document components. Errors are
+// documented as problem details responses.
+func (ws *WebServer) OpenAPI() *OpenAPIDocument {
+	generator := &schemaGenerator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+	document := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title:   defaultIfEmpty(ws.swaggerConfig.Title, defaultSwaggerTitle),
+			Version: defaultIfEmpty(ws.swaggerConfig.Version, defaultOpenAPIVersion),
+		},
+		Paths: map[string]map[string]*OpenAPIOperation{},
+	}
+
+	problem := generator.schema(reflect.TypeOf(Problem{}))
+	for _, route := range ws.routes() {
+		if route.Doc == nil {
+			continue
+		}
+
+		routePath, operation := generator.operation(route)
+		operation.Responses["default"] = OpenAPIResponse{
+			Description: "Problem details",
+			Content:     map[string]OpenAPIMediaType{MIMEApplicationProblemJSON: {Schema: problem}},
+		}
+
+		if document.Paths[routePath] == nil {
+			document.Paths[routePath] = map[string]*OpenAPIOperation{}
+		}
+		document.Paths[routePath][strings.ToLower(route.Method)] = operation
+	}
+
+	document.Components.Schemas = generator.schemas
+	return document
+}
+
+// hasDocs Check if any route is documented
+func (ws *WebServer) hasDocs() bool {
+	for _, route := range ws.routes() {
+		if route.Doc != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// openAPIHandler Generate the OpenAPI document and create the handler serving it
+func (ws *WebServer) openAPIHandler() (fiber.Handler, error) {
+	document, err := ws.app.Config().JSONEncoder(ws.OpenAPI())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the OpenAPI document. Cause: %w", err)
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(document)
+	}, nil
+}
+
+// schemaGenerator generates the schemas of the Go types, registering the named structs
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// operation Generate the OpenAPI path and operation of a route
+func (sg *schemaGenerator) operation(route WebRoute) (string, *OpenAPIOperation) {
+	doc := route.Doc
+	routePath, pathParams := openAPIPath(route.Path)
+	operation := &OpenAPIOperation{
+		OperationID: defaultIfEmpty(doc.OperationID, operationID(route.Method, routePath)),
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Parameters:  []OpenAPIParameter{},
+		Responses:   map[string]OpenAPIResponse{},
+	}
+
+	request := doc.Request
+	for request != nil && request.Kind() == reflect.Pointer {
+		request = request.Elem()
+	}
+
+	if request != nil && request.Kind() == reflect.Struct {
+		operation.Parameters = sg.parameters(request)
+
+		body := sg.structSchema(request, true)
+		if len(body.Properties) > 0 && route.Method != http.MethodGet && route.Method != http.MethodHead {
+			if len(operation.Parameters) == 0 {
+				body = sg.schema(request)
+			}
+
+			operation.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]OpenAPIMediaType{fiber.MIMEApplicationJSON: {Schema: body}},
+			}
+		}
+	}
+
+	for _, name := range pathParams {
+		declared := false
+		for _, param := range operation.Parameters {
+			declared = declared || (param.In == TagPath && param.Name == name)
+		}
+
+		if !declared {
+			operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+				Name: name, In: TagPath, Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := OpenAPIResponse{Description: http.StatusText(status)}
+	if status != http.StatusNoContent && doc.Response != nil && !isEmptyStruct(doc.Response) {
+		response.Content = map[string]OpenAPIMediaType{fiber.MIMEApplicationJSON: {Schema: sg.schema(doc.Response)}}
+	}
+	operation.Responses[strconv.Itoa(status)] = response
+	return routePath, operation
+}
+
+// parameters Generate the parameters of the path, query and header fields of a struct
+func (sg *schemaGenerator) parameters(structType reflect.Type) []OpenAPIParameter {
+	params := []OpenAPIParameter{}
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			params = append(params, sg.parameters(field.Type)...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, source := range []string{TagPath, TagQuery, TagHeader} {
+			name := tagName(field, source)
+			if name == "" {
+				continue
+			}
+
+			schema := sg.schema(field.Type)
+			if derefType(field.Type) == durationType {
+				// Durations are sent as strings, such as 2s
+				schema = &Schema{Type: "string", Format: "duration"}
+			}
+
+			required := applyRules(schema, field.Type, field.Tag.Get(TagValidate))
+			params = append(params, OpenAPIParameter{
+				Name:     name,
+				In:       source,
+				Required: required || source == TagPath,
+				Schema:   schema,
+			})
+		}
+	}
+	return params
+}
+
+// schema Generate the schema of a type. Named structs are references to the components.
+func (sg *schemaGenerator) schema(schemaType reflect.Type) *Schema {
+	schemaType = derefType(schemaType)
+
+	switch {
+	case schemaType == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case schemaType.Implements(textMarshalerType) || reflect.PointerTo(schemaType).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch schemaType.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if schemaType.Kind() == reflect.Slice && schemaType.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: sg.schema(schemaType.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sg.schema(schemaType.Elem())}
+	case reflect.Struct:
+		if schemaType.Name() == "" {
+			return sg.structSchema(schemaType, false)
+		}
+
+		name, ok := sg.names[schemaType]
+		if !ok {
+			name = sg.schemaName(schemaType)
+			sg.names[schemaType] = name
+			// Registered before its fields, so recursive types are references
+			sg.schemas[name] = &Schema{}
+			*sg.schemas[name] = *sg.structSchema(schemaType, false)
+		}
+		return &Schema{Ref: schemaRefPrefix + name}
+	}
+	return &Schema{}
+}
+
+// structSchema Generate the object schema of a struct. Parameters are skipped from the bodies.
+func (sg *schemaGenerator) structSchema(structType reflect.Type, body bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		name := tagName(field, TagJSON)
+		if field.Tag.Get(TagJSON) == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && derefType(field.Type).Kind() == reflect.Struct {
+			embedded := sg.structSchema(derefType(field.Type), body)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if body && name == "" && (tagName(field, TagPath) != "" || tagName(field, TagQuery) != "" || tagName(field, TagHeader) != "") {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		propertySchema := sg.schema(field.Type)
+		if applyRules(propertySchema, field.Type, field.Tag.Get(TagValidate)) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = propertySchema
+	}
+	return schema
+}
+
+// schemaName Get the unique component name of a named type
+func (sg *schemaGenerator) schemaName(schemaType reflect.Type) string {
+	name := strings.Trim(invalidSchemaChars.ReplaceAllString(schemaType.Name(), "_"), "_")
+	if _, ok := sg.schemas[name]; ok {
+		// Types with the same name in different packages
+		name = path.Base(schemaType.PkgPath()) + "." + name
+		for suffix := 2; sg.schemas[name] != nil; suffix++ {
+			name = fmt.Sprintf("%s.%s%d", path.Base(schemaType.PkgPath()), schemaType.Name(), suffix)
+		}
+	}
+	return name
+}
+
+// applyRules Convert the validate rules into schema constraints, returning if the field is required
+func applyRules(schema *Schema, fieldType reflect.Type, rules string) bool {
+	required := false
+	kind := derefType(fieldType).Kind()
+	for _, rule := range parseRules(rules) {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "required" {
+			required = true
+			continue
+		}
+
+		if schema.Ref != "" {
+			// Siblings of references are ignored by OpenAPI 3.0
+			continue
+		}
+
+		switch name {
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+
+			size := int(limit)
+			switch {
+			case kind == reflect.String:
+				if name != "max" {
+					schema.MinLength = &size
+				}
+				if name != "min" {
+					schema.MaxLength = &size
+				}
+			case kind == reflect.Slice || kind == reflect.Array:
+				if name != "max" {
+					schema.MinItems = &size
+				}
+				if name != "min" {
+					schema.MaxItems = &size
+				}
+			case schema.Type == "integer" || schema.Type == "number":
+				if name != "max" {
+					schema.Minimum = &limit
+				}
+				if name != "min" {
+					schema.Maximum = &limit
+				}
+			}
+		case "enum":
+			for _, option := range strings.Fields(param) {
+				if value, err := strconv.ParseFloat(option, 64); err == nil && schema.Type != "string" {
+					schema.Enum = append(schema.Enum, value)
+				} else {
+					schema.Enum = append(schema.Enum, option)
+				}
+			}
+		case "email":
+			schema.Format = "email"
+		case "regex":
+			schema.Pattern = param
+		}
+	}
+	return required
+}
+
+// openAPIPath Convert a fiber path into an OpenAPI path, returning the names of its params
+func openAPIPath(routePath string) (string, []string) {
+	params := []string{}
+	segments := strings.Split(routePath, "/")
+	for index, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			name = strings.TrimSuffix(name, "?")
+			params = append(params, name)
+			segments[index] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID Generate the operation id of a route, such as getUsersId
+func operationID(method string, routePath string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToLower(method))
+
+	upper := true
+	for _, char := range routePath {
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			char = unicode.ToUpper(char)
+			upper = false
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
+
+// derefType Get the type pointed by pointer types
+func derefType(fieldType reflect.Type) reflect.Type {
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType
+}
+
+// isEmptyStruct Check if a type is a struct without fields, such as struct{}
+func isEmptyStruct(structType reflect.Type) bool {
+	structType = derefType(structType)
+	return structType.Kind() == reflect.Struct && structType.NumField() == 0
+}
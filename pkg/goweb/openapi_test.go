@@ -0,0 +1,119 @@
+package goweb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	Zip string `json:"zip" validate:"len=5"`
+}
+
+type createUserRequest struct {
+	Email     string    `json:"email" validate:"required,email"`
+	Age       int       `json:"age" validate:"min=18"`
+	Role      string    `json:"role" validate:"enum=admin member"`
+	Addresses []address `json:"addresses" validate:"max=3"`
+}
+
+type listUsersRequest struct {
+	TenantID string `header:"X-Tenant-ID" validate:"required"`
+	Page     int    `query:"page" validate:"min=1"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{
+		Swagger: WebServerSwaggerConfig{Title: "Users API", Route: "/swagger/*", Version: "2.0.0"},
+	}))
+
+	ws.AddRoutes(
+		Handle(http.MethodPost, "/users", func(c *fiber.Ctx, req createUserRequest) (user, error) {
+			return user{}, nil
+		}, WithStatus(http.StatusCreated), WithTags("users"), WithSummary("Create a user")),
+		Handle(http.MethodGet, "/users", func(c *fiber.Ctx, req listUsersRequest) ([]user, error) {
+			return nil, nil
+		}, WithOperationID("listUsers")),
+		Handle(http.MethodGet, "/users/:id/avatar/:size?", func(c *fiber.Ctx, req getUserRequest) (user, error) {
+			return user{}, nil
+		}, WithDeprecated()),
+		WebRoute{Method: http.MethodGet, Path: "/undocumented", Handlers: []func(c *fiber.Ctx) error{
+			func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) },
+		}},
+	)
+
+	document := ws.OpenAPI()
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Equal(t, OpenAPIInfo{Title: "Users API", Version: "2.0.0"}, document.Info)
+	assert.Len(t, document.Paths, 2, "only the documented routes should be in the document")
+
+	create := document.Paths["/users"]["post"]
+	assert.Equal(t, "postUsers", create.OperationID)
+	assert.Equal(t, []string{"users"}, create.Tags)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/createUserRequest"}, create.RequestBody.Content[fiber.MIMEApplicationJSON].Schema)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/user"}, create.Responses["201"].Content[fiber.MIMEApplicationJSON].Schema)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/Problem"}, create.Responses["default"].Content[MIMEApplicationProblemJSON].Schema)
+
+	min18, max3, len5 := float64(18), 3, 5
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"email":     {Type: "string", Format: "email"},
+			"age":       {Type: "integer", Format: "int64", Minimum: &min18},
+			"role":      {Type: "string", Enum: []interface{}{"admin", "member"}},
+			"addresses": {Type: "array", Items: &Schema{Ref: "#/components/schemas/address"}, MaxItems: &max3},
+		},
+		Required: []string{"email"},
+	}, document.Components.Schemas["createUserRequest"])
+	assert.Equal(t, &len5, document.Components.Schemas["address"].Properties["zip"].MinLength)
+
+	list := document.Paths["/users"]["get"]
+	assert.Equal(t, "listUsers", list.OperationID)
+	assert.Nil(t, list.RequestBody)
+	assert.Equal(t, []OpenAPIParameter{
+		{Name: "X-Tenant-ID", In: "header", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "page", In: "query", Schema: &Schema{Type: "integer", Format: "int64", Minimum: func() *float64 { v := float64(1); return &v }()}},
+	}, list.Parameters)
+	assert.Equal(t, "array", list.Responses["200"].Content[fiber.MIMEApplicationJSON].Schema.Type)
+
+	avatar := document.Paths["/users/{id}/avatar/{size}"]["get"]
+	assert.True(t, avatar.Deprecated)
+	assert.Len(t, avatar.Parameters, 2, "path params without fields should be documented")
+	assert.Equal(t, "size", avatar.Parameters[1].Name)
+
+	listenAddress := freeAddress(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ws.ListenContext(ctx, listenAddress)
+	}()
+	waitReady(t, ws)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/swagger/openapi.json", listenAddress))
+	assert.NoError(t, err)
+	var served map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&served))
+	resp.Body.Close()
+	assert.Equal(t, "Users API", served["info"].(map[string]interface{})["title"])
+
+	resp, err = http.Get(fmt.Sprintf("http://%s/swagger/index.html", listenAddress))
+	assert.NoError(t, err)
+	index, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.True(t, strings.Contains(string(index), "/swagger/openapi.json"), "the swagger UI should load the generated document")
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestOperationID(t *testing.T) {
+	assert.Equal(t, "getUsersIdAvatar", operationID(http.MethodGet, "/users/{id}/avatar"))
+	assert.Equal(t, "deleteV1ApiKeys", operationID(http.MethodDelete, "/v1/api-keys"))
+}
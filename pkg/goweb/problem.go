@@ -0,0 +1,231 @@
+package goweb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const (
+	// MIMEApplicationProblemJSON is the content type of the problem details responses (RFC 7807)
+	MIMEApplicationProblemJSON = "application/problem+json"
+	defaultProblemType         = "about:blank"
+)
+
+// HTTPError is an error with the HTTP response it should be sent as
+type HTTPError struct {
+	// Status is the HTTP status code.
+	Status int
+	// Code is a stable identifier of the error, such as not_found. Defaults to the status text
+	// in snake case.
+	Code string
+	// Message is a human readable explanation sent to the client.
+	Message string
+	// Details describe the error, such as the invalid fields of a request.
+	Details []ErrorDetail
+	// Cause is the underlying error. It is logged but never sent to the client.
+	Cause error
+}
+
+type ErrorDetail struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// Problem is the body of the problem details responses (RFC 7807)
+type Problem struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Status    int           `json:"status"`
+	Detail    string        `json:"detail,omitempty"`
+	Instance  string        `json:"instance,omitempty"`
+	Code      string        `json:"code,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Errors    []ErrorDetail `json:"errors,omitempty"`
+}
+
+// ErrorMapper Converts an error into an HTTPError. It returns false for the errors it does not handle.
+type ErrorMapper func(err error) (*HTTPError, bool)
+
+// NewHTTPError Creates a new HTTPError
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Code: statusCode(status), Message: message}
+}
+
+// BadRequest Creates a new 400 HTTPError
+func BadRequest(message string) *HTTPError {
+	return NewHTTPError(http.StatusBadRequest, message)
+}
+
+// Unauthorized Creates a new 401 HTTPError
+func Unauthorized(message string) *HTTPError {
+	return NewHTTPError(http.StatusUnauthorized, message)
+}
+
+// Forbidden Creates a new 403 HTTPError
+func Forbidden(message string) *HTTPError {
+	return NewHTTPError(http.StatusForbidden, message)
+}
+
+// NotFound Creates a new 404 HTTPError
+func NotFound(message string) *HTTPError {
+	return NewHTTPError(http.StatusNotFound, message)
+}
+
+// Conflict Creates a new 409 HTTPError
+func Conflict(message string) *HTTPError {
+	return NewHTTPError(http.StatusConflict, message)
+}
+
+// UnprocessableEntity Creates a new 422 HTTPError
+func UnprocessableEntity(message string, details ...ErrorDetail) *HTTPError {
+	return NewHTTPError(http.StatusUnprocessableEntity, message).WithDetails(details...)
+}
+
+// TooManyRequests Creates a new 429 HTTPError
+func TooManyRequests(message string) *HTTPError {
+	return NewHTTPError(http.StatusTooManyRequests, message)
+}
+
+// InternalServerError Creates a new 500 HTTPError caused by cause
+func InternalServerError(cause error) *HTTPError {
+	return NewHTTPError(http.StatusInternalServerError, "").WithCause(cause)
+}
+
+// WithCode Set the error code
+func (e *HTTPError) WithCode(code string) *HTTPError {
+	e.Code = code
+	return e
+}
+
+// WithDetails Add details to the error
+func (e *HTTPError) WithDetails(details ...ErrorDetail) *HTTPError {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
+// WithCause Set the underlying error
+func (e *HTTPError) WithCause(cause error) *HTTPError {
+	e.Cause = cause
+	return e
+}
+
+// Error
+func (e *HTTPError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.Status)
+	}
+
+	if e.Cause != nil {
+		return fmt.Sprintf("%d %s. Cause: %s", e.Status, message, e.Cause)
+	}
+	return fmt.Sprintf("%d %s", e.Status, message)
+}
+
+// Unwrap
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// Problem Convert the error into a problem details body
+func (e *HTTPError) Problem() Problem {
+	return Problem{
+		Type:   defaultProblemType,
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Detail: e.Message,
+		Code:   defaultIfEmpty(e.Code, statusCode(e.Status)),
+		Errors: e.Details,
+	}
+}
+
+// AsHTTPError Convert an error into an HTTPError using the mappers, then the default mappings
+// of *HTTPError, *fiber.Error, context and godb errors. Other errors are 500 errors.
+func AsHTTPError(err error, mappers ...ErrorMapper) *HTTPError {
+	for _, mapper := range mappers {
+		if httpErr, ok := mapper(err); ok && httpErr != nil {
+			return httpErr
+		}
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return NewHTTPError(fiberErr.Code, fiberErr.Message).WithCause(err)
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFound("resource not found").WithCause(err)
+	case errors.Is(err, godb.ErrStaleUpdate):
+		return Conflict("resource was changed by another request").WithCode("stale_update").WithCause(err)
+	case errors.Is(err, godb.ErrUnknownColumn), errors.Is(err, godb.ErrInvalidFilter):
+		return BadRequest("invalid query parameters").WithCause(err)
+	case errors.Is(err, godb.ErrQueryTimeout), errors.Is(err, godb.ErrStatementTimeout), errors.Is(err, context.DeadlineExceeded):
+		return NewHTTPError(http.StatusGatewayTimeout, "request timed out").WithCause(err)
+	case errors.Is(err, context.Canceled):
+		// The client went away, so the response is never read
+		return NewHTTPError(fiber.StatusRequestTimeout, "request canceled").WithCode("request_canceled").WithCause(err)
+	}
+	return InternalServerError(err)
+}
+
+// NewErrorHandler Creates a fiber ErrorHandler sending problem details responses (RFC 7807).
+// Errors are converted with AsHTTPError, and 5xx errors are logged with their cause.
+func NewErrorHandler(logger WebServerLogger, mappers ...ErrorMapper) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		httpErr := AsHTTPError(err, mappers...)
+
+		if logger != nil && httpErr.Status >= http.StatusInternalServerError {
+			var message string
+			if _, ok := gocontext.Get[string](c.UserContext(), "panic-error"); ok {
+				message = fmt.Sprintf("Recovered from panic. Cause: %s", err)
+			} else {
+				message = fmt.Sprintf("Unexpected error. Cause: %s", err)
+			}
+			logger.Error(c.UserContext(), message)
+		}
+
+		problem := httpErr.Problem()
+		problem.Instance = c.Path()
+		problem.RequestID = RequestID(c.UserContext())
+		return c.Status(httpErr.Status).JSON(problem, MIMEApplicationProblemJSON)
+	}
+}
+
+// handledErrorKey defines the fiber Locals key of the error sent by handleError
+type handledErrorKey struct{}
+
+// handleError Send an error returned by the next handlers with the app ErrorHandler, so
+// middlewares can read its status, and keep it for the outer middlewares. See handledError.
+func handleError(c *fiber.Ctx, err error) {
+	c.Locals(handledErrorKey{}, err)
+	if err := c.App().ErrorHandler(c, err); err != nil {
+		_ = c.SendStatus(http.StatusInternalServerError)
+	}
+}
+
+// handledError Get the error sent by handleError, if any
+func handledError(c *fiber.Ctx) error {
+	err, _ := c.Locals(handledErrorKey{}).(error)
+	return err
+}
+
+// statusCode Get the default error code of a status, such as not_found
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
@@ -0,0 +1,104 @@
+package goweb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var errPaymentRequired = errors.New("payment required")
+
+func TestAsHTTPError(t *testing.T) {
+	mapper := func(err error) (*HTTPError, bool) {
+		if errors.Is(err, errPaymentRequired) {
+			return NewHTTPError(http.StatusPaymentRequired, "upgrade your plan").WithCode("plan_limit"), true
+		}
+		return nil, false
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		code     string
+		expected string
+	}{
+		{name: "http error", err: fmt.Errorf("wrapped. %w", Conflict("email already used")), status: http.StatusConflict, code: "conflict", expected: "email already used"},
+		{name: "fiber error", err: fiber.ErrMethodNotAllowed, status: http.StatusMethodNotAllowed, code: "method_not_allowed", expected: "Method Not Allowed"},
+		{name: "no rows", err: fmt.Errorf("failed to find user. %w", sql.ErrNoRows), status: http.StatusNotFound, code: "not_found", expected: "resource not found"},
+		{name: "stale update", err: godb.ErrStaleUpdate, status: http.StatusConflict, code: "stale_update"},
+		{name: "unknown column", err: fmt.Errorf("%w. users.password_hash", godb.ErrUnknownColumn), status: http.StatusBadRequest, code: "bad_request", expected: "invalid query parameters"},
+		{name: "invalid filter", err: fmt.Errorf("%w. users.deleted_at", godb.ErrInvalidFilter), status: http.StatusBadRequest, code: "bad_request", expected: "invalid query parameters"},
+		{name: "query timeout", err: godb.ErrQueryTimeout, status: http.StatusGatewayTimeout, code: "gateway_timeout"},
+		{name: "custom mapper", err: fmt.Errorf("checkout. %w", errPaymentRequired), status: http.StatusPaymentRequired, code: "plan_limit", expected: "upgrade your plan"},
+		{name: "unknown error", err: errors.New("boom"), status: http.StatusInternalServerError, code: "internal_server_error"},
+	}
+
+	for _, test := range tests {
+		httpErr := AsHTTPError(test.err, mapper)
+		assert.Equal(t, test.status, httpErr.Status, test.name)
+		assert.Equal(t, test.code, httpErr.Code, test.name)
+		if test.expected != "" {
+			assert.Equal(t, test.expected, httpErr.Message, test.name)
+		}
+	}
+
+	httpErr := AsHTTPError(fmt.Errorf("%w. users.password_hash", godb.ErrUnknownColumn), mapper)
+	assert.ErrorIs(t, httpErr, godb.ErrUnknownColumn, "the query error should be kept as the cause")
+	assert.NotContains(t, httpErr.Message, "password_hash", "the query error should not be sent to the client")
+
+	err := InternalServerError(sql.ErrConnDone)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.Equal(t, "500 Internal Server Error. Cause: sql: connection is already closed", err.Error())
+}
+
+func TestErrorHandler(t *testing.T) {
+	logger := &loggerRecorder{}
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(logger)})
+	app.Use(AccessLogMiddleware(&loggerRecorder{}, AccessLogConfig{}))
+	app.Post("/users", func(c *fiber.Ctx) error {
+		return UnprocessableEntity("invalid user", ErrorDetail{Field: "email", Code: "required", Message: "email is required"})
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fmt.Errorf("failed to list users. %w", context.DeadlineExceeded)
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/users", nil)
+	request.Header.Set("X-Request-ID", "request-1")
+	resp, err := app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, MIMEApplicationProblemJSON, resp.Header.Get("Content-Type"))
+
+	var problem Problem
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &problem))
+	assert.Equal(t, Problem{
+		Type:      "about:blank",
+		Title:     "Unprocessable Entity",
+		Status:    http.StatusUnprocessableEntity,
+		Detail:    "invalid user",
+		Instance:  "/users",
+		Code:      "unprocessable_entity",
+		RequestID: "request-1",
+		Errors:    []ErrorDetail{{Field: "email", Code: "required", Message: "email is required"}},
+	}, problem)
+	assert.Empty(t, logger.messages, "4xx errors should not be logged")
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, []string{"error Unexpected error. Cause: failed to list users. context deadline exceeded"}, logger.messages)
+}
@@ -0,0 +1,456 @@
+package goweb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitSlidingWindow allows Limit requests per Window, weighting the requests of the
+	// previous window by how much of it still overlaps the sliding window
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+	// RateLimitTokenBucket allows bursts of Limit requests, refilling the whole bucket in Window
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+	// RateLimitConcurrency allows Limit requests at the same time
+	RateLimitConcurrency RateLimitAlgorithm = "concurrency"
+)
+
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+
+	defaultRateLimitWindow = time.Minute
+	// rateLimitSweepInterval is how often MemoryRateLimitStore deletes the expired states
+	rateLimitSweepInterval = time.Minute
+	// rateLimitUpdateAttempts is how many times DBRateLimitStore tries an update that
+	// conflicts with other replicas
+	rateLimitUpdateAttempts = 5
+)
+
+var ErrRateLimitConflict = errors.New("rate limit update conflict")
+
+// RateLimitKeyFunc returns the key whose requests are limited together. Requests with an
+// empty key are not limited.
+type RateLimitKeyFunc func(c *fiber.Ctx) string
+
+// RateLimitState is the stored state of a key
+type RateLimitState struct {
+	// Count is the available tokens of RateLimitTokenBucket, the requests of the current window
+	// of RateLimitSlidingWindow and the active requests of RateLimitConcurrency
+	Count float64
+	// Previous is the requests of the previous window of RateLimitSlidingWindow
+	Previous float64
+	// Time is the last refill of RateLimitTokenBucket and the start of the current window of
+	// RateLimitSlidingWindow
+	Time time.Time
+}
+
+// RateLimitStore stores the rate limit states, such as in memory for a single replica or in
+// a database shared by every replica
+type RateLimitStore interface {
+	// Update atomically replaces the state of the key with the one returned by update, which
+	// expires after ttl. Missing and expired states are zero. update can be called more than
+	// once and must not have side effects.
+	Update(ctx context.Context, key string, ttl time.Duration, update func(state RateLimitState) RateLimitState) error
+}
+
+type RateLimitConfig struct {
+	// Algorithm defaults to RateLimitSlidingWindow
+	Algorithm RateLimitAlgorithm
+	// Limit is the requests per window of RateLimitSlidingWindow, the bucket size of
+	// RateLimitTokenBucket and the concurrent requests of RateLimitConcurrency
+	Limit int
+	// Window is the sliding window of RateLimitSlidingWindow and the time to refill the whole
+	// bucket of RateLimitTokenBucket. For RateLimitConcurrency it is how long the active requests
+	// of a key are kept without new requests, in case a replica stops before releasing them.
+	// Defaults to 1 minute.
+	Window time.Duration
+	// Key defaults to KeyByIP
+	Key RateLimitKeyFunc
+	// Store defaults to a new MemoryRateLimitStore
+	Store RateLimitStore
+	// Name prefixes the keys, so the limits of different routes sharing a store are independent.
+	// Defaults to the algorithm.
+	Name string
+	// SkipPaths are the paths that are not limited. Paths ending with * are prefixes.
+	SkipPaths []string
+}
+
+// rateLimitDecision is the result of taking a request
+type rateLimitDecision struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// rateLimiter applies the algorithm of a RateLimitConfig
+type rateLimiter struct {
+	algorithm RateLimitAlgorithm
+	limit     float64
+	window    time.Duration
+	now       func() time.Time
+}
+
+// RateLimitMiddleware Creates a middleware limiting the requests of every key, such as the
+// client IP, its API key or its user. Use it in route groups for different limits per group.
+//
+// Every response has the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers, and the requests over the limit are rejected with a 429 HTTPError
+// and the Retry-After header.
+func RateLimitMiddleware(config RateLimitConfig) fiber.Handler {
+	if config.Limit <= 0 {
+		panic(fmt.Sprintf("goweb: invalid rate limit %d", config.Limit))
+	}
+
+	limiter := &rateLimiter{
+		algorithm: RateLimitAlgorithm(defaultIfEmpty(string(config.Algorithm), string(RateLimitSlidingWindow))),
+		limit:     float64(config.Limit),
+		window:    config.Window,
+		now:       time.Now,
+	}
+	if limiter.window <= 0 {
+		limiter.window = defaultRateLimitWindow
+	}
+
+	switch limiter.algorithm {
+	case RateLimitSlidingWindow, RateLimitTokenBucket, RateLimitConcurrency:
+	default:
+		panic(fmt.Sprintf("goweb: unknown rate limit algorithm %q", limiter.algorithm))
+	}
+
+	if config.Key == nil {
+		config.Key = KeyByIP
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	name := defaultIfEmpty(config.Name, string(limiter.algorithm))
+
+	return func(c *fiber.Ctx) error {
+		if skipPath(c.Path(), config.SkipPaths) {
+			return c.Next()
+		}
+
+		key := config.Key(c)
+		if key == "" {
+			return c.Next()
+		}
+		key = name + ":" + key
+
+		var decision rateLimitDecision
+		err := config.Store.Update(c.UserContext(), key, limiter.ttl(), func(state RateLimitState) RateLimitState {
+			state, decision = limiter.take(state, limiter.now())
+			return state
+		})
+		if err != nil {
+			return err
+		}
+
+		limiter.setHeaders(c, decision)
+		if !decision.allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(ceilSeconds(decision.retryAfter), 1)))
+			return TooManyRequests("rate limit exceeded").WithCode("rate_limited")
+		}
+
+		if limiter.algorithm == RateLimitConcurrency {
+			// The request is released even if the client is gone
+			defer func() {
+				_ = config.Store.Update(context.WithoutCancel(c.UserContext()), key, limiter.ttl(), limiter.release)
+			}()
+		}
+		return c.Next()
+	}
+}
+
+// KeyByIP Limit the requests by client IP
+func KeyByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// KeyByAPIKey Limit the requests by the API key added by APIKeyMiddleware, or by client IP
+func KeyByAPIKey(c *fiber.Ctx) string {
+	if prefix := APIKeyPrefix(c.UserContext()); prefix != "" {
+		return "api-key:" + prefix
+	}
+	return KeyByIP(c)
+}
+
+// KeyByUser Limit the requests by the token subject added by JWTMiddleware, or by client IP
+func KeyByUser(c *fiber.Ctx) string {
+	if subject := JWTSubject(c.UserContext()); subject != "" {
+		return "user:" + subject
+	}
+	return KeyByIP(c)
+}
+
+// ttl Get how long the states are kept
+func (rl *rateLimiter) ttl() time.Duration {
+	if rl.algorithm == RateLimitSlidingWindow {
+		// The current window is the previous window of the next one
+		return 2 * rl.window
+	}
+	return rl.window
+}
+
+// take Take a request from the state
+func (rl *rateLimiter) take(state RateLimitState, now time.Time) (RateLimitState, rateLimitDecision) {
+	switch rl.algorithm {
+	case RateLimitTokenBucket:
+		return rl.tokenBucket(state, now)
+	case RateLimitConcurrency:
+		if state.Count+1 > rl.limit {
+			return state, rateLimitDecision{retryAfter: time.Second}
+		}
+		state.Count++
+		return state, rateLimitDecision{allowed: true, remaining: int(rl.limit - state.Count)}
+	default:
+		return rl.slidingWindow(state, now)
+	}
+}
+
+// release Release a request of RateLimitConcurrency
+func (rl *rateLimiter) release(state RateLimitState) RateLimitState {
+	state.Count = math.Max(state.Count-1, 0)
+	return state
+}
+
+// slidingWindow Take a request from a sliding window state
+func (rl *rateLimiter) slidingWindow(state RateLimitState, now time.Time) (RateLimitState, rateLimitDecision) {
+	start := now.Truncate(rl.window)
+	switch {
+	case state.Time.Equal(start):
+	case state.Time.Equal(start.Add(-rl.window)):
+		state.Previous, state.Count = state.Count, 0
+	default:
+		state.Previous, state.Count = 0, 0
+	}
+	state.Time = start
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(rl.window)
+	decision := rateLimitDecision{reset: rl.window - elapsed}
+
+	if state.Previous*weight+state.Count+1 <= rl.limit {
+		state.Count++
+		decision.allowed = true
+		decision.remaining = int(rl.limit - state.Previous*weight - state.Count)
+		return state, decision
+	}
+
+	if state.Count+1 <= rl.limit {
+		// Wait for enough requests of the previous window to slide out
+		slide := float64(rl.window) * (1 - (rl.limit-1-state.Count)/state.Previous)
+		decision.retryAfter = time.Duration(slide) - elapsed
+	} else {
+		// Wait for enough requests of the current window to slide out of the next one
+		slide := float64(rl.window) * (1 - (rl.limit-1)/state.Count)
+		decision.retryAfter = rl.window - elapsed + time.Duration(slide)
+	}
+	return state, decision
+}
+
+// tokenBucket Take a request from a token bucket state
+func (rl *rateLimiter) tokenBucket(state RateLimitState, now time.Time) (RateLimitState, rateLimitDecision) {
+	perSecond := rl.limit / rl.window.Seconds()
+
+	tokens := rl.limit
+	if !state.Time.IsZero() {
+		elapsed := math.Max(now.Sub(state.Time).Seconds(), 0)
+		tokens = math.Min(state.Count+elapsed*perSecond, rl.limit)
+	}
+
+	decision := rateLimitDecision{}
+	if tokens >= 1 {
+		tokens--
+		decision.allowed = true
+		decision.remaining = int(tokens)
+	} else {
+		decision.retryAfter = time.Duration((1 - tokens) / perSecond * float64(time.Second))
+	}
+	decision.reset = time.Duration((rl.limit - tokens) / perSecond * float64(time.Second))
+
+	state.Count = tokens
+	state.Time = now
+	return state, decision
+}
+
+// setHeaders Set the RateLimit headers of a decision
+func (rl *rateLimiter) setHeaders(c *fiber.Ctx, decision rateLimitDecision) {
+	limit := strconv.Itoa(int(rl.limit))
+	c.Set(RateLimitLimitHeader, limit)
+	c.Set(RateLimitRemainingHeader, strconv.Itoa(decision.remaining))
+
+	if rl.algorithm == RateLimitConcurrency {
+		c.Set(RateLimitPolicyHeader, limit)
+		return
+	}
+
+	c.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(decision.reset)))
+	c.Set(RateLimitPolicyHeader, fmt.Sprintf("%s;w=%d", limit, ceilSeconds(rl.window)))
+}
+
+// ceilSeconds Get a duration in whole seconds, rounded up
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
+
+// memoryRateLimitEntry is a state stored by MemoryRateLimitStore
+type memoryRateLimitEntry struct {
+	state     RateLimitState
+	expiresAt time.Time
+}
+
+// MemoryRateLimitStore stores the rate limit states in memory, limiting each replica on its own
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	states    map[string]memoryRateLimitEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitStore Creates a new in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{states: map[string]memoryRateLimitEntry{}, now: time.Now}
+}
+
+// Update
+func (ms *MemoryRateLimitStore) Update(_ context.Context, key string, ttl time.Duration, update func(state RateLimitState) RateLimitState) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	now := ms.now()
+	if now.Sub(ms.lastSweep) >= rateLimitSweepInterval {
+		for stateKey, entry := range ms.states {
+			if !now.Before(entry.expiresAt) {
+				delete(ms.states, stateKey)
+			}
+		}
+		ms.lastSweep = now
+	}
+
+	state := RateLimitState{}
+	if entry, ok := ms.states[key]; ok && now.Before(entry.expiresAt) {
+		state = entry.state
+	}
+
+	ms.states[key] = memoryRateLimitEntry{state: update(state), expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// rateLimitRow is a state stored by DBRateLimitStore. The times are Unix nanoseconds, so
+// they are compared the same way by every database.
+type rateLimitRow struct {
+	_         struct{} `godb:"table:rate_limits"`
+	ID        string   `db:"id" godb:"pk"`
+	Count     float64  `db:"count"`
+	Previous  float64  `db:"previous"`
+	StateTime int64    `db:"state_time"`
+	ExpiresAt int64    `db:"expires_at"`
+	Version   int64    `db:"version"`
+}
+
+// DBRateLimitStore stores the rate limit states in the rate_limits table, limiting every
+// replica together. Concurrent updates of a key are retried with optimistic locking.
+//
+//	CREATE TABLE rate_limits (
+//		id         VARCHAR(255) PRIMARY KEY,
+//		count      DOUBLE PRECISION NOT NULL,
+//		previous   DOUBLE PRECISION NOT NULL,
+//		state_time BIGINT NOT NULL,
+//		expires_at BIGINT NOT NULL,
+//		version    BIGINT NOT NULL
+//	);
+type DBRateLimitStore struct {
+	db         godb.DB
+	repository *godb.Repository[rateLimitRow]
+	now        func() time.Time
+}
+
+// NewDBRateLimitStore Creates a new rate limit store using db
+func NewDBRateLimitStore(db godb.DB) (*DBRateLimitStore, error) {
+	repository, err := godb.NewRepository[rateLimitRow](db)
+	if err != nil {
+		return nil, err
+	}
+	return &DBRateLimitStore{db: db, repository: repository, now: time.Now}, nil
+}
+
+// Update
+func (ds *DBRateLimitStore) Update(ctx context.Context, key string, ttl time.Duration, update func(state RateLimitState) RateLimitState) error {
+	var lastErr error
+	for attempt := 0; attempt < rateLimitUpdateAttempts; attempt++ {
+		now := ds.now()
+		row, err := ds.repository.FindByID(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			row = rateLimitRow{ID: key}
+			row.set(update(RateLimitState{}), now.Add(ttl))
+			// Another replica can insert the key first
+			if lastErr = ds.repository.Insert(ctx, &row); lastErr == nil {
+				return nil
+			}
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		state := RateLimitState{}
+		if now.UnixNano() < row.ExpiresAt {
+			state = row.state()
+		}
+
+		row.set(update(state), now.Add(ttl))
+		lastErr = godb.UpdateVersioned(ctx, ds.db, godb.VersionConfig{Table: ds.repository.Table()}, &row)
+		if !errors.Is(lastErr, godb.ErrStaleUpdate) {
+			return lastErr
+		}
+	}
+	return fmt.Errorf("%w. key %s. %s", ErrRateLimitConflict, key, lastErr)
+}
+
+// DeleteExpired Delete the expired states, such as from a periodic job
+func (ds *DBRateLimitStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := ds.db.Rebind(fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", ds.repository.Table()))
+	result, err := ds.db.ExecContext(ctx, query, ds.now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// state Get the state of the row
+func (r *rateLimitRow) state() RateLimitState {
+	state := RateLimitState{Count: r.Count, Previous: r.Previous}
+	if r.StateTime != 0 {
+		state.Time = time.Unix(0, r.StateTime)
+	}
+	return state
+}
+
+// set Set the state of the row
+func (r *rateLimitRow) set(state RateLimitState, expiresAt time.Time) {
+	r.Count = state.Count
+	r.Previous = state.Previous
+	r.StateTime = 0
+	if !state.Time.IsZero() {
+		r.StateTime = state.Time.UnixNano()
+	}
+	r.ExpiresAt = expiresAt.UnixNano()
+}
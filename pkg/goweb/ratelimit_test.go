@@ -0,0 +1,208 @@
+package goweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb/godbtest"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Use(RateLimitMiddleware(RateLimitConfig{
+		Limit:     2,
+		Window:    time.Hour,
+		Key:       func(c *fiber.Ctx) string { return c.Get("X-Client") },
+		SkipPaths: []string{"/health"},
+	}))
+	app.Get("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	send := func(path string, client string) *http.Response {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if client != "" {
+			request.Header.Set("X-Client", client)
+		}
+
+		resp, err := app.Test(request)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := send("/", "a")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get(RateLimitLimitHeader))
+	assert.Equal(t, "1", resp.Header.Get(RateLimitRemainingHeader))
+	assert.Equal(t, "2;w=3600", resp.Header.Get(RateLimitPolicyHeader))
+	assert.NotEmpty(t, resp.Header.Get(RateLimitResetHeader))
+
+	assert.Equal(t, http.StatusOK, send("/", "a").StatusCode)
+	resp = send("/", "a")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get(RateLimitRemainingHeader))
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+
+	assert.Equal(t, http.StatusOK, send("/", "b").StatusCode, "keys should be limited independently")
+	assert.Equal(t, http.StatusOK, send("/health", "a").StatusCode, "skipped paths should not be limited")
+	for index := 0; index < 3; index++ {
+		assert.Equal(t, http.StatusOK, send("/", "").StatusCode, "empty keys should not be limited")
+	}
+
+	assert.Panics(t, func() { RateLimitMiddleware(RateLimitConfig{}) })
+	assert.Panics(t, func() { RateLimitMiddleware(RateLimitConfig{Limit: 1, Algorithm: "leaky_bucket"}) })
+}
+
+func TestRateLimitAlgorithms(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("sliding window", func(t *testing.T) {
+		limiter := &rateLimiter{algorithm: RateLimitSlidingWindow, limit: 10, window: time.Minute}
+
+		state := RateLimitState{}
+		var decision rateLimitDecision
+		for index := 0; index < 10; index++ {
+			state, decision = limiter.take(state, start.Add(30*time.Second))
+			assert.True(t, decision.allowed)
+		}
+		assert.Equal(t, 0, decision.remaining)
+		assert.Equal(t, 30*time.Second, decision.reset)
+
+		state, decision = limiter.take(state, start.Add(40*time.Second))
+		assert.False(t, decision.allowed)
+		assert.InDelta(t, 26*time.Second, decision.retryAfter, float64(time.Millisecond))
+
+		// Half of the previous window still overlaps the sliding window
+		state, decision = limiter.take(state, start.Add(90*time.Second))
+		assert.True(t, decision.allowed)
+		assert.Equal(t, 4, decision.remaining)
+		assert.Equal(t, 10.0, state.Previous)
+
+		_, decision = limiter.take(state, start.Add(3*time.Minute))
+		assert.True(t, decision.allowed)
+		assert.Equal(t, 9, decision.remaining, "older windows should be forgotten")
+	})
+
+	t.Run("token bucket", func(t *testing.T) {
+		limiter := &rateLimiter{algorithm: RateLimitTokenBucket, limit: 10, window: 10 * time.Second}
+
+		state := RateLimitState{}
+		var decision rateLimitDecision
+		for index := 0; index < 10; index++ {
+			state, decision = limiter.take(state, start)
+			assert.True(t, decision.allowed, "bursts up to the limit should be allowed")
+		}
+
+		state, decision = limiter.take(state, start.Add(500*time.Millisecond))
+		assert.False(t, decision.allowed)
+		assert.Equal(t, 500*time.Millisecond, decision.retryAfter)
+
+		_, decision = limiter.take(state, start.Add(3*time.Second))
+		assert.True(t, decision.allowed)
+		assert.Equal(t, 2, decision.remaining)
+		assert.Equal(t, 8*time.Second, decision.reset)
+	})
+}
+
+func TestRateLimitConcurrency(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Use(RateLimitMiddleware(RateLimitConfig{Algorithm: RateLimitConcurrency, Limit: 1}))
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		started <- struct{}{}
+		<-release
+		return c.SendStatus(http.StatusOK)
+	})
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/slow", nil), -1)
+		assert.NoError(t, err)
+		done <- resp.StatusCode
+	}()
+	<-started
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(t, "1", resp.Header.Get(RateLimitPolicyHeader))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "finished requests should be released")
+}
+
+func TestRateLimitStores(t *testing.T) {
+	dbStore, err := NewDBRateLimitStore(godbtest.NewSQLiteDB(t, godbtest.Config{Schema: []string{"testdata/rate_limits.sql"}}))
+	assert.NoError(t, err)
+
+	for name, store := range map[string]RateLimitStore{"memory": NewMemoryRateLimitStore(), "db": dbStore} {
+		t.Run(name, func(t *testing.T) {
+			// Both apps share the store, as the replicas of a service
+			replicas := make([]*fiber.App, 2)
+			for index := range replicas {
+				replicas[index] = fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+				replicas[index].Use(RateLimitMiddleware(RateLimitConfig{
+					Algorithm: RateLimitTokenBucket,
+					Limit:     3,
+					Window:    time.Hour,
+					Key:       KeyByAPIKey,
+					Store:     store,
+				}))
+				replicas[index].Get("/", func(c *fiber.Ctx) error {
+					return c.SendStatus(http.StatusOK)
+				})
+			}
+
+			statuses := []int{}
+			for index := 0; index < 4; index++ {
+				resp, err := replicas[index%2].Test(httptest.NewRequest(http.MethodGet, "/", nil))
+				assert.NoError(t, err)
+				statuses = append(statuses, resp.StatusCode)
+			}
+			assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, statuses)
+		})
+	}
+
+	ctx := context.Background()
+	ttl := time.Minute
+	increment := func(state RateLimitState) RateLimitState {
+		state.Count++
+		return state
+	}
+	assert.NoError(t, dbStore.Update(ctx, "expiring", ttl, increment))
+
+	dbStore.now = func() time.Time { return time.Now().Add(2 * ttl) }
+	var count float64
+	assert.NoError(t, dbStore.Update(ctx, "expiring", ttl, func(state RateLimitState) RateLimitState {
+		count = state.Count
+		return state
+	}))
+	assert.Equal(t, 0.0, count, "expired states should be zero")
+
+	dbStore.now = func() time.Time { return time.Now().Add(4 * ttl) }
+	deleted, err := dbStore.DeleteExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted, "only the expired states should be deleted")
+
+	memoryStore := NewMemoryRateLimitStore()
+	assert.NoError(t, memoryStore.Update(ctx, "expiring", ttl, increment))
+	memoryStore.now = func() time.Time { return time.Now().Add(2 * ttl) }
+	assert.NoError(t, memoryStore.Update(ctx, "other", ttl, increment))
+	assert.Len(t, memoryStore.states, 1, "expired states should be swept")
+}
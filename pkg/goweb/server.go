@@ -3,12 +3,18 @@ package goweb
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
@@ -18,7 +24,6 @@ import (
 
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/favicon"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
@@ -28,24 +33,47 @@ const (
 	defaultAppName      = "ms-backend-default"
 	defaultSwaggerTitle = "Default Swagger UI"
 	defaultSwaggerRoute = "/swagger/*"
+	defaultMaxRequests  = 5
+
+	defaultReadinessRoute  = "/readyz"
+	defaultShutdownTimeout = 30 * time.Second
 )
 
 type WebRoute struct {
 	Method   string
 	Path     string
 	Handlers []func(c *fiber.Ctx) error
+	// Doc describes the route in the OpenAPI document. Routes without it are not documented.
+	// See Handle.
+	Doc *RouteDoc
 }
 
+// ShutdownHook is called when the web server shuts down, such as to close a godb.DB
+type ShutdownHook func(ctx context.Context) error
+
 type WebServer struct {
-	app           *fiber.App
-	routers       []WebRoute
-	swaggerConfig WebServerSwaggerConfig
+	app            *fiber.App
+	routers        []WebRoute
+	groups         []*RouteGroup
+	swaggerConfig  WebServerSwaggerConfig
+	shutdownConfig ShutdownConfig
+	logger         WebServerLogger
+
+	ready         *atomic.Bool
+	hooksMutex    sync.Mutex
+	shutdownHooks []ShutdownHook
+	shutdownOnce  sync.Once
+	shutdownStart chan struct{}
+	shutdownErr   error
 }
 
 type WebServerConfig struct {
-	app           *fiber.App
-	routers       []WebRoute
-	swaggerConfig WebServerSwaggerConfig
+	app            *fiber.App
+	routers        []WebRoute
+	swaggerConfig  WebServerSwaggerConfig
+	shutdownConfig ShutdownConfig
+	logger         WebServerLogger
+	ready          *atomic.Bool
 }
 
 type CorsConfig struct {
@@ -57,7 +85,9 @@ type CorsConfig struct {
 }
 
 type RateLimiteConfig struct {
-	MaxRequests         int
+	// MaxRequests is the limit of requests per interval. Defaults to 5.
+	MaxRequests int
+	// MaxRequestsInterval is the sliding window of the limit. Defaults to 1 minute.
 	MaxRequestsInterval time.Duration
 }
 
@@ -68,6 +98,22 @@ type ProfilingConfig struct {
 type WebServerSwaggerConfig struct {
 	Title string
 	Route string
+	// Version is the API version of the OpenAPI document generated from the routes created
+	// with Handle. Defaults to 1.0.0.
+	Version string
+}
+
+type ShutdownConfig struct {
+	// Signals start the shutdown. Defaults to SIGINT and SIGTERM.
+	Signals []os.Signal
+	// DrainPeriod is how long the web server keeps serving requests after the readiness
+	// route starts failing, so load balancers can stop sending new requests.
+	DrainPeriod time.Duration
+	// Timeout is the maximum time to wait for the active requests. Defaults to 30 seconds.
+	Timeout time.Duration
+	// ReadinessRoute responds 200 while the web server is ready and 503 once the shutdown
+	// starts. Defaults to /readyz.
+	ReadinessRoute string
 }
 
 type WebServerLogger interface {
@@ -83,13 +129,23 @@ type JSONConfig struct {
 }
 
 type WebServerDefaultConfig struct {
-	AppName    string
-	Cors       CorsConfig
-	Swagger    WebServerSwaggerConfig
+	AppName string
+	Cors    CorsConfig
+	Swagger WebServerSwaggerConfig
+	// RateLimite limits the requests of every route by client IP. See RateLimitMiddleware for
+	// other keys, algorithms and stores, and for limits per route group.
 	RateLimite RateLimiteConfig
 	Profiling  ProfilingConfig
 	Logger     WebServerLogger
 	JSONConfig JSONConfig
+	Shutdown   ShutdownConfig
+	// AccessLog logs every request through Logger when enabled
+	AccessLog AccessLogConfig
+	// Tracing starts a Datadog span per request when enabled
+	Tracing TracingConfig
+	// ErrorMappers convert the errors returned by the handlers into HTTP errors, before the
+	// default mappings. See AsHTTPError.
+	ErrorMappers []ErrorMapper
 }
 
 // DefaultConfig Build the web server default configurations
@@ -98,18 +154,7 @@ func DefaultConfig(config WebServerDefaultConfig) *WebServerConfig {
 		AppName:               config.AppName,
 		CompressedFileSuffix:  fmt.Sprintf(".%s.gz", config.AppName),
 		DisableStartupMessage: true,
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			if config.Logger != nil {
-				var message string
-				if _, ok := gocontext.Get[string](c.UserContext(), "panic-error"); ok {
-					message = fmt.Sprintf("Recovered from panic. Cause: %s", err)
-				} else {
-					message = fmt.Sprintf("Unexpected error. Cause: %s", err)
-				}
-				config.Logger.Error(c.Context(), message)
-			}
-			return c.SendStatus(http.StatusInternalServerError)
-		},
+		ErrorHandler:          NewErrorHandler(config.Logger, config.ErrorMappers...),
 		// ReadTimeout:  time.Second * 5, // max time for reading the request
 		// WriteTimeout: time.Second * 5, // max time for write the response
 		JSONEncoder: func(v interface{}) ([]byte, error) {
@@ -131,6 +176,30 @@ func DefaultConfig(config WebServerDefaultConfig) *WebServerConfig {
 	})
 
 	app.Use(favicon.New())
+
+	// The readiness route is added before the middlewares, so probes are never limited
+	ready := &atomic.Bool{}
+	readinessRoute := defaultIfEmpty(config.Shutdown.ReadinessRoute, defaultReadinessRoute)
+	app.Get(readinessRoute, readinessHandler(ready))
+
+	// Internal routes are neither traced, logged nor limited
+	internalRoutes := []string{
+		defaultIfEmpty(config.Swagger.Route, defaultSwaggerRoute),
+		readinessRoute,
+	}
+
+	if config.Tracing.Enabled {
+		tracing := config.Tracing
+		tracing.ServiceName = defaultIfEmpty(tracing.ServiceName, config.AppName)
+		tracing.SkipPaths = append(append([]string{}, tracing.SkipPaths...), internalRoutes...)
+		app.Use(TracingMiddleware(tracing))
+	}
+
+	if config.AccessLog.Enabled && config.Logger != nil {
+		accessLog := config.AccessLog
+		accessLog.SkipPaths = append(append([]string{}, accessLog.SkipPaths...), internalRoutes...)
+		app.Use(AccessLogMiddleware(config.Logger, accessLog))
+	}
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     strings.Join(config.Cors.AllowOrigins, ","),
 		AllowMethods:     strings.Join(config.Cors.AllowMethods, ","),
@@ -152,10 +221,16 @@ func DefaultConfig(config WebServerDefaultConfig) *WebServerConfig {
 	}))
 
 	if config.RateLimite != (RateLimiteConfig{}) {
-		app.Use(limiter.New(limiter.Config{
-			Max:               config.RateLimite.MaxRequests,
-			Expiration:        config.RateLimite.MaxRequestsInterval,
-			LimiterMiddleware: limiter.SlidingWindow{},
+		maxRequests := config.RateLimite.MaxRequests
+		if maxRequests <= 0 {
+			maxRequests = defaultMaxRequests
+		}
+
+		app.Use(RateLimitMiddleware(RateLimitConfig{
+			Algorithm: RateLimitSlidingWindow,
+			Limit:     maxRequests,
+			Window:    config.RateLimite.MaxRequestsInterval,
+			SkipPaths: internalRoutes,
 		}))
 	}
 
@@ -166,12 +241,23 @@ func DefaultConfig(config WebServerDefaultConfig) *WebServerConfig {
 	}
 
 	return &WebServerConfig{
-		app:           app,
-		routers:       []WebRoute{},
-		swaggerConfig: config.Swagger,
+		app:            app,
+		routers:        []WebRoute{},
+		swaggerConfig:  config.Swagger,
+		shutdownConfig: config.Shutdown,
+		logger:         config.Logger,
+		ready:          ready,
 	}
 }
 
+// defaultIfEmpty Get value or the default value if it is empty
+func defaultIfEmpty(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
 // isNil check if the webserver config is nil
 func (wsc *WebServerConfig) isNil() bool {
 	return wsc.app == nil || wsc.routers == nil
@@ -189,10 +275,31 @@ func NewWebServer(config *WebServerConfig) *WebServer {
 		})
 	}
 
+	if len(config.shutdownConfig.Signals) == 0 {
+		config.shutdownConfig.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	if config.shutdownConfig.Timeout <= 0 {
+		config.shutdownConfig.Timeout = defaultShutdownTimeout
+	}
+
+	if config.shutdownConfig.ReadinessRoute == "" {
+		config.shutdownConfig.ReadinessRoute = defaultReadinessRoute
+	}
+
+	if config.ready == nil {
+		config.ready = &atomic.Bool{}
+		config.app.Get(config.shutdownConfig.ReadinessRoute, readinessHandler(config.ready))
+	}
+
 	return &WebServer{
-		app:           config.app,
-		routers:       config.routers,
-		swaggerConfig: config.swaggerConfig,
+		app:            config.app,
+		routers:        config.routers,
+		swaggerConfig:  config.swaggerConfig,
+		shutdownConfig: config.shutdownConfig,
+		logger:         config.logger,
+		ready:          config.ready,
+		shutdownStart:  make(chan struct{}),
 	}
 }
 
@@ -210,22 +317,90 @@ func (ws *WebServer) AddRoutes(routes ...WebRoute) {
 	ws.routers = append(ws.routers, routes...)
 }
 
-// gracefulShutdown Terminates the web server
-func (ws *WebServer) gracefulShutdown() {
-	shutdownChannel := make(chan os.Signal, 1)
-	signal.Notify(shutdownChannel, os.Interrupt)
+// OnShutdown Add hooks called in order when the web server shuts down, after the active
+// requests finished, such as closing a godb.DB or flushing golog
+func (ws *WebServer) OnShutdown(hooks ...ShutdownHook) {
+	ws.hooksMutex.Lock()
+	defer ws.hooksMutex.Unlock()
+	ws.shutdownHooks = append(ws.shutdownHooks, hooks...)
+}
 
-	go func(shutdownChannel chan os.Signal, app *fiber.App) {
-		<-shutdownChannel
-		err := app.Shutdown()
-		if err != nil {
-			panic(err)
+// CloserHook Returns a ShutdownHook closing closer
+func CloserHook(closer io.Closer) ShutdownHook {
+	return func(context.Context) error {
+		return closer.Close()
+	}
+}
+
+// Ready Returns if the web server is listening and not shutting down
+func (ws *WebServer) Ready() bool {
+	return ws.ready.Load()
+}
+
+// Shutdown Terminates the web server. The readiness route starts failing, the web server keeps
+// serving during the drain period, waits for the active requests up to the shutdown timeout
+// and calls the shutdown hooks. Hooks are called even if the web server fails to shut down,
+// and all the errors are returned. Later calls return the result of the first one.
+func (ws *WebServer) Shutdown(ctx context.Context) error {
+	ws.shutdownOnce.Do(func() {
+		close(ws.shutdownStart)
+		ws.shutdownErr = ws.shutdown(ctx)
+	})
+	return ws.shutdownErr
+}
+
+// shutdown terminates the web server
+func (ws *WebServer) shutdown(ctx context.Context) error {
+	ws.ready.Store(false)
+	if ws.logger != nil {
+		ws.logger.Info(ctx, "Shutting down the web server")
+	}
+
+	if ws.shutdownConfig.DrainPeriod > 0 {
+		select {
+		case <-time.After(ws.shutdownConfig.DrainPeriod):
+		case <-ctx.Done():
 		}
-	}(shutdownChannel, ws.app)
+	}
+
+	errs := []error{}
+	shutdownCtx, cancel := context.WithTimeout(ctx, ws.shutdownConfig.Timeout)
+	defer cancel()
+
+	if err := ws.app.ShutdownWithContext(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to shutdown the web server. Cause: %w", err))
+	}
+
+	ws.hooksMutex.Lock()
+	hooks := append([]ShutdownHook{}, ws.shutdownHooks...)
+	ws.hooksMutex.Unlock()
+
+	for index, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to run the shutdown hook %d. Cause: %w", index, err))
+		}
+	}
+
+	err := errors.Join(errs...)
+	if err != nil && ws.logger != nil {
+		ws.logger.Error(ctx, fmt.Sprintf("Failed to shutdown the web server. Cause: %s", err))
+	}
+	return err
 }
 
-// swaggerUI Configurate the default swagger route
-func (ws *WebServer) swaggerUI() {
+// readinessHandler responds 200 while ready and 503 otherwise
+func readinessHandler(ready *atomic.Bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if ready.Load() {
+			return c.SendStatus(http.StatusOK)
+		}
+		return c.SendStatus(http.StatusServiceUnavailable)
+	}
+}
+
+// swaggerUI Configurate the default swagger route. The OpenAPI document generated from the
+// documented routes is served with it, instead of the swag generated one.
+func (ws *WebServer) swaggerUI() error {
 	if ws.swaggerConfig.Title == "" {
 		ws.swaggerConfig.Title = defaultSwaggerTitle
 	}
@@ -234,8 +409,20 @@ func (ws *WebServer) swaggerUI() {
 		ws.swaggerConfig.Route = defaultSwaggerRoute
 	}
 
+	var documentURL string
+	if ws.hasDocs() {
+		handler, err := ws.openAPIHandler()
+		if err != nil {
+			return err
+		}
+
+		documentURL = path.Join(strings.TrimSuffix(ws.swaggerConfig.Route, "*"), defaultOpenAPIFile)
+		ws.app.Get(documentURL, handler)
+	}
+
 	ws.app.Get(ws.swaggerConfig.Route, swagger.New(swagger.Config{
 		Title:  ws.swaggerConfig.Title,
+		URL:    documentURL,
 		Layout: "BaseLayout",
 		Plugins: []template.JS{
 			template.JS("SwaggerUIBundle.plugins.DownloadUrl"),
@@ -255,20 +442,60 @@ func (ws *WebServer) swaggerUI() {
 		},
 		ShowMutatedRequest: true,
 	}))
+	return nil
 }
 
-// Listen Start the web server
+// Listen Start the web server. See ListenContext.
 func (ws *WebServer) Listen(address string) error {
-	ws.gracefulShutdown()
+	return ws.ListenContext(context.Background(), address)
+}
 
+// ListenContext Start the web server until ctx is done, one of the shutdown signals is received
+// or Shutdown is called. It returns the error of the shutdown, if any.
+func (ws *WebServer) ListenContext(ctx context.Context, address string) error {
 	if ws.swaggerConfig != (WebServerSwaggerConfig{}) {
-		ws.swaggerUI()
+		if err := ws.swaggerUI(); err != nil {
+			return err
+		}
+	}
+
+	for _, route := range ws.routes() {
+		ws.app.Add(route.Method, route.Path, route.Handlers...)
 	}
 
-	if ws.routers != nil && len(ws.routers) > 0 {
-		for _, route := range ws.routers {
-			ws.app.Add(route.Method, route.Path, route.Handlers...)
+	signalCtx, stop := signal.NotifyContext(ctx, ws.shutdownConfig.Signals...)
+	defer stop()
+
+	listenErr := make(chan error, 1)
+	ws.app.Hooks().OnListen(func(fiber.ListenData) error {
+		ws.ready.Store(true)
+		return nil
+	})
+
+	go func() {
+		listenErr <- ws.app.Listen(address)
+	}()
+
+	select {
+	case err := <-listenErr:
+		ws.ready.Store(false)
+		select {
+		case <-ws.shutdownStart:
+			// Shutdown was called, so its result is returned once it finishes
+			shutdownErr := ws.Shutdown(ctx)
+			if err == nil {
+				return shutdownErr
+			}
+			return errors.Join(err, shutdownErr)
+		default:
+			return err
+		}
+	case <-signalCtx.Done():
+		// The shutdown must not be canceled with the listen context
+		err := ws.Shutdown(context.WithoutCancel(ctx))
+		if listenErr := <-listenErr; listenErr != nil {
+			return errors.Join(listenErr, err)
 		}
+		return err
 	}
-	return ws.app.Listen(address)
 }
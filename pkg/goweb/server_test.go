@@ -1,11 +1,15 @@
 package goweb
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
 	"testing"
 	"time"
 
@@ -102,5 +106,166 @@ func TestWebServer(t *testing.T) {
 
 	bs, err = io.ReadAll(resp.Body)
 	assert.NoErrorf(t, err, "failed to read response body from GET /panic request. Cause: %s", err)
-	assert.Equalf(t, "Internal Server Error", string(bs), "invalid response body when calling GET /. Expected Internal Server Error but got %s", bs)
+	assert.Equalf(t, MIMEApplicationProblemJSON, resp.Header.Get("Content-Type"), "invalid content type when calling GET /panic")
+	assert.JSONEqf(t,
+		`{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/panic","code":"internal_server_error"}`,
+		string(bs), "invalid response body when calling GET /panic. Got %s", bs,
+	)
+}
+
+func TestWebServerRateLimitDefaults(t *testing.T) {
+	var ws *WebServer
+	assert.NotPanics(t, func() {
+		ws = NewWebServer(DefaultConfig(WebServerDefaultConfig{
+			RateLimite: RateLimiteConfig{MaxRequestsInterval: time.Minute},
+		}))
+	}, "a zero MaxRequests should use the default limit")
+
+	ws.AddRoutes(WebRoute{
+		Method:   http.MethodGet,
+		Path:     "/",
+		Handlers: []func(c *fiber.Ctx) error{func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) }},
+	})
+
+	statuses := []int{}
+	for index := 0; index <= defaultMaxRequests; index++ {
+		resp, err := ws.GetApp().Test(httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NoError(t, err)
+		statuses = append(statuses, resp.StatusCode)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, statuses[defaultMaxRequests])
+	assert.NotContains(t, statuses[:defaultMaxRequests], http.StatusTooManyRequests)
+
+	ws.ready.Store(true)
+	for index := 0; index <= 2*defaultMaxRequests; index++ {
+		resp, err := ws.GetApp().Test(httptest.NewRequest(http.MethodGet, defaultReadinessRoute, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "readiness probes should not be limited")
+	}
+}
+
+// freeAddress returns a local address with a free port
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// waitReady waits for the web server to be ready
+func waitReady(t *testing.T, ws *WebServer) {
+	assert.Eventually(t, ws.Ready, 5*time.Second, 10*time.Millisecond, "the web server should be ready")
+}
+
+func TestWebServerShutdown(t *testing.T) {
+	t.Run("should drain, shutdown and run the hooks in order", func(t *testing.T) {
+		ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{
+			Shutdown: ShutdownConfig{DrainPeriod: 500 * time.Millisecond},
+		}))
+
+		calls := []string{}
+		ws.OnShutdown(
+			func(ctx context.Context) error {
+				calls = append(calls, "db")
+				return nil
+			},
+			func(ctx context.Context) error {
+				calls = append(calls, "logs")
+				return nil
+			},
+		)
+
+		address := freeAddress(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- ws.ListenContext(ctx, address)
+		}()
+		waitReady(t, ws)
+
+		resp, err := http.Get(fmt.Sprintf("http://%s/readyz", address))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		assert.Eventually(t, func() bool { return !ws.Ready() }, time.Second, 10*time.Millisecond)
+
+		resp, err = http.Get(fmt.Sprintf("http://%s/readyz", address))
+		assert.NoError(t, err, "the web server should keep serving while draining")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		assert.NoError(t, <-done)
+		assert.Equal(t, []string{"db", "logs"}, calls)
+	})
+
+	t.Run("should serve the readiness route before the middlewares", func(t *testing.T) {
+		ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{}))
+		ws.GetApp().Use(func(c *fiber.Ctx) error {
+			return TooManyRequests("limited")
+		})
+
+		resp, err := ws.GetApp().Test(httptest.NewRequest(http.MethodGet, defaultReadinessRoute, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		ws.ready.Store(true)
+		resp, err = ws.GetApp().Test(httptest.NewRequest(http.MethodGet, defaultReadinessRoute, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "probes should not run the middlewares")
+	})
+
+	t.Run("should shutdown on SIGTERM", func(t *testing.T) {
+		ws := NewWebServer(nil)
+		address := freeAddress(t)
+		done := make(chan error, 1)
+		go func() {
+			done <- ws.Listen(address)
+		}()
+		waitReady(t, ws)
+
+		assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "the web server should shutdown on SIGTERM")
+		}
+	})
+
+	t.Run("should return the errors", func(t *testing.T) {
+		ws := NewWebServer(nil)
+		closed := false
+		ws.OnShutdown(
+			func(ctx context.Context) error {
+				return errors.New("failed to close the db")
+			},
+			CloserHook(closerFunc(func() error {
+				closed = true
+				return nil
+			})),
+		)
+
+		address := freeAddress(t)
+		done := make(chan error, 1)
+		go func() {
+			done <- ws.Listen(address)
+		}()
+		waitReady(t, ws)
+
+		err := ws.Shutdown(context.Background())
+		assert.ErrorContains(t, err, "failed to close the db")
+		assert.True(t, closed, "the hooks should run after a failed hook")
+		assert.Equal(t, err, <-done)
+		assert.Equal(t, err, ws.Shutdown(context.Background()), "later calls should return the same result")
+	})
+}
+
+// closerFunc implements io.Closer using a function
+type closerFunc func() error
+
+// Close
+func (cf closerFunc) Close() error {
+	return cf()
 }
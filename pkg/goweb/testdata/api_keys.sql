@@ -0,0 +1,8 @@
+CREATE TABLE api_keys (
+	prefix TEXT PRIMARY KEY,
+	hash TEXT NOT NULL,
+	name TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NULL
+);
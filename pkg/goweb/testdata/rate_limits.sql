@@ -0,0 +1,8 @@
+CREATE TABLE rate_limits (
+	id TEXT PRIMARY KEY,
+	count REAL NOT NULL,
+	previous REAL NOT NULL,
+	state_time INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL,
+	version INTEGER NOT NULL
+);
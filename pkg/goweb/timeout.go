@@ -0,0 +1,21 @@
+package goweb
+
+import (
+	"context"
+	"time"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// TimeoutMiddleware Creates a middleware setting a deadline on the fiber UserContext, so godb
+// statements and outgoing calls using it are canceled once the request takes longer than
+// timeout. Handlers returning context.DeadlineExceeded are sent as 504 errors.
+func TimeoutMiddleware(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
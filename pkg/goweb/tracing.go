@@ -0,0 +1,155 @@
+package goweb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+const (
+	defaultTracingOperation = "http.request"
+	traceParentHeader       = "traceparent"
+)
+
+type TracingConfig struct {
+	Enabled bool
+	// ServiceName is the span service. Defaults to the tracer service, or the app name when
+	// enabled with DefaultConfig.
+	ServiceName string
+	// OperationName is the span name. Defaults to http.request.
+	OperationName string
+	// SkipPaths are the paths that are not traced. Paths ending with * are prefixes.
+	// DefaultConfig also skips the swagger and readiness routes.
+	SkipPaths []string
+}
+
+// TracingMiddleware Creates a middleware starting a Datadog span per request.
+//
+// The span continues the trace of the incoming Datadog or W3C traceparent headers, is stored
+// in the fiber UserContext, so golog and godb statements using it are correlated, and its
+// headers are sent in the response. Errors returned by the next handlers are sent with the
+// app ErrorHandler, so their status is traced. Only 5xx responses mark the span as failed,
+// with the original error when there is one.
+func TracingMiddleware(config TracingConfig) fiber.Handler {
+	if config.OperationName == "" {
+		config.OperationName = defaultTracingOperation
+	}
+
+	return func(c *fiber.Ctx) error {
+		if skipPath(c.Path(), config.SkipPaths) {
+			return c.Next()
+		}
+
+		headers := tracer.TextMapCarrier{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			headers[string(key)] = string(value)
+		})
+
+		opts := []ddtrace.StartSpanOption{
+			tracer.SpanType(ext.SpanTypeWeb),
+			tracer.Tag(ext.HTTPMethod, c.Method()),
+			tracer.Tag(ext.HTTPURL, c.OriginalURL()),
+			tracer.Tag(ext.HTTPUserAgent, c.Get(fiber.HeaderUserAgent)),
+			tracer.Measured(),
+		}
+
+		if config.ServiceName != "" {
+			opts = append(opts, tracer.ServiceName(config.ServiceName))
+		}
+
+		if parent, err := tracer.Extract(headers); err == nil {
+			opts = append(opts, tracer.ChildOf(parent))
+		} else if parent, ok := parseTraceParent(c.Get(traceParentHeader)); ok {
+			// The tracer can be configured without the W3C propagation
+			opts = append(opts, tracer.ChildOf(parent))
+		}
+
+		span, ctx := tracer.StartSpanFromContext(c.UserContext(), config.OperationName, opts...)
+		c.SetUserContext(ctx)
+
+		responseHeaders := tracer.TextMapCarrier{}
+		if err := tracer.Inject(span.Context(), responseHeaders); err == nil {
+			for key, value := range responseHeaders {
+				c.Set(key, value)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			handleError(c, err)
+		}
+
+		status := c.Response().StatusCode()
+		span.SetTag(ext.HTTPRoute, c.Route().Path)
+		span.SetTag(ext.ResourceName, fmt.Sprintf("%s %s", c.Method(), c.Route().Path))
+		span.SetTag(ext.HTTPCode, strconv.Itoa(status))
+
+		var err error
+		if status >= http.StatusInternalServerError {
+			// Inner middlewares, such as the access log, can have sent the error already
+			if err = handledError(c); err == nil {
+				err = fmt.Errorf("%d: %s", status, http.StatusText(status))
+			}
+		}
+		span.Finish(tracer.WithError(err))
+		return nil
+	}
+}
+
+// traceParent implements the ddtrace.SpanContextW3C of a W3C traceparent header
+type traceParent struct {
+	traceID [16]byte
+	spanID  uint64
+}
+
+// SpanID
+func (tp traceParent) SpanID() uint64 {
+	return tp.spanID
+}
+
+// TraceID
+func (tp traceParent) TraceID() uint64 {
+	return binary.BigEndian.Uint64(tp.traceID[8:])
+}
+
+// TraceID128
+func (tp traceParent) TraceID128() string {
+	return hex.EncodeToString(tp.traceID[:])
+}
+
+// TraceID128Bytes
+func (tp traceParent) TraceID128Bytes() [16]byte {
+	return tp.traceID
+}
+
+// ForeachBaggageItem
+func (tp traceParent) ForeachBaggageItem(func(key, value string) bool) {}
+
+// parseTraceParent Parse a W3C traceparent header, such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceParent(header string) (traceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceParent{}, false
+	}
+
+	var parent traceParent
+	if _, err := hex.Decode(parent.traceID[:], []byte(parts[1])); err != nil || parent.traceID == [16]byte{} {
+		return traceParent{}, false
+	}
+
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil || spanID == 0 {
+		return traceParent{}, false
+	}
+
+	parent.spanID = spanID
+	return parent, true
+}
@@ -0,0 +1,110 @@
+package goweb
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(nil)})
+	app.Use(TracingMiddleware(TracingConfig{ServiceName: "users-api", SkipPaths: []string{"/readyz"}}))
+
+	spanIDs := []uint64{}
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		span, ok := tracer.SpanFromContext(c.UserContext())
+		assert.True(t, ok, "the span should be in the user context")
+		spanIDs = append(spanIDs, span.Context().SpanID())
+		return c.SendString("user")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("database is down")
+	})
+	app.Get("/invalid", func(c *fiber.Ctx) error {
+		return BadRequest("invalid id")
+	})
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/users/10", nil)
+	request.Header.Set("X-Datadog-Trace-Id", "123")
+	request.Header.Set("X-Datadog-Parent-Id", "456")
+	resp, err := app.Test(request)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", resp.Header.Get("X-Datadog-Trace-Id"), "the trace should be sent in the response")
+
+	for _, path := range []string{"/fail", "/invalid", "/readyz"} {
+		_, err = app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.NoError(t, err)
+	}
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 3, "skipped paths should not be traced")
+
+	span := spans[0]
+	assert.Equal(t, uint64(123), span.TraceID())
+	assert.Equal(t, uint64(456), span.ParentID())
+	assert.Equal(t, spanIDs[0], span.SpanID())
+	assert.Equal(t, "http.request", span.OperationName())
+	assert.Equal(t, "users-api", span.Tag(ext.ServiceName))
+	assert.Equal(t, "GET /users/:id", span.Tag(ext.ResourceName))
+	assert.Equal(t, "/users/:id", span.Tag(ext.HTTPRoute))
+	assert.Equal(t, "200", span.Tag(ext.HTTPCode))
+	assert.Nil(t, span.Tag(ext.Error))
+
+	span = spans[1]
+	assert.Equal(t, "500", span.Tag(ext.HTTPCode))
+	assert.EqualError(t, span.Tag(ext.Error).(error), "database is down")
+
+	span = spans[2]
+	assert.Equal(t, "400", span.Tag(ext.HTTPCode))
+	assert.Nil(t, span.Tag(ext.Error), "4xx should not be errors")
+	mt.Reset()
+
+	ws := NewWebServer(DefaultConfig(WebServerDefaultConfig{
+		Logger:    &loggerRecorder{},
+		Tracing:   TracingConfig{Enabled: true},
+		AccessLog: AccessLogConfig{Enabled: true},
+	}))
+	ws.GetApp().Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("database is down")
+	})
+	_, err = ws.GetApp().Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.NoError(t, err)
+
+	spans = mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "500", spans[0].Tag(ext.HTTPCode))
+	assert.EqualError(t, spans[0].Tag(ext.Error).(error), "database is down", "errors sent by the access log should be traced")
+}
+
+func TestParseTraceParent(t *testing.T) {
+	parent, ok := parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", parent.TraceID128())
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), parent.TraceID())
+	assert.Equal(t, uint64(0x00f067aa0ba902b7), parent.SpanID())
+
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		_, ok := parseTraceParent(header)
+		assert.False(t, ok, header)
+	}
+}